@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/abzicht/svgocode/llog"
 	"github.com/abzicht/svgocode/svgocode"
 	"github.com/abzicht/svgocode/svgocode/conf"
 	"github.com/abzicht/svgocode/svgocode/conv"
 	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
 	"github.com/abzicht/svgocode/svgocode/ordering"
 	"github.com/abzicht/svgocode/svgocode/svg"
 	"github.com/jessevdk/go-flags"
@@ -62,6 +68,49 @@ func main() {
 		llog.Warn("No plotter configuration specified. Using default configuration for LONGER LK5 PRO 3D printer.\n")
 		plotterConfig = conf.PlotterConfigLongerLK5ProDefault()
 	}
+	if f.DPI > 0 {
+		plotterConfig.DPI = math64.Float(f.DPI)
+	}
+	if f.CurveTolerance > 0 {
+		plotterConfig.CurveTolerance = math64.Float(f.CurveTolerance)
+	}
+	if len(f.Dialect) > 0 {
+		plotterConfig.Dialect = f.Dialect
+	}
+	if f.NoArcs {
+		plotterConfig.Arcs = false
+	}
+	if f.OutlineFills {
+		plotterConfig.OutlineFills = true
+	}
+	plotterConfig.Fonts = append(plotterConfig.Fonts, f.Fonts...)
+	if len(f.RasterMode) > 0 {
+		plotterConfig.Raster.Mode = conf.RasterMode(f.RasterMode)
+	}
+	if f.RasterSpacing > 0 {
+		plotterConfig.Raster.Spacing = math64.Float(f.RasterSpacing)
+	}
+	if len(plotterConfig.Raster.Dir) == 0 && len(f.SvgFile) > 0 {
+		// Images are referenced relative to the SVG file
+		plotterConfig.Raster.Dir = filepath.Dir(f.SvgFile)
+	}
+	if f.Jobs > 0 {
+		plotterConfig.Jobs = f.Jobs
+	}
+	var converter conv.ConverterI = conv.NewDirect()
+	if f.Hatch || f.CrossHatch {
+		if f.HatchSpacing > 0 {
+			plotterConfig.Hatch.Spacing = math64.Float(f.HatchSpacing)
+		}
+		if f.HatchAngle != nil {
+			// Set explicitly, e.g., to 0 for horizontal lines
+			plotterConfig.Hatch.Angle = math64.AngDeg(*f.HatchAngle)
+		}
+		if f.CrossHatch {
+			plotterConfig.Hatch.Cross = true
+		}
+		converter = conv.NewHatch()
+	}
 	var reader io.Reader = os.Stdin
 	if len(f.SvgFile) > 0 {
 		// Read from file (instead of STDIN)
@@ -87,30 +136,105 @@ func main() {
 	if err != nil {
 		llog.Panic(err.Error())
 	}
+	var filter svgocode.ElementFilter
+	if len(f.OnlyLayer) > 0 {
+		filter = svgocode.LayerFilter(f.OnlyLayer)
+	}
+	alg := ordering.OrderingAlg(f.Ordering)
+	order := ordering.ParseOrdering(alg)
+	if jobs := plotterConfig.Jobs; jobs > 1 && alg.MinimizesTravel() {
+		// Order spatial tiles concurrently. Only if the number of jobs is set
+		// explicitly, such that the output does not depend on the host's CPUs.
+		order = ordering.NewTiled(order, jobs)
+	}
+	if f.OrderingGroups != 0 {
+		order = ordering.NewGrouped(order, f.OrderingGroups)
+	}
+	order = newInterruptibleOrder(order, f.OrderingTimeout)
+	if len(f.Split) > 0 {
+		// Convert and write one file per part
+		base := f.GcodeFile
+		if len(base) == 0 {
+			base = f.SvgFile
+		}
+		if len(base) == 0 {
+			llog.Panic("Splitting requires a GCODE file (--gcode) or SVG file (--svg) for naming the output files")
+		}
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+		parts := svgocode.Svg2GcodeSplit(&parsed_svg, plotterConfig, converter, order, svgocode.SplitMode(f.Split), filter)
+		if len(parts) == 0 {
+			llog.Warn("No GCODE produced\n")
+		}
+		for _, part := range parts {
+			writeGcode(fmt.Sprintf("%s.%s.gcode", base, svgocode.SafeFileName(part.Name)), part.Gcode)
+		}
+		return
+	}
 	// Convert to *gcode.Gcode
-	gcode_ := svgocode.Svg2Gcode(&parsed_svg, plotterConfig, conv.NewDirect(), ordering.ParseOrdering(ordering.OrderingAlg(f.Ordering)))
+	gcode_ := svgocode.Svg2GcodeFiltered(&parsed_svg, plotterConfig, converter, order, filter)
+	writeGcode(f.GcodeFile, gcode_)
+	// Fin
+}
 
+// Stops ordering early on the first interrupt (Ctrl-C), including all
+// subsequent orderings. Interrupts are only caught while ordering: at any
+// other time (e.g., while converting) and after the first interrupt, they
+// terminate the program.
+type interruptibleOrder struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	order  ordering.OrderingI
+}
+
+func newInterruptibleOrder(order ordering.OrderingI, timeout time.Duration) *interruptibleOrder {
+	o := new(interruptibleOrder)
+	o.ctx, o.cancel = context.WithCancelCause(context.Background())
+	o.order = ordering.NewBounded(o.ctx, order, timeout)
+	return o
+}
+
+func (o *interruptibleOrder) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	if o.ctx.Err() == nil {
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		done := make(chan struct{})
+		defer close(done)
+		defer signal.Stop(interrupts)
+		go func() {
+			select {
+			case <-interrupts:
+				signal.Stop(interrupts)
+				llog.Warn("Interrupted. Finishing with the best segment order found so far (interrupt again to abort).\n")
+				o.cancel(errors.New("interrupted"))
+			case <-done:
+			}
+		}()
+	}
+	return o.order.Order(gcodes)
+}
+
+// Write gcode to the given file (or, if empty, to STDOUT)
+func writeGcode(gcodeFile string, gcode_ *gcode.Gcode) {
 	var writer io.Writer = os.Stdout
-	if len(f.GcodeFile) > 0 {
+	if len(gcodeFile) > 0 {
 		// Write to file (instead of STDOUT)
-		fi, err := os.Create(f.GcodeFile)
+		fi, err := os.Create(gcodeFile)
+		if err != nil {
+			llog.Panicf("Failed to open file %s: %s", gcodeFile, err.Error())
+		}
 		defer func() {
 			if err := fi.Close(); err != nil {
-				llog.Panicf("Failed to close file %s: %s", f.GcodeFile, err.Error())
+				llog.Panicf("Failed to close file %s: %s", gcodeFile, err.Error())
 			}
 		}()
-		if err != nil {
-			llog.Panicf("Failed to open file %s: %s", f.GcodeFile, err.Error())
-		}
 		writer = fi
 	}
 	// Encode gcode
 	encoder := gcode.NewEncoder(writer)
-	if err = encoder.Encode(gcode_); err != nil {
+	if err := encoder.Encode(gcode_); err != nil {
 		llog.Panic(err.Error())
 	}
-	if err = encoder.Close(); err != nil {
+	if err := encoder.Close(); err != nil {
 		llog.Panic(err.Error())
 	}
-	// Fin
 }
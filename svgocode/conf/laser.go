@@ -0,0 +1,82 @@
+package conf
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+type LaserMapping string
+
+const (
+	LaserMappingNone  = LaserMapping("none")  // Use the default setting for all shapes
+	LaserMappingColor = LaserMapping("color") // Map the stroke color to power and speed
+	LaserMappingWidth = LaserMapping("width") // Map the stroke width to power and speed
+)
+
+// Power and speed of the laser while drawing
+type LaserSetting struct {
+	Power math64.Float `yaml:"power"` // Percent of max-power
+	Speed math64.Speed `yaml:"speed"` // Drawing speed. If 0, draw-speed applies.
+}
+
+type LaserWidthSetting struct {
+	Width        math64.Float `yaml:"width"` // Stroke width, in the plotter's unit
+	LaserSetting `yaml:",inline"`
+}
+
+// Laser cutter / engraver mode: the laser is switched on for drawing and off
+// for travel moves; Z is not moved.
+type LaserConfig struct {
+	Enabled  bool         `yaml:"enabled"`
+	Dynamic  bool         `yaml:"dynamic"`   // Use M4 (dynamic power, scaled with the actual speed; GRBL laser mode) instead of M3
+	MaxPower math64.Float `yaml:"max-power"` // Spindle value (S) for 100% power (GRBL: $30)
+	Default  LaserSetting `yaml:"default"`
+	MapBy    LaserMapping `yaml:"map-by"` // 'none' (default), 'color', or 'width'
+	// Colors: Settings per stroke color ('#rrggbb'). Colors that are not
+	// listed use the default speed and the default power, scaled by the
+	// color's darkness.
+	Colors map[string]LaserSetting `yaml:"colors,omitempty"`
+	// Widths: Settings per stroke width. Power and speed are interpolated
+	// linearly between the listed widths.
+	Widths []LaserWidthSetting `yaml:"widths,omitempty"`
+}
+
+// Setting for the given stroke color (in hexadecimal notation) with the given
+// darkness (0: white, 1: black)
+func (l *LaserConfig) ForColor(hex string, darkness math64.Float) LaserSetting {
+	for color, setting := range l.Colors {
+		if strings.EqualFold(color, hex) {
+			return setting
+		}
+	}
+	return LaserSetting{Power: l.Default.Power * darkness, Speed: l.Default.Speed}
+}
+
+// Setting for the given stroke width
+func (l *LaserConfig) ForWidth(width math64.Float) LaserSetting {
+	if len(l.Widths) == 0 {
+		return l.Default
+	}
+	widths := slices.Clone(l.Widths)
+	slices.SortFunc(widths, func(a, b LaserWidthSetting) int {
+		return cmp.Compare(a.Width, b.Width)
+	})
+	if width <= widths[0].Width {
+		return widths[0].LaserSetting
+	}
+	for i, w := range widths[1:] {
+		if width > w.Width {
+			continue
+		}
+		prev := widths[i]
+		t := (width - prev.Width) / (w.Width - prev.Width)
+		return LaserSetting{
+			Power: prev.Power + t*(w.Power-prev.Power),
+			Speed: prev.Speed + math64.Speed(t)*(w.Speed-prev.Speed),
+		}
+	}
+	return widths[len(widths)-1].LaserSetting
+}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"io"
+	"runtime"
 	"strings"
 
 	"github.com/abzicht/svgocode/svgocode/math64"
@@ -18,9 +19,106 @@ type Plate struct {
 	Max math64.VectorF3 `yaml:"max"`
 }
 
+// Parameters for filling shapes with hatch lines
+type HatchConfig struct {
+	Spacing math64.Float  `yaml:"spacing"` // Distance between hatch lines (should match the pen's line width)
+	Angle   math64.AngDeg `yaml:"angle"`   // Angle of hatch lines, counter-clockwise from the X-axis
+	Cross   bool          `yaml:"cross"`   // Add a second set of hatch lines, perpendicular to the first one
+}
+
+type RasterMode string
+
+const (
+	RasterModeHatch    = RasterMode("hatch")    // Horizontal scanlines, more of them where the image is darker
+	RasterModeStipple  = RasterMode("stipple")  // Dots, placed by dithering the image (Floyd-Steinberg)
+	RasterModeSpiral   = RasterMode("spiral")   // A spiral from the center, wiggling with the image's darkness
+	RasterModeSquiggle = RasterMode("squiggle") // Horizontal lines, wiggling with the image's darkness
+	RasterModeNone     = RasterMode("none")     // Skip images
+)
+
+// Parameters for drawing raster images ('image' elements) with strokes
+type RasterConfig struct {
+	Mode    RasterMode   `yaml:"mode"`    // 'hatch' (default), 'stipple', 'spiral', 'squiggle', or 'none'
+	Spacing math64.Float `yaml:"spacing"` // Distance between lines and dots (the smaller, the denser)
+	// Dir: Directory that relative image file references are resolved
+	// against. Defaults to the directory of the SVG file.
+	Dir string `yaml:"dir,omitempty"`
+}
+
+type PenLiftMode string
+
+const (
+	PenLiftModeZ      = PenLiftMode("z")      // Move the Z axis between drawing-height and retract-height
+	PenLiftModeServo  = PenLiftMode("servo")  // Turn a servo via 'M280 P<index> S<angle>' or 'M3 S<angle>'/'M5'
+	PenLiftModeCustom = PenLiftMode("custom") // Run custom gcode
+)
+
+// Time to wait after lifting and lowering the pen, in milliseconds
+type PenLiftDelay struct {
+	Up   math64.Float `yaml:"up"`
+	Down math64.Float `yaml:"down"`
+}
+
+type PenLiftZConfig struct {
+	Delay PenLiftDelay `yaml:"delay"`
+}
+
+type PenLiftServoConfig struct {
+	Command string       `yaml:"command"` // 'M280' (Marlin, Klipper) or 'M3' (GRBL, spindle PWM)
+	Index   int          `yaml:"index"`   // Servo index (P), only for M280
+	Up      math64.Float `yaml:"up"`      // Servo angle (S) for lifting the pen. For M3, 0 emits 'M5'.
+	Down    math64.Float `yaml:"down"`    // Servo angle (S) for lowering the pen
+	Delay   PenLiftDelay `yaml:"delay"`
+}
+
+type PenLiftCustomConfig struct {
+	Up    string       `yaml:"up"`   // Newline-separated gcode for lifting the pen
+	Down  string       `yaml:"down"` // Newline-separated gcode for lowering the pen
+	Delay PenLiftDelay `yaml:"delay"`
+}
+
+// How the pen is lifted and lowered. Only the section of the selected mode
+// applies.
+type PenLiftConfig struct {
+	Mode   PenLiftMode         `yaml:"mode"` // 'z' (default), 'servo', or 'custom'
+	Z      PenLiftZConfig      `yaml:"z"`
+	Servo  PenLiftServoConfig  `yaml:"servo"`
+	Custom PenLiftCustomConfig `yaml:"custom"`
+}
+
+// A pen of a multi-pen setup
+type PenConfig struct {
+	Name   string   `yaml:"name"`
+	Colors []string `yaml:"colors"` // Colors that are drawn with this pen
+}
+
+type PenMatch string
+
+const (
+	PenMatchExact   = PenMatch("exact")   // Colors must match one of the pen's colors. Others are drawn with the first pen.
+	PenMatchNearest = PenMatch("nearest") // Use the pen with the most similar color
+)
+
+type PenChangeMode string
+
+const (
+	PenChangeModePause = PenChangeMode("pause") // Pause (e.g., M0 or M600) and let the user change the pen
+	PenChangeModeMacro = PenChangeMode("macro") // Run custom gcode, e.g., for an automatic tool changer
+)
+
+// How to change between pens
+type PenChangeConfig struct {
+	Mode    PenChangeMode `yaml:"mode"`    // 'pause' (default) or 'macro'
+	Command string        `yaml:"command"` // Pause command, e.g., 'M0' (default) or 'M600'
+	Prompt  string        `yaml:"prompt"`  // Message for the user. '{pen}' is replaced by the pen's name.
+	Macro   string        `yaml:"macro"`   // Newline-separated gcode. '{pen}' is replaced by the pen's name, '{index}' by its index (starting at 0).
+}
+
 type PlotterConfig struct {
 	GcodePrefix string `yaml:"gprefix"`
 	GcodeSuffix string `yaml:"gsuffix"`
+	// Dialect: Flavor of the produced gcode ('marlin', 'grbl', 'klipper', 'linuxcnc', or 'smoothieware'). Defaults to 'marlin'.
+	Dialect string `yaml:"dialect"`
 	// Unit that is used in PlotterConfig's variables and that will be used for gcode ('mm' or 'in')
 	UnitLength    math64.UnitLength `yaml:"length-unit"`
 	Plate         Plate             `yaml:"plate"`
@@ -28,12 +126,43 @@ type PlotterConfig struct {
 	RetractHeight math64.Float      `yaml:"retract-height"`
 	DrawSpeed     math64.Speed      `yaml:"draw-speed"`
 	RetractSpeed  math64.Speed      `yaml:"retract-speed"`
+	// PenLift: Strategy for lifting and lowering the pen. Heights only apply to mode 'z'.
+	PenLift PenLiftConfig `yaml:"pen-lift"`
+	// Laser: Laser cutter / engraver mode. If enabled, it replaces PenLift.
+	Laser LaserConfig `yaml:"laser"`
+	// Pens: Multi-pen setup. Elements are assigned to pens by their stroke
+	// (or fill) color and drawn pen by pen.
+	Pens      []PenConfig     `yaml:"pens,omitempty"`
+	PenMatch  PenMatch        `yaml:"pen-match"` // 'exact' or 'nearest' (default)
+	PenChange PenChangeConfig `yaml:"pen-change"`
 	// RemoveComments: Strip produced gcode from all comments
 	RemoveComments bool            `yaml:"remove-comments"`
 	MirrorX        bool            `yaml:"mirror-x-axis"`
 	MirrorY        bool            `yaml:"mirror-y-axis"`
 	PenOffset      math64.VectorF2 `yaml:"pen-offset"` // Pen may not be at [X: 0, Y: 0], but instead mounted with an offset.
-	yamlPrefix     string
+	// DPI: Dots per inch, used for converting SVG pixels ('px', unitless values) to physical units. Defaults to 96.
+	DPI math64.Float `yaml:"dpi"`
+	// Hatch: Parameters for filling shapes (only applies, if filling is enabled)
+	Hatch HatchConfig `yaml:"hatch"`
+	// Arcs: Draw circular arcs and curves with G2/G3 instead of line segments (where possible)
+	Arcs bool `yaml:"arcs"`
+	// CurveTolerance: Maximum deviation of approximated curves from the original shape. Defaults to 0.05mm.
+	CurveTolerance math64.Float `yaml:"curve-tolerance"`
+	// OutlineFills: Draw the outlines of shapes that are filled, but not
+	// stroked ('stroke: none'). By default, only their fill is drawn (if
+	// filling is enabled).
+	OutlineFills bool `yaml:"outline-fills"`
+	// Fonts: Font files for rendering text: Hershey ('.jhf'), SVG fonts
+	// ('.svg'), or TrueType ('.ttf'). Text is rendered with the first font
+	// that matches its font-family, the first given font, or the bundled
+	// single-line font.
+	Fonts []string `yaml:"fonts,omitempty"`
+	// Raster: Parameters for drawing raster images
+	Raster RasterConfig `yaml:"raster"`
+	// Jobs: Number of shapes that are converted concurrently. 0 (default):
+	// one per CPU. If set, segments are also ordered in as many tiles.
+	Jobs       int `yaml:"jobs"`
+	yamlPrefix string
 }
 
 // Read a PlotterConfig struct from a reader in YAML-format and return it.
@@ -50,6 +179,14 @@ func (p *PlotterConfig) convUnitF2(v math64.VectorF2, unit math64.UnitLength) ma
 	return math64.VectorF2{X: x, Y: y}
 }
 
+// Number of concurrent jobs (cf. Jobs)
+func (p *PlotterConfig) NumJobs() int {
+	if p.Jobs > 0 {
+		return p.Jobs
+	}
+	return runtime.NumCPU()
+}
+
 // Create a list of transform commands for the given configuration.
 // Transform matrix is scaled to the given transformUnit
 func (p *PlotterConfig) Transform(transformUnit math64.UnitLength) svgtransform.TransformChain {
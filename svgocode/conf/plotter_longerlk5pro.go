@@ -41,6 +41,7 @@ func PlotterConfigLongerLK5ProDefault() *PlotterConfig {
 	p := new(PlotterConfig)
 	p.GcodePrefix = gCodePrefix
 	p.GcodeSuffix = gCodeSuffix
+	p.Dialect = "marlin"
 	p.UnitLength = math64.UnitMM
 	p.Plate = Plate{
 		Center: math64.VectorF2{X: 150, Y: 150},
@@ -51,10 +52,28 @@ func PlotterConfigLongerLK5ProDefault() *PlotterConfig {
 	p.RetractHeight = 23.0
 	p.DrawSpeed = 2000.0
 	p.RetractSpeed = 4000.0
+	p.PenLift = PenLiftConfig{
+		Mode:  PenLiftModeZ,
+		Servo: PenLiftServoConfig{Command: "M280", Index: 0, Up: 90, Down: 30, Delay: PenLiftDelay{Up: 150, Down: 150}},
+	}
+	p.Laser = LaserConfig{
+		Enabled:  false,
+		Dynamic:  true,
+		MaxPower: 1000,
+		Default:  LaserSetting{Power: 100, Speed: 1000},
+		MapBy:    LaserMappingNone,
+	}
+	p.PenMatch = PenMatchNearest
+	p.PenChange = PenChangeConfig{Mode: PenChangeModePause, Command: "M0", Prompt: "Insert pen {pen}"}
 	p.RemoveComments = false
 	p.MirrorX = false
 	p.MirrorY = true
 	p.PenOffset = math64.VectorF2{X: 52, Y: 30}
+	p.DPI = 96
+	p.Hatch = HatchConfig{Spacing: 1.0, Angle: 45, Cross: false}
+	p.Raster = RasterConfig{Mode: RasterModeHatch, Spacing: 0.5}
+	p.Arcs = true
+	p.CurveTolerance = 0.05
 	p.yamlPrefix = longerLK5ProYamlPrefix
 	return p
 }
@@ -9,6 +9,7 @@ type RuntimeConfig struct {
 	Plotter     *PlotterConfig
 	PlotterUnit math64.UnitLength
 	SvgUnit     math64.UnitLength
+	DPI         math64.Float // Dots per inch for converting pixels (cf. PlotterConfig's DPI)
 }
 
 func NewRuntimeConfig(plotter *PlotterConfig, plotterUnit, svgUnit math64.UnitLength) *RuntimeConfig {
@@ -17,6 +18,10 @@ func NewRuntimeConfig(plotter *PlotterConfig, plotterUnit, svgUnit math64.UnitLe
 		llog.Panicf("Pointer to plotter configuration is nil! We really need a config, please do better")
 	}
 	r.Plotter = plotter
+	r.DPI = math64.DefaultDPI
+	if plotter.DPI > 0 {
+		r.DPI = plotter.DPI
+	}
 	r.SetPlotterUnit(plotterUnit)
 	r.SetSvgUnit(svgUnit)
 	return r
@@ -33,11 +38,22 @@ func (r *RuntimeConfig) SetPlotterUnit(u math64.UnitLength) {
 }
 
 func (r *RuntimeConfig) SetSvgUnit(u math64.UnitLength) {
-	switch u {
-	case math64.UnitCM, math64.UnitMM, math64.UnitIN:
-		break
-	default:
-		llog.Panicf("Unsupported unit (%s). Must be 'cm', 'mm', or 'in'", u)
+	if !u.IsAbsolute() {
+		llog.Panicf("Unsupported unit (%s). Must be 'cm', 'mm', 'in', 'pt', 'pc', or 'px'", u)
 	}
 	r.SvgUnit = u
 }
+
+// Convert a length from unit 'from' to unit 'to', with pixels at the
+// configured DPI
+func (r *RuntimeConfig) LengthConvert(l math64.Float, from, to math64.UnitLength) math64.Float {
+	return math64.LengthConvertDPI(l, from, to, r.DPI)
+}
+
+// Maximum deviation of approximated curves, in the plotter's unit
+func (r *RuntimeConfig) CurveTolerance() math64.Float {
+	if r.Plotter.CurveTolerance > 0 {
+		return r.Plotter.CurveTolerance
+	}
+	return math64.LengthConvert(0.05, math64.UnitMM, r.PlotterUnit)
+}
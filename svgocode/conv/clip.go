@@ -0,0 +1,123 @@
+package conv
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/abzicht/gogenericfunc/fun"
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Express a shape as svg path. Lengths must be expressed in user units.
+// Returns an empty string for elements that are no shapes.
+func shapePathStr(s svg.SVGElement) string {
+	switch s := s.(type) {
+	case *svg.Path:
+		return s.D
+	case *svg.Line:
+		return fmt.Sprintf("M %g %g L %g %g", s.X1.Value, s.Y1.Value, s.X2.Value, s.Y2.Value)
+	case *svg.Rect:
+		return rectPathStr(s)
+	case *svg.Circle:
+		return circlePathStr(s)
+	case *svg.Ellipse:
+		return ellipsePathStr(s)
+	case *svg.Polygon:
+		return svg.PointsToPathStr(s.Points(), true)
+	case *svg.Polyline:
+		return svg.PointsToPathStr(s.Points(), false)
+	}
+	return ""
+}
+
+// Build the region (in gcode space) that the given clip paths and masks leave
+// visible, i.e., the intersection of their regions. plotterTransform
+// precedes the transformations of each clip path. Returns nil, if there are no
+// clips.
+func ClipRegion(clips []svg.ClipRef, root svg.SVGElement, cascade *svg.Cascade, plotterTransform svgtransform.TransformChain, runtConf *conf.RuntimeConfig) math64.Region {
+	if len(clips) == 0 {
+		return nil
+	}
+	var regions math64.RegionIntersection
+	for _, clip := range clips {
+		// The clip path is the union of its shapes
+		var union math64.RegionUnion
+		for _, child := range clip.Element.Children() {
+			for path := range svg.PathSeq_(child, root, true) {
+				element := path[len(path)-1]
+				if !svg.IsLeaf(element) {
+					continue
+				}
+				style := cascade.ComputedStyle(append([]svg.SVGElement{clip.Element}, path...))
+				if !style.IsVisible() {
+					continue
+				}
+				pathStr := shapePathStr(svg.ResolveLengths(element, clip.Lengths))
+				if len(pathStr) == 0 {
+					continue
+				}
+				cmds, err := svg.ParseSVGPath(pathStr)
+				if err != nil {
+					llog.Warnf("Failed to parse shape of clip path '%s': %s. Ignoring the shape.\n", clip.Element.ID(), err.Error())
+					continue
+				}
+				chain := slices.Concat(plotterTransform, clip.TransformChain, clip.Element.Transform(), cascade.TransformChainForPath(path))
+				dCtx := directPathContext{tMat: chain.ToMatrix(), runtime: runtConf}
+				union = append(union, math64.NewPolygonRegion(dCtx.flattenPath(cmds), math64.FillRuleFromString(style.Get("clip-rule"))))
+			}
+		}
+		regions = append(regions, union)
+	}
+	return regions
+}
+
+// Clip all given polylines, returning the parts that lie inside of the region
+func clipPolylines(polylines []math64.Polyline, clip math64.Region) []math64.Polyline {
+	var clipped []math64.Polyline
+	for _, pl := range polylines {
+		clipped = append(clipped, math64.ClipPolyline(pl, clip)...)
+	}
+	return clipped
+}
+
+// Convert path commands into gcode, drawing only the parts of the path that
+// lie inside of the clip region. Curves are drawn as line segments. Returns
+// None, if no part of the path is inside.
+func clippedPathCommandsToGcode(commands []svg.PathCommand, transformChain svgtransform.TransformChain, g *gcode.Gcode, runtConf *conf.RuntimeConfig, ins *gcode.Ins, clip math64.Region) fun.Option[*gcode.Gcode] {
+	dCtx := directPathContext{g: g, tMat: transformChain.ToMatrix(), runtime: runtConf, ins: ins}
+	subpaths, _ := svg.ParseSubpaths(commands)
+	polylines := dCtx.flattenPath(commands)
+	for i, sp := range subpaths {
+		if sp.Closed {
+			polylines[i] = append(polylines[i], polylines[i][0])
+		}
+	}
+	polylines = clipPolylines(polylines, clip)
+	if len(polylines) == 0 {
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	return fun.NewSome[*gcode.Gcode](polylinesToGcode(g, polylines, runtConf, ins))
+}
+
+// Draw the polylines (in gcode space), lifting the pen in-between
+func polylinesToGcode(g *gcode.Gcode, polylines []math64.Polyline, runtConf *conf.RuntimeConfig, ins *gcode.Ins) *gcode.Gcode {
+	for i, pl := range polylines {
+		if i > 0 {
+			ins.Retract(g)
+		}
+		ins.MoveRetracted(g, pl[0])
+		if i == 0 {
+			g.StartCoord = math64.VectorF3{X: pl[0].X, Y: pl[0].Y, Z: runtConf.Plotter.DrawHeight}
+		}
+		ins.DrawPos(g)
+		for _, p := range pl[1:] {
+			ins.Draw(g, p)
+		}
+	}
+	return g
+}
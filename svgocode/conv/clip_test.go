@@ -0,0 +1,118 @@
+package conv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/gogenericfunc/fun"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+const clipTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <defs>
+    <clipPath id="clip"><rect x="20" y="0" width="20" height="100"/></clipPath>
+    <mask id="mask"><circle cx="30" cy="50" r="5" transform="translate(0, -40)"/></mask>
+  </defs>
+  <g clip-path="url(#clip)" transform="translate(10, 0)">
+    <line id="clipped" x1="0" y1="50" x2="100" y2="50" stroke="black"/>
+    <line id="outside" x1="0" y1="0" x2="0" y2="100" stroke="black"/>
+  </g>
+  <line id="masked" x1="0" y1="10" x2="100" y2="10" stroke="black" style="mask: url('#mask')"/>
+</svg>`
+
+func TestClipRegion(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(clipTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	plotter := conf.PlotterConfigLongerLK5ProDefault()
+	runtime := conf.NewRuntimeConfig(plotter, math64.UnitMM, math64.UnitMM)
+	d := NewDirect()
+	d.SetConfig(NewConvConf(runtime))
+	cascade := svg.NewCascade(&s)
+	sMap := svg.SvgToMap(&s)
+	expected := map[svg.SvgId]struct {
+		drawn    bool
+		min, max math64.Float
+	}{
+		"clipped": {true, 30, 50},
+		"outside": {false, 0, 0},
+		"masked":  {true, 25, 35},
+	}
+	for path := range svg.PathSeq(&s) {
+		line, ok := path[len(path)-1].(*svg.Line)
+		if !ok {
+			continue
+		}
+		e := expected[line.Id]
+		clip := ClipRegion(cascade.ClipsForPath(path, sMap), &s, cascade, svgtransform.TransformChain{}, runtime)
+		if clip == nil {
+			t.Fatalf("Line '%s': expected a clip region", line.Id)
+		}
+		line = svg.ResolveLengths(line, svg.LengthContextForPath(path)).(*svg.Line)
+		g := d.Line(line, cascade.TransformChainForPath(path), cascade.ComputedStyle(path), clip)
+		if _, ok := g.(fun.Some[*gcode.Gcode]); !ok {
+			if e.drawn {
+				t.Errorf("Line '%s': expected it to be drawn", line.Id)
+			}
+			continue
+		}
+		if !e.drawn {
+			t.Errorf("Line '%s': expected it to be clipped entirely", line.Id)
+			continue
+		}
+		min, max := g.GetValue().BoundsMin, g.GetValue().BoundsMax
+		if (min.X-e.min).Abs() > 1e-6 || (max.X-e.max).Abs() > 1e-6 {
+			t.Errorf("Line '%s': expected to be drawn from x=%f to x=%f, got %f to %f", line.Id, e.min, e.max, min.X, max.X)
+		}
+	}
+}
+
+const viewportClipTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <svg x="20" y="20" width="40" height="40" viewBox="0 0 10 10">
+    <rect id="border" x="0" y="0" width="10" height="10" stroke="black" fill="none"/>
+    <line id="top" x1="-5" y1="0" x2="15" y2="0" stroke="black"/>
+    <line id="outside" x1="0" y1="-1" x2="10" y2="-1" stroke="black"/>
+  </svg>
+</svg>`
+
+func TestViewportClip(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(viewportClipTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	runtime := conf.NewRuntimeConfig(conf.PlotterConfigLongerLK5ProDefault(), math64.UnitMM, math64.UnitMM)
+	d := NewDirect()
+	d.SetConfig(NewConvConf(runtime))
+	cascade := svg.NewCascade(&s)
+	sMap := svg.SvgToMap(&s)
+	// Drawn length: strokes on the border of the viewport are kept
+	expected := map[svg.SvgId]math64.Float{"border": 160, "top": 40, "outside": 0}
+	for path := range svg.PathSeq(&s) {
+		shape := path[len(path)-1]
+		if !svg.IsLeaf(shape) {
+			continue
+		}
+		clip := ClipRegion(cascade.ClipsForPath(path, sMap), &s, cascade, svgtransform.TransformChain{}, runtime)
+		shape = svg.ResolveLengths(shape, svg.LengthContextForPath(path))
+		g := SVGConvert(shape, cascade.TransformChainForPath(path), cascade.ComputedStyle(path), clip, d)
+		var length math64.Float
+		if _, ok := g.(fun.Some[*gcode.Gcode]); ok {
+			strokes, _ := g.GetValue().Strokes()
+			for _, stroke := range strokes {
+				from := stroke.Start
+				for _, m := range stroke.Moves {
+					length += from.DistEuclid(m.To)
+					from = m.To
+				}
+			}
+		}
+		if (length - expected[shape.ID()]).Abs() > 1e-6 {
+			t.Errorf("Element '%s': expected drawn length %f, got %f", shape.ID(), expected[shape.ID()], length)
+		}
+	}
+}
@@ -5,19 +5,28 @@ import (
 	"github.com/abzicht/svgocode/llog"
 	"github.com/abzicht/svgocode/svgocode/conf"
 	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
 	"github.com/abzicht/svgocode/svgocode/svg"
 	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
 )
 
+// Converts SVG shapes to gcode. Length attributes of the given shapes are
+// expected to be expressed in user units (cf. svg.ResolveLengths). Along with
+// each shape, converters receive its computed style (cf. svg.Cascade) and the
+// region that it is clipped to (cf. ClipRegion; nil, if it is not clipped).
+// Converters are not safe for concurrent use. Instead, every goroutine uses
+// its own clone, which has to be configured via SetConfig.
 type ConverterI interface {
 	SetConfig(*ConvConf)
-	Path(p *svg.Path, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
-	Line(l *svg.Line, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
-	Rect(c *svg.Rect, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
-	Circle(c *svg.Circle, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
-	Ellipse(c *svg.Ellipse, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
-	Polygon(p *svg.Polygon, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
-	Polyline(p *svg.Polyline, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode]
+	Clone() ConverterI
+	Path(p *svg.Path, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Line(l *svg.Line, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Rect(c *svg.Rect, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Circle(c *svg.Circle, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Ellipse(c *svg.Ellipse, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Polygon(p *svg.Polygon, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Polyline(p *svg.Polyline, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
+	Image(i *svg.Image, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode]
 }
 
 // ConvConf: The greatest type name so far
@@ -38,22 +47,24 @@ func WithConfig(converter ConverterI, config *ConvConf) ConverterI {
 }
 
 // Convert using the converter, based on the element's type
-func SVGConvert(s svg.SVGShapeElement, transformChain svgtransform.TransformChain, converter ConverterI) fun.Option[*gcode.Gcode] {
+func SVGConvert(s svg.SVGShapeElement, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region, converter ConverterI) fun.Option[*gcode.Gcode] {
 	switch s.(type) {
 	case *svg.Path:
-		return converter.Path(s.(*svg.Path), transformChain)
+		return converter.Path(s.(*svg.Path), transformChain, style, clip)
 	case *svg.Line:
-		return converter.Line(s.(*svg.Line), transformChain)
+		return converter.Line(s.(*svg.Line), transformChain, style, clip)
 	case *svg.Rect:
-		return converter.Rect(s.(*svg.Rect), transformChain)
+		return converter.Rect(s.(*svg.Rect), transformChain, style, clip)
 	case *svg.Circle:
-		return converter.Circle(s.(*svg.Circle), transformChain)
+		return converter.Circle(s.(*svg.Circle), transformChain, style, clip)
 	case *svg.Ellipse:
-		return converter.Ellipse(s.(*svg.Ellipse), transformChain)
+		return converter.Ellipse(s.(*svg.Ellipse), transformChain, style, clip)
 	case *svg.Polygon:
-		return converter.Polygon(s.(*svg.Polygon), transformChain)
+		return converter.Polygon(s.(*svg.Polygon), transformChain, style, clip)
 	case *svg.Polyline:
-		return converter.Polyline(s.(*svg.Polyline), transformChain)
+		return converter.Polyline(s.(*svg.Polyline), transformChain, style, clip)
+	case *svg.Image:
+		return converter.Image(s.(*svg.Image), transformChain, style, clip)
 	default:
 		llog.Panicf("Unknown SVG object received, cannot convert to gcode. Type: %T\n", s)
 		return nil
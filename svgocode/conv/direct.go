@@ -29,10 +29,14 @@ func (d *Direct) SetConfig(config *ConvConf) {
 	d.ins = gcode.NewIns(d.conf.runtime)
 }
 
+func (d *Direct) Clone() ConverterI {
+	return NewDirect()
+}
+
 // Convert value from svg to plotter unit
 func (d *Direct) convUnitF2(v math64.VectorF2) math64.VectorF2 {
-	x := math64.LengthConvert(v.X, d.conf.runtime.SvgUnit, d.conf.runtime.PlotterUnit)
-	y := math64.LengthConvert(v.Y, d.conf.runtime.SvgUnit, d.conf.runtime.PlotterUnit)
+	x := d.conf.runtime.LengthConvert(v.X, d.conf.runtime.SvgUnit, d.conf.runtime.PlotterUnit)
+	y := d.conf.runtime.LengthConvert(v.Y, d.conf.runtime.SvgUnit, d.conf.runtime.PlotterUnit)
 	return math64.VectorF2{X: x, Y: y}
 }
 
@@ -44,7 +48,22 @@ func (d *Direct) addIdComment(g *gcode.Gcode, type_ string, id svg.SvgId) {
 	d.ins.AddComment(g, fmt.Sprintf("SVG %s (ID: %s)", type_, id))
 }
 
-func (d *Direct) PathStr(g *gcode.Gcode, pathStr string, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
+// Prepare the conversion of an element: describe it and, in laser mode, set
+// power and speed for its stroke
+func (d *Direct) begin(g *gcode.Gcode, type_ string, id svg.SvgId, style svg.Style, transformChain svgtransform.TransformChain) {
+	d.addIdComment(g, type_, id)
+	if d.conf.runtime.Plotter.Laser.Enabled {
+		d.ins.SetLaser(laserSetting(d.conf.runtime, id, style, "stroke", transformChain))
+	}
+}
+
+// Returns true, if the outline of an element with the given style is drawn:
+// if it is stroked or, if configured, if it is filled.
+func (d *Direct) outlined(style svg.Style) bool {
+	return style.HasStroke() || (style.HasFill() && d.conf.runtime.Plotter.OutlineFills)
+}
+
+func (d *Direct) PathStr(g *gcode.Gcode, pathStr string, transformChain svgtransform.TransformChain, clip math64.Region) fun.Option[*gcode.Gcode] {
 	if len(pathStr) == 0 {
 		return fun.NewNone[*gcode.Gcode]()
 	}
@@ -52,89 +71,125 @@ func (d *Direct) PathStr(g *gcode.Gcode, pathStr string, transformChain svgtrans
 	if err != nil {
 		llog.Panicf("Failed to parse SVG path: %s. Path string: '%s'\n", err.Error(), pathStr)
 	}
+	if clip != nil {
+		return clippedPathCommandsToGcode(cmds, transformChain, g, d.conf.runtime, d.ins, clip)
+	}
 	g = PathCommandsToGcode(cmds, transformChain, g, d.conf.runtime, d.ins)
 	return fun.NewSome[*gcode.Gcode](g)
 }
 
-func (d *Direct) Path(p *svg.Path, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
-	g := gcode.NewGcode()
-	if len(p.D) == 0 {
+func (d *Direct) Path(p *svg.Path, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if len(p.D) == 0 || !d.outlined(style) {
 		return fun.NewNone[*gcode.Gcode]()
 	}
-	d.addIdComment(g, "Path", p.Id)
-	return d.PathStr(g, p.D, transformChain)
+	g := gcode.NewGcode()
+	d.begin(g, "Path", p.Id, style, transformChain)
+	return d.PathStr(g, p.D, transformChain, clip)
 }
 
-func (d *Direct) Line(l *svg.Line, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
+func (d *Direct) Line(l *svg.Line, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if !d.outlined(style) {
+		return fun.NewNone[*gcode.Gcode]()
+	}
 	g := gcode.NewGcode()
+	if clip != nil {
+		d.begin(g, "Line", l.Id, style, transformChain)
+		return d.PathStr(g, shapePathStr(l), transformChain, clip)
+	}
 	tMatrix := transformChain.ToMatrix()
-	p1 := d.convUnitF2(tMatrix.ApplyP(math64.VectorF2{X: l.X1, Y: l.Y1}))
-	p2 := d.convUnitF2(tMatrix.ApplyP(math64.VectorF2{X: l.X2, Y: l.Y2}))
-	d.addIdComment(g, "Line", l.Id)
+	p1 := d.convUnitF2(tMatrix.ApplyP(math64.VectorF2{X: l.X1.Value, Y: l.Y1.Value}))
+	p2 := d.convUnitF2(tMatrix.ApplyP(math64.VectorF2{X: l.X2.Value, Y: l.Y2.Value}))
+	d.begin(g, "Line", l.Id, style, transformChain)
 	// Actual bounds values will be updated by move operation
-	g.StartCoord = math64.VectorF3{X: p1.X, Y: p1.Y, Z: d.conf.runtime.Plotter.DrawHeight}
-	d.ins.Move(g, g.StartCoord, d.conf.runtime.Plotter.DrawSpeed)
+	d.ins.MoveRetracted(g, p1)
+	d.ins.DrawPos(g)
 	d.ins.Draw(g, math64.VectorF2{X: p2.X, Y: p2.Y})
+	g.StartCoord = math64.VectorF3{X: p1.X, Y: p1.Y, Z: d.conf.runtime.Plotter.DrawHeight}
 	return fun.NewSome[*gcode.Gcode](g)
 }
 
-func (d *Direct) Polygon(p *svg.Polygon, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
+func (d *Direct) Polygon(p *svg.Polygon, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if !d.outlined(style) {
+		return fun.NewNone[*gcode.Gcode]()
+	}
 	g := gcode.NewGcode()
-	d.addIdComment(g, "Polygon", p.Id)
-	return d.PathStr(g, svg.PointsToPathStr(p.Points(), true), transformChain)
+	d.begin(g, "Polygon", p.Id, style, transformChain)
+	return d.PathStr(g, svg.PointsToPathStr(p.Points(), true), transformChain, clip)
 }
 
-func (d *Direct) Polyline(p *svg.Polyline, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
+func (d *Direct) Polyline(p *svg.Polyline, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if !d.outlined(style) {
+		return fun.NewNone[*gcode.Gcode]()
+	}
 	g := gcode.NewGcode()
-	d.addIdComment(g, "Polyline", p.Id)
-	return d.PathStr(g, svg.PointsToPathStr(p.Points(), false), transformChain)
+	d.begin(g, "Polyline", p.Id, style, transformChain)
+	return d.PathStr(g, svg.PointsToPathStr(p.Points(), false), transformChain, clip)
 }
 
-func (d *Direct) Circle(c *svg.Circle, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
+func (d *Direct) Circle(c *svg.Circle, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if !d.outlined(style) {
+		return fun.NewNone[*gcode.Gcode]()
+	}
 	g := gcode.NewGcode()
-	d.addIdComment(g, "Circle", c.Id)
-	//if len(transformChain) != 0 {
-	// We transform the circle, better let the path converter figure that one out
-	pathStr := fmt.Sprintf(
+	d.begin(g, "Circle", c.Id, style, transformChain)
+	// The path converter draws the two half circles as arcs (if enabled and
+	// permitted by the transformation)
+	return d.PathStr(g, circlePathStr(c), transformChain, clip)
+}
+
+// Express the circle as svg path
+func circlePathStr(c *svg.Circle) string {
+	cx, cy, r := c.CX.Value, c.CY.Value, c.R.Value
+	return fmt.Sprintf(
 		"M %g %g A %g %g 0 1 0 %g %g A %g %g 0 1 0 %g %g Z",
-		c.CX-c.R, c.CY, c.R, c.R, c.CX+c.R, c.CY, c.R, c.R, c.CX-c.R, c.CY)
-	return d.PathStr(g, pathStr, transformChain)
-	//}
-	// No transformations, direct gcode call to draw a circle.
-	//g.BoundsMin.X = c.CX - c.R
-	//g.BoundsMin.Y = c.CY - c.R
-	//g.BoundsMax.X = c.CX + c.R
-	//g.BoundsMax.Y = c.CY + c.R
-	// Start at the top of the circle
-	//g.StartCoord = math64.VectorF3{X: c.CX, Y: c.CY - c.R, Z: d.conf.runtime.Plotter.DrawHeight}
-	//d.ins.Move(g, g.StartCoord, d.conf.runtime.Plotter.DrawSpeed)
-	//d.ins.DrawCircle(g, math64.VectorF2{X: 0, Y: c.R}, c.R, true)
-	//return fun.NewSome[*gcode.Gcode](g)
-}
-
-func (d *Direct) Ellipse(e *svg.Ellipse, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
+		cx-r, cy, r, r, cx+r, cy, r, r, cx-r, cy)
+}
+
+func (d *Direct) Ellipse(e *svg.Ellipse, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if !d.outlined(style) {
+		return fun.NewNone[*gcode.Gcode]()
+	}
 	g := gcode.NewGcode()
-	d.addIdComment(g, "Ellipse", e.Id)
-	// No need to use math here, just convert the ellipse to a SVG path and let the
-	// path parser do the work.
-	pathStr := fmt.Sprintf(
+	d.begin(g, "Ellipse", e.Id, style, transformChain)
+	return d.PathStr(g, ellipsePathStr(e), transformChain, clip)
+}
+
+// Express the ellipse as svg path.
+// No need to use math here, just convert the ellipse to a SVG path and let the
+// path parser do the work.
+func ellipsePathStr(e *svg.Ellipse) string {
+	cx, cy, rx, ry := e.CX.Value, e.CY.Value, e.RX.Value, e.RY.Value
+	return fmt.Sprintf(
 		"M %g,%g A %g,%g 0 1,0 %g,%g A %g,%g 0 1,0 %g,%g Z",
-		e.CX-e.RX, e.CY, e.RX, e.RY, e.CX+e.RX, e.CY, e.RX, e.RY, e.CX-e.RX, e.CY)
-	return d.PathStr(g, pathStr, transformChain)
+		cx-rx, cy, rx, ry, cx+rx, cy, rx, ry, cx-rx, cy)
 }
 
-func (d *Direct) Rect(r *svg.Rect, transformChain svgtransform.TransformChain) fun.Option[*gcode.Gcode] {
-	if r.Width <= 0 || r.Height <= 0 {
+func (d *Direct) Rect(r *svg.Rect, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if !d.outlined(style) {
 		return fun.NewNone[*gcode.Gcode]()
 	}
+	pathStr := rectPathStr(r)
+	if len(pathStr) == 0 {
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	g := gcode.NewGcode()
+	d.begin(g, "Rect", r.Id, style, transformChain)
+	return d.PathStr(g, pathStr, transformChain, clip)
+}
+
+// Express the rect as svg path. Returns an empty string, if the rect has no
+// area.
+func rectPathStr(r *svg.Rect) string {
+	x, y, width, height := r.X.Value, r.Y.Value, r.Width.Value, r.Height.Value
+	if width <= 0 || height <= 0 {
+		return ""
+	}
 
 	// Rounded corners are complicated, let the path parser do the work.
 
-	g := gcode.NewGcode()
-	d.addIdComment(g, "Rect", r.Id)
 	// Apply SVG rounding rules
-	rx := r.RX
-	ry := r.RY
+	rx := r.RX.Value
+	ry := r.RY.Value
 	if rx < 0 {
 		rx = 0
 	}
@@ -147,35 +202,35 @@ func (d *Direct) Rect(r *svg.Rect, transformChain svgtransform.TransformChain) f
 	if ry == 0 && rx > 0 {
 		ry = rx
 	}
-	if rx > r.Width/2 {
-		rx = r.Width / 2
+	if rx > width/2 {
+		rx = width / 2
 	}
-	if ry > r.Height/2 {
-		ry = r.Height / 2
+	if ry > height/2 {
+		ry = height / 2
 	}
 
 	var pathStr string
 	// No rounded corners
 	if rx == 0 && ry == 0 {
 		pathStr = fmt.Sprintf("M %g,%g H %g V %g H %g Z",
-			r.X, r.Y,
-			r.X+r.Width,
-			r.Y+r.Height,
-			r.X,
+			x, y,
+			x+width,
+			y+height,
+			x,
 		)
 	} else {
 		// With rounded corners (arcs)
 		var sb strings.Builder
-		sb.WriteString(fmt.Sprintf("M %g,%g ", r.X+rx, r.Y))
-		sb.WriteString(fmt.Sprintf("H %g ", r.X+r.Width-rx))
-		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g ", rx, ry, r.X+r.Width, r.Y+ry))
-		sb.WriteString(fmt.Sprintf("V %g ", r.Y+r.Height-ry))
-		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g ", rx, ry, r.X+r.Width-rx, r.Y+r.Height))
-		sb.WriteString(fmt.Sprintf("H %g ", r.X+rx))
-		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g ", rx, ry, r.X, r.Y+r.Height-ry))
-		sb.WriteString(fmt.Sprintf("V %g ", r.Y+ry))
-		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g Z", rx, ry, r.X+rx, r.Y))
+		sb.WriteString(fmt.Sprintf("M %g,%g ", x+rx, y))
+		sb.WriteString(fmt.Sprintf("H %g ", x+width-rx))
+		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g ", rx, ry, x+width, y+ry))
+		sb.WriteString(fmt.Sprintf("V %g ", y+height-ry))
+		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g ", rx, ry, x+width-rx, y+height))
+		sb.WriteString(fmt.Sprintf("H %g ", x+rx))
+		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g ", rx, ry, x, y+height-ry))
+		sb.WriteString(fmt.Sprintf("V %g ", y+ry))
+		sb.WriteString(fmt.Sprintf("A %g,%g 0 0 1 %g,%g Z", rx, ry, x+rx, y))
 		pathStr = sb.String()
 	}
-	return d.PathStr(g, pathStr, transformChain)
+	return pathStr
 }
@@ -0,0 +1,75 @@
+package conv
+
+import (
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+func flattenPathStr(t *testing.T, pathStr string) []math64.Polyline {
+	cmds, err := svg.ParseSVGPath(pathStr)
+	if err != nil {
+		t.Fatalf("Failed to parse path '%s': %s", pathStr, err.Error())
+	}
+	plotter := conf.PlotterConfigLongerLK5ProDefault()
+	plotter.CurveTolerance = 0.001
+	runtime := conf.NewRuntimeConfig(plotter, math64.UnitMM, math64.UnitMM)
+	dCtx := directPathContext{tMat: svgtransform.TransformChain{}.ToMatrix(), runtime: runtime}
+	return dCtx.flattenPath(cmds)
+}
+
+func equalPolylines(a, b []math64.Polyline) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j].DistEuclid(b[i][j]) > 1e-9 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestSmoothCurveReflection(t *testing.T) {
+	// Smooth curves and their equivalents with explicit control points
+	golden := []struct{ smooth, reference string }{
+		{"M 0 0 C 0 10 10 10 10 0 S 20 -10 20 0", "M 0 0 C 0 10 10 10 10 0 C 10 -10 20 -10 20 0"},
+		{"m 0 0 c 0 10 10 10 10 0 s 10 -10 10 0", "M 0 0 C 0 10 10 10 10 0 C 10 -10 20 -10 20 0"},
+		{"M 0 0 C 0 10 10 10 10 0 S 20 -10 20 0 S 30 10 30 0", "M 0 0 C 0 10 10 10 10 0 C 10 -10 20 -10 20 0 C 20 10 30 10 30 0"},
+		// Without a preceding cubic curve, the first control point is the current point
+		{"M 0 0 L 10 0 S 20 10 20 0", "M 0 0 L 10 0 C 10 0 20 10 20 0"},
+		{"M 0 0 Q 5 10 10 0 S 20 10 20 0", "M 0 0 Q 5 10 10 0 C 10 0 20 10 20 0"},
+		{"M 0 0 Q 5 10 10 0 T 20 0", "M 0 0 Q 5 10 10 0 Q 15 -10 20 0"},
+		{"M 0 0 Q 5 10 10 0 T 20 0 T 30 0", "M 0 0 Q 5 10 10 0 Q 15 -10 20 0 Q 25 10 30 0"},
+		{"m 0 0 q 5 10 10 0 t 10 0", "M 0 0 Q 5 10 10 0 Q 15 -10 20 0"},
+		// Without a preceding quadratic curve, the curve is a straight line
+		{"M 0 0 C 0 10 10 10 10 0 T 20 0", "M 0 0 C 0 10 10 10 10 0 L 20 0"},
+		// Reflection does not carry over to new subpaths
+		{"M 0 0 Q 5 10 10 0 Z T 10 0", "M 0 0 Q 5 10 10 0 Z L 10 0"},
+	}
+	for _, g := range golden {
+		smooth := flattenPathStr(t, g.smooth)
+		reference := flattenPathStr(t, g.reference)
+		if !equalPolylines(smooth, reference) {
+			t.Errorf("Path '%s' does not match '%s':\n%v\n%v", g.smooth, g.reference, smooth, reference)
+		}
+	}
+}
+
+func TestSmoothCurveReferencePolyline(t *testing.T) {
+	// Control points coincide with the current point, so both curves are
+	// straight lines
+	got := flattenPathStr(t, "M 0 0 L 10 0 T 20 0 S 30 0 30 0")
+	want := []math64.Polyline{{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 20, Y: 0}, {X: 30, Y: 0}}}
+	if !equalPolylines(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
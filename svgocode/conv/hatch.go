@@ -0,0 +1,118 @@
+package conv
+
+import (
+	"fmt"
+
+	"github.com/abzicht/gogenericfunc/fun"
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Conversion like Direct, but bodies of filled shapes are additionally filled
+// with parallel (cross-)hatch lines. Spacing and angle are taken from the
+// plotter configuration.
+type Hatch struct {
+	Direct
+	spacing math64.Float // in plotter units
+}
+
+// Do not use the returned *Hatch before calling SetConfig on it.
+func NewHatch() *Hatch {
+	h := new(Hatch)
+	return h
+}
+
+func (h *Hatch) Clone() ConverterI {
+	return NewHatch()
+}
+
+func (h *Hatch) SetConfig(config *ConvConf) {
+	h.Direct.SetConfig(config)
+	h.spacing = h.conf.runtime.Plotter.Hatch.Spacing
+	if h.spacing <= 0 {
+		h.spacing = math64.LengthConvert(1, math64.UnitMM, h.conf.runtime.PlotterUnit)
+		llog.Warnf("No hatch spacing configured, using %f%s\n", h.spacing, h.conf.runtime.PlotterUnit)
+	}
+}
+
+// Add hatch lines for the shape described by pathStr to the outline's gcode,
+// if the shape is filled. If the shape has no outline (e.g., because it is not
+// stroked), the hatch lines make up the shape's gcode.
+func (h *Hatch) fill(outline fun.Option[*gcode.Gcode], type_ string, pathStr string, id svg.SvgId, style svg.Style, transformChain svgtransform.TransformChain, clip math64.Region) fun.Option[*gcode.Gcode] {
+	if len(pathStr) == 0 || !style.HasFill() {
+		return outline
+	}
+	cmds, err := svg.ParseSVGPath(pathStr)
+	if err != nil {
+		llog.Panicf("Failed to parse SVG path: %s. Path string: '%s'\n", err.Error(), pathStr)
+	}
+	var g *gcode.Gcode
+	switch outline.(type) {
+	case fun.Some[*gcode.Gcode]:
+		g = outline.GetValue()
+	default:
+		g = gcode.NewGcode()
+		h.addIdComment(g, type_, id)
+	}
+	dCtx := directPathContext{g: g, tMat: transformChain.ToMatrix(), runtime: h.conf.runtime, ins: h.ins}
+	// For filling, all subpaths are closed implicitly
+	polygons := dCtx.flattenPath(cmds)
+
+	hatchConf := h.conf.runtime.Plotter.Hatch
+	rule := math64.FillRuleFromString(style.Get("fill-rule"))
+	lines := math64.Hatch(polygons, rule, h.spacing, hatchConf.Angle.Rad())
+	if hatchConf.Cross {
+		lines = append(lines, math64.Hatch(polygons, rule, h.spacing, (hatchConf.Angle+90).Rad())...)
+	}
+	if clip != nil {
+		lines = clipPolylines(lines, clip)
+	}
+	if len(lines) == 0 {
+		return outline
+	}
+	_, hasOutline := outline.(fun.Some[*gcode.Gcode])
+	h.ins.AddComment(g, fmt.Sprintf("Hatch fill (%d lines)", len(lines)))
+	if h.conf.runtime.Plotter.Laser.Enabled {
+		h.ins.SetLaser(laserSetting(h.conf.runtime, id, style, "fill", transformChain))
+	}
+	for i, line := range lines {
+		// New gcode starts without position, there is nothing to retract
+		if (i > 0 || hasOutline) && h.ins.IsDrawing(g) {
+			h.ins.Retract(g)
+		}
+		h.ins.MoveRetracted(g, line[0])
+		if i == 0 && !hasOutline {
+			g.StartCoord = math64.VectorF3{X: line[0].X, Y: line[0].Y, Z: h.conf.runtime.Plotter.DrawHeight}
+		}
+		h.ins.DrawPos(g)
+		h.ins.Draw(g, line[1])
+	}
+	return fun.NewSome[*gcode.Gcode](g)
+}
+
+func (h *Hatch) Path(p *svg.Path, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	return h.fill(h.Direct.Path(p, transformChain, style, clip), "Path", p.D, p.Id, style, transformChain, clip)
+}
+
+func (h *Hatch) Rect(r *svg.Rect, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	return h.fill(h.Direct.Rect(r, transformChain, style, clip), "Rect", rectPathStr(r), r.Id, style, transformChain, clip)
+}
+
+func (h *Hatch) Circle(c *svg.Circle, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	return h.fill(h.Direct.Circle(c, transformChain, style, clip), "Circle", circlePathStr(c), c.Id, style, transformChain, clip)
+}
+
+func (h *Hatch) Ellipse(e *svg.Ellipse, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	return h.fill(h.Direct.Ellipse(e, transformChain, style, clip), "Ellipse", ellipsePathStr(e), e.Id, style, transformChain, clip)
+}
+
+func (h *Hatch) Polygon(p *svg.Polygon, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	return h.fill(h.Direct.Polygon(p, transformChain, style, clip), "Polygon", svg.PointsToPathStr(p.Points(), true), p.Id, style, transformChain, clip)
+}
+
+func (h *Hatch) Polyline(p *svg.Polyline, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	return h.fill(h.Direct.Polyline(p, transformChain, style, clip), "Polyline", svg.PointsToPathStr(p.Points(), false), p.Id, style, transformChain, clip)
+}
@@ -0,0 +1,51 @@
+package conv
+
+import (
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Determine laser power and speed for an element, based on its color (the
+// given property, e.g., 'stroke' or 'fill') or its stroke width.
+func laserSetting(runtConf *conf.RuntimeConfig, id svg.SvgId, style svg.Style, colorProperty string, transformChain svgtransform.TransformChain) conf.LaserSetting {
+	laser := &runtConf.Plotter.Laser
+	switch laser.MapBy {
+	case conf.LaserMapping(""), conf.LaserMappingNone:
+		return laser.Default
+	case conf.LaserMappingColor:
+		value := style.Get(colorProperty)
+		if len(value) == 0 || value == "none" {
+			return laser.Default
+		}
+		color, err := svg.ParseColor(value)
+		if err != nil {
+			llog.Warnf("Element '%s': %s. Using default laser setting.\n", id, err.Error())
+			return laser.Default
+		}
+		return laser.ForColor(color.Hex(), 1-color.Luminance())
+	case conf.LaserMappingWidth:
+		width := strokeWidth(id, style, runtConf)
+		width = runtConf.LengthConvert(width*transformChain.ToMatrix().MaxScale(), runtConf.SvgUnit, runtConf.PlotterUnit)
+		return laser.ForWidth(width)
+	default:
+		llog.Panicf("Unknown laser mapping: '%s'", laser.MapBy)
+		return conf.LaserSetting{}
+	}
+}
+
+// The element's stroke width, in user units. Defaults to 1.
+func strokeWidth(id svg.SvgId, style svg.Style, runtConf *conf.RuntimeConfig) math64.Float {
+	value := style.Get("stroke-width")
+	width, unit, err := math64.ParseLength(value)
+	if err != nil || !(unit == math64.UnitNone || unit.IsAbsolute()) {
+		llog.Warnf("Element '%s': unsupported stroke-width '%s'. Using 1.\n", id, value)
+		return 1
+	}
+	if unit == math64.UnitNone {
+		return width
+	}
+	return runtConf.LengthConvert(width, unit, runtConf.SvgUnit)
+}
@@ -0,0 +1,342 @@
+package conv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/abzicht/gogenericfunc/fun"
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Drawing raster images with strokes. Strokes are laid out in pixel
+// coordinates, following the image's darkness, and are then projected into
+// gcode space.
+
+// Number of gray levels that hatch scanlines distinguish
+const rasterHatchLevels = 4
+
+// Order in which the scanlines of a group are drawn with increasing darkness,
+// such that lines are spread evenly
+var rasterHatchOrder = [rasterHatchLevels]int{0, 2, 1, 3}
+
+// Darkness below which spiral and squiggle lines are interrupted
+const rasterWhite = 1.0 / 16
+
+// Load the image that href refers to: a data URI or a local file (relative
+// paths are resolved against dir). PNG, JPEG, and GIF are supported.
+func loadImage(href, dir string) (image.Image, error) {
+	href = strings.TrimSpace(href)
+	var r io.Reader
+	switch {
+	case len(href) == 0:
+		return nil, fmt.Errorf("no href given")
+	case strings.HasPrefix(href, "data:"):
+		meta, data, ok := strings.Cut(href[len("data:"):], ",")
+		if !ok {
+			return nil, fmt.Errorf("malformed data URI")
+		}
+		if strings.HasSuffix(meta, ";base64") {
+			// Base64 data may be interspersed with white space
+			decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+			if err != nil {
+				return nil, err
+			}
+			r = bytes.NewReader(decoded)
+		} else {
+			decoded, err := url.PathUnescape(data)
+			if err != nil {
+				return nil, err
+			}
+			r = strings.NewReader(decoded)
+		}
+	case strings.Contains(href, "://") && !strings.HasPrefix(href, "file://"):
+		return nil, fmt.Errorf("non-local image '%s'", href)
+	default:
+		path := strings.TrimPrefix(href, "file://")
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Darkness of an image, from 0 (white) to 1 (black). Transparent pixels are
+// white, like the paper that they reveal.
+type rasterDarkness struct {
+	img image.Image
+}
+
+// Darkness of the pixel at p (in pixel coordinates, relative to the image's
+// bounds)
+func (r rasterDarkness) at(p math64.VectorF2) math64.Float {
+	bounds := r.img.Bounds()
+	x := min(max(bounds.Min.X+int(math.Floor(float64(p.X))), bounds.Min.X), bounds.Max.X-1)
+	y := min(max(bounds.Min.Y+int(math.Floor(float64(p.Y))), bounds.Min.Y), bounds.Max.Y-1)
+	c := color.NRGBA64Model.Convert(r.img.At(x, y)).(color.NRGBA64)
+	luminance := (0.2126*math64.Float(c.R) + 0.7152*math64.Float(c.G) + 0.0722*math64.Float(c.B)) / 0xffff
+	return (1 - luminance) * math64.Float(c.A) / 0xffff
+}
+
+// Mean darkness of the pixels in the given area
+func (r rasterDarkness) mean(from, to math64.VectorF2) math64.Float {
+	// Sample a few points only, larger cells are not worth more
+	const samples = 4
+	var sum math64.Float
+	for i := range samples {
+		for j := range samples {
+			sum += r.at(math64.VectorF2{
+				X: from.X + (to.X-from.X)*(math64.Float(i)+0.5)/samples,
+				Y: from.Y + (to.Y-from.Y)*(math64.Float(j)+0.5)/samples,
+			})
+		}
+	}
+	return sum / (samples * samples)
+}
+
+// Scanlines, spacing apart. Within each group of rasterHatchLevels lines,
+// the darker the image, the more lines are drawn. Black areas are covered
+// entirely.
+func rasterHatch(dark rasterDarkness, from, to math64.VectorF2, spacing math64.Float) []math64.Polyline {
+	var lines []math64.Polyline
+	step := spacing / 2
+	row := 0
+	for y := from.Y + spacing/2; y < to.Y; y += spacing {
+		threshold := (math64.Float(rasterHatchOrder[row%rasterHatchLevels]) + 0.5) / rasterHatchLevels
+		var rowLines []math64.Polyline
+		start := math64.Float(-1)
+		for x := from.X; x < to.X; x += step {
+			end := (x + step).Min(to.X)
+			isDark := dark.at(math64.VectorF2{X: (x + end) / 2, Y: y}) > threshold
+			if isDark && start < 0 {
+				start = x
+			}
+			if start >= 0 && (!isDark || end >= to.X) {
+				if !isDark {
+					end = x
+				}
+				rowLines = append(rowLines, math64.Polyline{{X: start, Y: y}, {X: end, Y: y}})
+				start = -1
+			}
+		}
+		// Every other line is drawn backwards, saving travel
+		if row%2 == 1 {
+			slices.Reverse(rowLines)
+			for _, l := range rowLines {
+				slices.Reverse(l)
+			}
+		}
+		lines = append(lines, rowLines...)
+		row++
+	}
+	return lines
+}
+
+// Dots on a grid with the given spacing, placed by dithering the image
+// (Floyd-Steinberg). Each dot is a polyline with a single point.
+func rasterStipple(dark rasterDarkness, from, to math64.VectorF2, spacing math64.Float) []math64.Polyline {
+	cols := int(math.Ceil(float64((to.X - from.X) / spacing)))
+	rows := int(math.Ceil(float64((to.Y - from.Y) / spacing)))
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	// Error that is carried to the current and the next row
+	errs := [2][]math64.Float{make([]math64.Float, cols+2), make([]math64.Float, cols+2)}
+	var dots []math64.Polyline
+	for row := range rows {
+		y := from.Y + math64.Float(row)*spacing
+		// Serpentine scanning, saving travel and avoiding directional
+		// artifacts
+		dir := 1
+		if row%2 == 1 {
+			dir = -1
+		}
+		for i := range cols {
+			col := i
+			if dir < 0 {
+				col = cols - 1 - i
+			}
+			x := from.X + math64.Float(col)*spacing
+			cellFrom := math64.VectorF2{X: x, Y: y}
+			cellTo := math64.VectorF2{X: (x + spacing).Min(to.X), Y: (y + spacing).Min(to.Y)}
+			value := dark.mean(cellFrom, cellTo) + errs[0][col+1]
+			e := value
+			if value > 0.5 {
+				dots = append(dots, math64.Polyline{cellFrom.Add(cellTo).Scale(0.5)})
+				e = value - 1
+			}
+			errs[0][col+1+dir] += e * 7 / 16
+			errs[1][col+1-dir] += e * 3 / 16
+			errs[1][col+1] += e * 5 / 16
+			errs[1][col+1+dir] += e * 1 / 16
+		}
+		errs[0], errs[1] = errs[1], errs[0]
+		clear(errs[1])
+	}
+	return dots
+}
+
+// Follow a line through the image, given as a function of the distance
+// travelled along it, wiggling perpendicular to it with an amplitude that
+// follows the image's darkness. Where the image is white or where the line
+// leaves the visible area, it is interrupted.
+func rasterWiggle(dark rasterDarkness, from, to math64.VectorF2, spacing, length math64.Float, line func(s math64.Float) (p, normal math64.VectorF2)) []math64.Polyline {
+	// One wiggle per spacing, sampled four times
+	step := spacing / 4
+	var lines []math64.Polyline
+	var current math64.Polyline
+	for s := math64.Float(0); s <= length; s += step {
+		p, normal := line(s)
+		inside := p.X >= from.X && p.Y >= from.Y && p.X <= to.X && p.Y <= to.Y
+		d := math64.Float(0)
+		if inside {
+			d = dark.at(p)
+		}
+		if d < rasterWhite {
+			if len(current) > 1 {
+				lines = append(lines, current)
+			}
+			current = nil
+			continue
+		}
+		amplitude := d * spacing * 0.45
+		phase := math64.AngRad(2 * math.Pi * s / spacing)
+		current = append(current, p.Add(normal.Scale(amplitude*phase.Sin())))
+	}
+	if len(current) > 1 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// A spiral from the center of the visible area, with its turns spacing apart
+func rasterSpiral(dark rasterDarkness, from, to math64.VectorF2, spacing math64.Float) []math64.Polyline {
+	center := from.Add(to).Scale(0.5)
+	radius := to.Sub(from).Length() / 2
+	// Archimedean spiral r = b*θ; its length up to θ is about b*θ²/2
+	b := spacing / (2 * math.Pi)
+	turns := radius / spacing
+	length := b * (2 * math.Pi * turns) * (2 * math.Pi * turns) / 2
+	return rasterWiggle(dark, from, to, spacing, length, func(s math64.Float) (p, normal math64.VectorF2) {
+		theta := math64.AngRad((2 * s / b).Sqrt())
+		normal = math64.VectorF2{X: theta.Cos(), Y: theta.Sin()}
+		return center.Add(normal.Scale(b * math64.Float(theta))), normal
+	})
+}
+
+// Horizontal lines, spacing apart, drawn back and forth
+func rasterSquiggle(dark rasterDarkness, from, to math64.VectorF2, spacing math64.Float) []math64.Polyline {
+	var lines []math64.Polyline
+	width := to.X - from.X
+	row := 0
+	for y := from.Y + spacing/2; y < to.Y; y += spacing {
+		forward := row%2 == 0
+		lines = append(lines, rasterWiggle(dark, from, to, spacing, width, func(s math64.Float) (p, normal math64.VectorF2) {
+			if !forward {
+				s = width - s
+			}
+			return math64.VectorF2{X: from.X + s, Y: y}, math64.VectorF2{X: 0, Y: 1}
+		})...)
+		row++
+	}
+	return lines
+}
+
+// Spacing of raster strokes, in plotter units
+func (d *Direct) rasterSpacing() math64.Float {
+	spacing := d.conf.runtime.Plotter.Raster.Spacing
+	if spacing <= 0 {
+		spacing = math64.LengthConvert(0.5, math64.UnitMM, d.conf.runtime.PlotterUnit)
+	}
+	return spacing
+}
+
+// Draw the image with strokes, as configured for raster images
+func (d *Direct) Image(i *svg.Image, transformChain svgtransform.TransformChain, style svg.Style, clip math64.Region) fun.Option[*gcode.Gcode] {
+	rasterConf := d.conf.runtime.Plotter.Raster
+	if rasterConf.Mode == conf.RasterModeNone {
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	img, err := loadImage(i.GetHref(), rasterConf.Dir)
+	if err != nil {
+		llog.Warnf("Failed to load image '%s': %s. Skipping it.\n", i.Id, err.Error())
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	size := math64.VectorF2{X: math64.Float(img.Bounds().Dx()), Y: math64.Float(img.Bounds().Dy())}
+	if size.X <= 0 || size.Y <= 0 {
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	placement, from, to := i.Placement(size)
+	dCtx := directPathContext{tMat: slices.Concat(transformChain, placement).ToMatrix(), runtime: d.conf.runtime}
+	// Size of a pixel on the plotter
+	pixelSize := d.conf.runtime.LengthConvert(dCtx.tMat.Det().Abs().Sqrt(), d.conf.runtime.SvgUnit, d.conf.runtime.PlotterUnit)
+	if pixelSize <= 0 {
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	spacing := d.rasterSpacing() / pixelSize
+	dark := rasterDarkness{img: img}
+	var strokes []math64.Polyline
+	switch rasterConf.Mode {
+	case conf.RasterModeHatch, conf.RasterMode(""):
+		strokes = rasterHatch(dark, from, to, spacing)
+	case conf.RasterModeStipple:
+		strokes = rasterStipple(dark, from, to, spacing)
+	case conf.RasterModeSpiral:
+		strokes = rasterSpiral(dark, from, to, spacing)
+	case conf.RasterModeSquiggle:
+		strokes = rasterSquiggle(dark, from, to, spacing)
+	default:
+		llog.Panicf("Unknown raster mode: '%s'. Must be 'hatch', 'stipple', 'spiral', 'squiggle', or 'none'", rasterConf.Mode)
+	}
+	for _, stroke := range strokes {
+		for j := range stroke {
+			stroke[j] = dCtx.project(stroke[j])
+		}
+	}
+	if clip != nil {
+		var clipped []math64.Polyline
+		for _, stroke := range strokes {
+			if len(stroke) > 1 {
+				clipped = append(clipped, math64.ClipPolyline(stroke, clip)...)
+			} else if clip.Contains(stroke[0]) {
+				// Dots are either drawn or not
+				clipped = append(clipped, stroke)
+			}
+		}
+		strokes = clipped
+	}
+	if len(strokes) == 0 {
+		return fun.NewNone[*gcode.Gcode]()
+	}
+	g := gcode.NewGcode()
+	d.addIdComment(g, "Image", i.Id)
+	d.ins.AddComment(g, fmt.Sprintf("Raster image (%d strokes)", len(strokes)))
+	if d.conf.runtime.Plotter.Laser.Enabled {
+		d.ins.SetLaser(d.conf.runtime.Plotter.Laser.Default)
+	}
+	return fun.NewSome[*gcode.Gcode](polylinesToGcode(g, strokes, d.conf.runtime, d.ins))
+}
@@ -0,0 +1,102 @@
+package conv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/abzicht/gogenericfunc/fun"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// A data URI of a PNG image whose left half is black and whose right half is
+// white
+func halfBlackDataURI(t *testing.T, width, height int) string {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			if x >= width/2 {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+func TestLoadImage(t *testing.T) {
+	img, err := loadImage(halfBlackDataURI(t, 4, 2), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark := rasterDarkness{img: img}
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 2 {
+		t.Errorf("Unexpected image size: %s", img.Bounds().String())
+	}
+	if d := dark.at(math64.VectorF2{X: 0.5, Y: 0.5}); d != 1 {
+		t.Errorf("Expected black pixel, got darkness %f", d)
+	}
+	if d := dark.at(math64.VectorF2{X: 3.5, Y: 1.5}); d != 0 {
+		t.Errorf("Expected white pixel, got darkness %f", d)
+	}
+	for _, href := range []string{"", "data:image/png;base64", "https://example.com/image.png", "does-not-exist.png"} {
+		if _, err := loadImage(href, t.TempDir()); err == nil {
+			t.Errorf("Expected an error for href '%s'", href)
+		}
+	}
+}
+
+func TestRasterStipple(t *testing.T) {
+	img := image.NewUniform(color.Gray{Y: 0x80})
+	dark := rasterDarkness{img: img}
+	dots := rasterStipple(dark, math64.VectorF2{X: 0, Y: 0}, math64.VectorF2{X: 100, Y: 100}, 2)
+	// Half of the 50x50 cells get a dot
+	if len(dots) < 1100 || len(dots) > 1400 {
+		t.Errorf("Expected about 1250 dots, got %d", len(dots))
+	}
+}
+
+func TestImage(t *testing.T) {
+	const svgStr = `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <image x="10" y="20" width="40" height="40" href="%s"/>
+</svg>`
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(fmt.Sprintf(svgStr, halfBlackDataURI(t, 8, 4)))).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []conf.RasterMode{conf.RasterModeHatch, conf.RasterModeStipple, conf.RasterModeSpiral, conf.RasterModeSquiggle} {
+		plotter := conf.PlotterConfigLongerLK5ProDefault()
+		plotter.Raster = conf.RasterConfig{Mode: mode, Spacing: 1}
+		d := NewDirect()
+		d.SetConfig(NewConvConf(conf.NewRuntimeConfig(plotter, math64.UnitMM, math64.UnitMM)))
+		for path := range svg.PathSeq(&s) {
+			i, ok := path[len(path)-1].(*svg.Image)
+			if !ok {
+				continue
+			}
+			i = svg.ResolveLengths(i, svg.LengthContextForPath(path)).(*svg.Image)
+			g := d.Image(i, svgtransform.TransformChain{}, svg.Style{}, nil)
+			if _, ok := g.(fun.Some[*gcode.Gcode]); !ok {
+				t.Errorf("Mode '%s': expected the image to be drawn", mode)
+				continue
+			}
+			// The image is centered vertically, only its black half is drawn
+			min, max := g.GetValue().BoundsMin, g.GetValue().BoundsMax
+			if min.X < 9.5 || max.X > 30.5 || min.Y < 29.5 || max.Y > 50.5 {
+				t.Errorf("Mode '%s': unexpected bounds %s, %s", mode, min.String(), max.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package conv
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/font"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+// A glyph of a text element: a path (in the text element's user space) and
+// the style that it is drawn with
+type TextGlyph struct {
+	Path  *svg.Path
+	Style svg.Style
+}
+
+// A glyph, before text-anchor is applied
+type placedGlyph struct {
+	glyph  font.Glyph
+	origin math64.VectorF2 // Position on the baseline
+	scale  math64.Float
+	style  svg.Style
+}
+
+// Lay out the text element at the end of the given path (from root to text)
+// with the given fonts, honoring x, y, dx, dy, font-family, font-size, and
+// text-anchor. Every glyph becomes a path element that is positioned via its
+// transform attribute. Glyphs of single-line fonts are never filled. Instead,
+// they are stroked with the fill color (unless they have a stroke).
+func TextGlyphs(path []svg.SVGElement, cascade *svg.Cascade, fonts font.Fonts) []TextGlyph {
+	text := path[len(path)-1].(*svg.Text)
+	ctx := svg.LengthContextForPath(path)
+	styles := make(map[svg.SVGElement]svg.Style)
+	var missing []rune
+
+	var glyphs []TextGlyph
+	var chunk []placedGlyph // Glyphs of the current text chunk
+	var pos, chunkStart math64.VectorF2
+	anchor := "start"
+	// Shift the glyphs of the chunk as per text-anchor
+	endChunk := func() {
+		var shift math64.Float
+		switch anchor {
+		case "middle":
+			shift = (pos.X - chunkStart.X) / 2
+		case "end":
+			shift = pos.X - chunkStart.X
+		}
+		for _, g := range chunk {
+			p := new(svg.Path)
+			p.Id = text.Id
+			p.D = g.glyph.D
+			p.TransformStr = fmt.Sprintf("translate(%g, %g) scale(%g, %g)", g.origin.X-shift, g.origin.Y, g.scale, g.scale)
+			glyphs = append(glyphs, TextGlyph{Path: p, Style: g.style})
+		}
+		chunk = nil
+	}
+	for i, c := range text.Chars() {
+		element := c.Path[len(c.Path)-1]
+		style, ok := styles[element]
+		if !ok {
+			style = cascade.ComputedStyle(slices.Concat(path[:len(path)-1], c.Path))
+			styles[element] = style
+		}
+		if !style.IsDisplayed() {
+			continue
+		}
+		// Absolute positions start a new text chunk
+		if i == 0 || c.X != nil || c.Y != nil {
+			endChunk()
+			if c.X != nil {
+				pos.X = c.X.Resolve(ctx, svg.AxisX)
+			}
+			if c.Y != nil {
+				pos.Y = c.Y.Resolve(ctx, svg.AxisY)
+			}
+			chunkStart = pos
+			anchor = style.Get("text-anchor")
+		}
+		if c.DX != nil {
+			pos.X += c.DX.Resolve(ctx, svg.AxisX)
+		}
+		if c.DY != nil {
+			pos.Y += c.DY.Resolve(ctx, svg.AxisY)
+		}
+		f := fonts.Lookup(style.Get("font-family"))
+		scale := fontSize(style, ctx) / f.UnitsPerEm
+		glyph, ok := f.Glyph(c.Rune)
+		if !ok {
+			missing = append(missing, c.Rune)
+			if glyph, ok = f.Glyph('?'); !ok {
+				glyph = font.Glyph{Advance: f.UnitsPerEm / 2}
+			}
+		}
+		if len(glyph.D) > 0 && style.IsVisible() {
+			if f.SingleLine {
+				style = singleLineStyle(style)
+			}
+			chunk = append(chunk, placedGlyph{glyph: glyph, origin: pos, scale: scale, style: style})
+		}
+		pos.X += glyph.Advance * scale
+	}
+	endChunk()
+	if len(missing) > 0 {
+		llog.Warnf("No glyphs for '%s' (text '%s'). Drawing '?' instead.\n", string(missing), text.Id)
+	}
+	return glyphs
+}
+
+// The font size of the given style, in user units
+func fontSize(style svg.Style, ctx svg.LengthContext) math64.Float {
+	value, unit, err := math64.ParseLength(style.Get("font-size"))
+	if err != nil {
+		llog.Warnf("Cannot interpret font-size '%s'. Using the default font size.\n", style.Get("font-size"))
+		return ctx.FontSize
+	}
+	return svg.Length{Value: value, Unit: unit}.Resolve(ctx, svg.AxisOther)
+}
+
+// Style of glyphs that consist of strokes: they are drawn with the fill
+// color, if they have no stroke, and are never filled.
+func singleLineStyle(style svg.Style) svg.Style {
+	style = maps.Clone(style)
+	if !style.HasStroke() {
+		style["stroke"] = style.Get("fill")
+		style["stroke-opacity"] = style.Get("fill-opacity")
+	}
+	style["fill"] = "none"
+	return style
+}
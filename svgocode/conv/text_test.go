@@ -0,0 +1,77 @@
+package conv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/font"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+const textTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100" font-size="32">
+  <text id="start" x="10" y="20">AB</text>
+  <text id="middle" x="50" y="20" text-anchor="middle">AB</text>
+  <text id="end" x="50" y="20" text-anchor="end" fill="red">A<tspan dy="5" font-size="16" stroke="blue">B</tspan></text>
+  <text id="spaces" x="0 100" y="0">
+    A  B
+  </text>
+</svg>`
+
+func TestTextGlyphs(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(textTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	fonts := font.Fonts{font.Default()}
+	a, _ := fonts[0].Glyph('A')
+	b, _ := fonts[0].Glyph('B')
+	space, _ := fonts[0].Glyph(' ')
+	cascade := svg.NewCascade(&s)
+	expected := map[svg.SvgId][]struct {
+		origin math64.VectorF2
+		scale  math64.Float
+		stroke string
+	}{
+		"start": {
+			{math64.VectorF2{X: 10, Y: 20}, 1, "black"},
+			{math64.VectorF2{X: 10 + a.Advance, Y: 20}, 1, "black"},
+		},
+		"middle": {
+			{math64.VectorF2{X: 50 - (a.Advance+b.Advance)/2, Y: 20}, 1, "black"},
+			{math64.VectorF2{X: 50 + (a.Advance-b.Advance)/2, Y: 20}, 1, "black"},
+		},
+		"end": {
+			{math64.VectorF2{X: 50 - a.Advance - b.Advance/2, Y: 20}, 1, "red"},
+			{math64.VectorF2{X: 50 - b.Advance/2, Y: 25}, 0.5, "blue"},
+		},
+		"spaces": {
+			{math64.VectorF2{X: 0, Y: 0}, 1, "black"},
+			// Consecutive white space collapses, the second x applies to the space
+			{math64.VectorF2{X: 100 + space.Advance, Y: 0}, 1, "black"},
+		},
+	}
+	for path := range svg.PathSeq(&s) {
+		text, ok := path[len(path)-1].(*svg.Text)
+		if !ok {
+			continue
+		}
+		glyphs := TextGlyphs(path, cascade, fonts)
+		e := expected[text.Id]
+		if len(glyphs) != len(e) {
+			t.Errorf("Text '%s': expected %d glyphs, got %d", text.Id, len(e), len(glyphs))
+			continue
+		}
+		for i, g := range glyphs {
+			m := g.Path.Transform().ToMatrix()
+			origin := m.ApplyP(math64.VectorF2{})
+			scale := m.ApplyP(math64.VectorF2{X: 1}).Sub(origin).X
+			if origin.DistEuclid(e[i].origin) > 1e-6 || (scale-e[i].scale).Abs() > 1e-6 {
+				t.Errorf("Text '%s', glyph %d: expected origin %s and scale %f, got %s and %f", text.Id, i, e[i].origin.String(), e[i].scale, origin.String(), scale)
+			}
+			if g.Style.Get("fill") != "none" || g.Style.Get("stroke") != e[i].stroke {
+				t.Errorf("Text '%s', glyph %d: expected no fill and stroke '%s', got '%s' and '%s'", text.Id, i, e[i].stroke, g.Style.Get("fill"), g.Style.Get("stroke"))
+			}
+		}
+	}
+}
@@ -1,25 +1,63 @@
 package svgocode
 
 import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
 	"github.com/abzicht/gogenericfunc/fun"
 	"github.com/abzicht/svgocode/llog"
 	"github.com/abzicht/svgocode/svgocode/conf"
 	"github.com/abzicht/svgocode/svgocode/conv"
+	"github.com/abzicht/svgocode/svgocode/font"
 	"github.com/abzicht/svgocode/svgocode/gcode"
 	"github.com/abzicht/svgocode/svgocode/math64"
 	"github.com/abzicht/svgocode/svgocode/ordering"
 	"github.com/abzicht/svgocode/svgocode/svg"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
 )
 
 // Convert an SVG object to GCODE instructions
 func Svg2Gcode(s *svg.SVG, plotterConf *conf.PlotterConfig, converter conv.ConverterI, order ordering.OrderingI) *gcode.Gcode {
-	svgUnit := s.Unit()
-	runtConf := conf.NewRuntimeConfig(plotterConf, plotterConf.UnitLength, svgUnit)
-	converter = conv.WithConfig(converter, conv.NewConvConf(runtConf))
+	return Svg2GcodeFiltered(s, plotterConf, converter, order, nil)
+}
 
+// Convert an SVG object to GCODE instructions, only considering the shapes
+// that pass the filter (if not nil)
+func Svg2GcodeFiltered(s *svg.SVG, plotterConf *conf.PlotterConfig, converter conv.ConverterI, order ordering.OrderingI, filter ElementFilter) *gcode.Gcode {
+	runtConf := newRuntimeConfig(s, plotterConf)
+	return assembleGcode(convertSegments(s, runtConf, converter, filter), runtConf, order)
+}
+
+// Create the runtime configuration for converting the SVG
+func newRuntimeConfig(s *svg.SVG, plotterConf *conf.PlotterConfig) *conf.RuntimeConfig {
+	runtConf := conf.NewRuntimeConfig(plotterConf, plotterConf.UnitLength, s.Unit())
+	// Absolute lengths are resolved with the configured DPI
+	s.DPI = runtConf.DPI
+	return runtConf
+}
+
+// GCODE of a single shape, along with the pen that draws it and the shape's
+// path (from root to shape)
+type segment struct {
+	gcode *gcode.Gcode
+	pen   int
+	path  []svg.SVGElement
+}
+
+// Convert the shapes that pass the filter (if not nil) to GCODE segments, in
+// document order
+func convertSegments(s *svg.SVG, runtConf *conf.RuntimeConfig, converter conv.ConverterI, filter ElementFilter) []segment {
+	svgUnit := s.Unit()
 	plotterTransform := runtConf.Plotter.Transform(svgUnit)
 
-	var gcodes []*gcode.Gcode
+	pens := newPenMatcher(runtConf)
+	cascade := svg.NewCascade(s)
+	sMap := svg.SvgToMap(s)
+	fonts := font.LoadFonts(runtConf.Plotter.Fonts)
+	groupNames := newGroupNamer()
+	// Shapes to convert, in document order
+	var jobs []convertJob
 	for svgElementPath := range svg.PathSeq(s) {
 		if len(svgElementPath) == 0 {
 			continue
@@ -28,60 +66,160 @@ func Svg2Gcode(s *svg.SVG, plotterConf *conf.PlotterConfig, converter conv.Conve
 		// Convert the SVG objects to individual GCODE segments using the
 		// provided converter.
 		if svg.IsLeaf(svgElement) {
-			transformChain := append(plotterTransform, svg.TransformChainForPath(svgElementPath)...)
-			gcodeOpt := conv.SVGConvert(svgElement, transformChain, converter)
-			switch gcodeOpt.(type) {
-			case fun.Some[*gcode.Gcode]:
-				gcodes = append(gcodes, gcodeOpt.GetValue())
-				if gcodeOpt.GetValue().BoundsMin.Equal(math64.VectorF3{X: 0, Y: 0, Z: 20.0}) {
-					llog.Panic(svgElement.ID())
+			if filter != nil && !filter(svgElementPath) {
+				continue
+			}
+			transformChain := append(plotterTransform, cascade.TransformChainForPath(svgElementPath)...)
+			// Converters expect all lengths in user units
+			svgElement = svg.ResolveLengths(svgElement, svg.LengthContextForPath(svgElementPath))
+			style := cascade.ComputedStyle(svgElementPath)
+			if reason := notRendered(svgElement, style); len(reason) > 0 {
+				llog.Debugf("Skipping %s '%s': %s\n", svg.TagName(svgElement), svgElement.ID(), reason)
+				continue
+			}
+			clip := conv.ClipRegion(cascade.ClipsForPath(svgElementPath, sMap), s, cascade, plotterTransform, runtConf)
+			groups := groupNames.path(svgElementPath)
+			if _, ok := svgElement.(*svg.Text); ok {
+				// Text is drawn glyph by glyph
+				for _, glyph := range conv.TextGlyphs(svgElementPath, cascade, fonts) {
+					jobs = append(jobs, convertJob{glyph.Path, slices.Concat(transformChain, glyph.Path.Transform()), glyph.Style, clip, groups, svgElementPath})
 				}
-			case fun.None[*gcode.Gcode]:
-			default:
-				llog.Panicf("Unknown option type: %T\n", gcodeOpt)
+				continue
+			}
+			jobs = append(jobs, convertJob{svgElement, transformChain, style, clip, groups, svgElementPath})
+		}
+	}
+
+	var segments []segment
+	for i, gcodeOpt := range convertJobs(jobs, converter, runtConf) {
+		shape, style := jobs[i].shape, jobs[i].style
+		switch gcodeOpt.(type) {
+		case fun.Some[*gcode.Gcode]:
+			gcodeOpt.GetValue().Groups = jobs[i].groups
+			segments = append(segments, segment{gcodeOpt.GetValue(), pens.Pen(shape.ID(), style), jobs[i].path})
+			if gcodeOpt.GetValue().BoundsMin.Equal(math64.VectorF3{X: 0, Y: 0, Z: 20.0}) {
+				llog.Panic(shape.ID())
 			}
-		} else {
-			switch svgElement.(type) {
-			case *svg.Text:
-				llog.Warn("Text elements are not supported and will not be added to GCODE. Please convert text to paths.\n")
+		case fun.None[*gcode.Gcode]:
+			if _, ok := shape.(*svg.Image); !ok && !style.HasStroke() && !runtConf.Plotter.OutlineFills {
+				llog.Debugf("Skipping %s '%s': no stroke (cf. --outline-fills)\n", svg.TagName(shape), shape.ID())
 			}
+		default:
+			llog.Panicf("Unknown option type: %T\n", gcodeOpt)
 		}
 	}
+	return segments
+}
 
-	if len(gcodes) < 1 {
+// Order the segments pen by pen and join them, along with prefix, suffix, and
+// summary
+func assembleGcode(segments []segment, runtConf *conf.RuntimeConfig, order ordering.OrderingI) *gcode.Gcode {
+	if len(segments) < 1 {
 		llog.Warn("No GCODE produced\n")
 		return gcode.NewGcode()
 	}
-	if llog.GetLevel() >= llog.LDebug {
-		//Only call this function, if we even want to print this info
-		llog.Debugf("Non-drawing travel distance before ordering: %.0f%s\n", gcode.TotalDistanceInBetween(gcodes), runtConf.PlotterUnit)
+
+	// GCODE segments per pen
+	groups := make([][]*gcode.Gcode, newPenMatcher(runtConf).Len())
+	for _, seg := range segments {
+		groups[seg.pen] = append(groups[seg.pen], seg.gcode)
 	}
-	// Order the gcode segments, e.g., such that travel distance is
-	// minimized (depends on the given ordering method)
-	gcodes = order.Order(gcodes)
-	if llog.GetLevel() >= llog.LDebug {
-		totalTravelDist := gcode.TotalDistanceInBetween(gcodes)
-		llog.Debugf("Non-drawing travel distance after ordering: %.0f%s\n", totalTravelDist, runtConf.PlotterUnit)
+
+	// Pen lift and dialect are parsed once for joining all segments
+	ins := gcode.NewIns(runtConf)
+	var bodies []*gcode.Gcode
+	var pensUsed []conf.PenConfig
+	for pen, gcodes := range groups {
+		if len(gcodes) == 0 {
+			continue
+		}
+		if llog.GetLevel() >= llog.LDebug {
+			//Only call this function, if we even want to print this info
+			llog.Debugf("Non-drawing travel distance before ordering: %.0f%s\n", gcode.TotalDistanceInBetween(gcodes), runtConf.PlotterUnit)
+		}
+		// Order the gcode segments, e.g., such that travel distance is
+		// minimized (depends on the given ordering method)
+		gcodes = order.Order(gcodes)
+		if llog.GetLevel() >= llog.LDebug {
+			totalTravelDist := gcode.TotalDistanceInBetween(gcodes)
+			llog.Debugf("Non-drawing travel distance after ordering: %.0f%s\n", totalTravelDist, runtConf.PlotterUnit)
+		}
+		body := gcode.Join(gcodes, ins)
+		if len(runtConf.Plotter.Pens) > 1 {
+			body = gcode.Join([]*gcode.Gcode{NewGcodePenChange(runtConf, ins, body, pen), body}, ins)
+			pensUsed = append(pensUsed, runtConf.Plotter.Pens[pen])
+		}
+		bodies = append(bodies, body)
 	}
 
 	// Join all instructions
-	gcode_joined := gcode.Join(gcodes, runtConf)
+	gcode_joined := gcode.Join(bodies, ins)
 
 	// Add statistics, prefix, and suffix
 	gcode_full := GcodeAddSummary(gcode.Join([]*gcode.Gcode{
-		NewGcodePrefix(runtConf, gcode_joined),
+		NewGcodePrefix(runtConf, ins, gcode_joined),
 		gcode_joined,
-		NewGcodeSuffix(runtConf, gcode_joined),
-	}, runtConf), runtConf)
+		NewGcodeSuffix(runtConf, ins, gcode_joined),
+	}, ins), runtConf, pensUsed)
 
 	WarnBoundariesConditional(runtConf, gcode_full)
 	// Remove comments, if they are not desired
 	if runtConf.Plotter.RemoveComments {
 		gcode_full.Code = gcode_full.Code.RemoveComments()
 	}
+	gcode_full.Code.SetFormatter(gcode.NewFormatter(ins.Dialect()))
 	return gcode_full
 }
 
+// A shape to convert, along with its transformation, computed style, clip
+// region, the names of the groups that contain it, and its path
+type convertJob struct {
+	shape          svg.SVGElement
+	transformChain svgtransform.TransformChain
+	style          svg.Style
+	clip           math64.Region
+	groups         []string
+	path           []svg.SVGElement // For glyphs, the path of their text element
+}
+
+// Convert the shapes of the given jobs concurrently (cf. conf.PlotterConfig's
+// Jobs). Every worker uses its own clone of the converter. Results are in the
+// order of the jobs.
+func convertJobs(jobs []convertJob, converter conv.ConverterI, runtConf *conf.RuntimeConfig) []fun.Option[*gcode.Gcode] {
+	results := make([]fun.Option[*gcode.Gcode], len(jobs))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(runtConf.Plotter.NumJobs(), len(jobs)) {
+		converter := conv.WithConfig(converter.Clone(), conv.NewConvConf(runtConf))
+		wg.Go(func() {
+			for i := int(next.Add(1) - 1); i < len(jobs); i = int(next.Add(1) - 1) {
+				job := jobs[i]
+				results[i] = conv.SVGConvert(job.shape, job.transformChain, job.style, job.clip, converter)
+			}
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// Returns the reason why the element with the given computed style would not
+// be rendered, or an empty string, if it would be.
+func notRendered(element svg.SVGElement, style svg.Style) string {
+	_, isImage := element.(*svg.Image)
+	switch {
+	case !style.IsDisplayed():
+		return "display: none"
+	case !style.IsVisible():
+		return "visibility: " + style.Get("visibility")
+	case style.Number("opacity") <= 0:
+		return "opacity: 0"
+	case !style.HasStroke() && !style.HasFill() && !isImage:
+		// Images are drawn regardless of fill and stroke
+		return "neither stroke nor fill"
+	}
+	return ""
+}
+
 func WarnBoundariesConditional(runtConf *conf.RuntimeConfig, g *gcode.Gcode) {
 	if !g.BoundsMin.Min(runtConf.Plotter.Plate.Min).Equal(runtConf.Plotter.Plate.Min) {
 		llog.Warnf("(Parts of) GCODE lies outside of plotter dimensions. Minimum GCODE position: %s. Minimum plotter coordinates: %s.\n", g.BoundsMin.String(), runtConf.Plotter.Plate.Min.String())
@@ -92,8 +230,7 @@ func WarnBoundariesConditional(runtConf *conf.RuntimeConfig, g *gcode.Gcode) {
 }
 
 // Create gcode for the plotter's gcode prefix
-func NewGcodePrefix(runtConf *conf.RuntimeConfig, body *gcode.Gcode) *gcode.Gcode {
-	ins := gcode.NewIns(runtConf)
+func NewGcodePrefix(runtConf *conf.RuntimeConfig, ins *gcode.Ins, body *gcode.Gcode) *gcode.Gcode {
 	g := body.CopyMeta()
 	g.AppendCode(runtConf.Plotter.GcodePrefix)
 	ins.AddComment(g, "--- SVGOCODE START ---")
@@ -104,24 +241,38 @@ func NewGcodePrefix(runtConf *conf.RuntimeConfig, body *gcode.Gcode) *gcode.Gcod
 	ins.SetSpeed(g, runtConf.Plotter.DrawSpeed, true)
 	target := math64.VectorF3{X: body.StartCoord.X, Y: body.StartCoord.Y, Z: runtConf.Plotter.RetractHeight}
 	ins.AddComment(g, "Moving to start position of first segment")
+	ins.Retract(g)
 	ins.Move(g, target, runtConf.Plotter.RetractSpeed)
 	g.StartCoord = target
 	g.EndCoord = target
-	g.BoundsMin = target
-	g.BoundsMax = target
+	g.BoundsMin = ins.BoundsPoint(target)
+	g.BoundsMax = ins.BoundsPoint(target)
+	return g
+}
+
+// Create gcode for changing to the given pen at the start position of body
+func NewGcodePenChange(runtConf *conf.RuntimeConfig, ins *gcode.Ins, body *gcode.Gcode, pen int) *gcode.Gcode {
+	g := gcode.NewGcode()
+	start := math64.VectorF3{X: body.StartCoord.X, Y: body.StartCoord.Y, Z: runtConf.Plotter.RetractHeight}
+	g.StartCoord = start
+	g.EndCoord = start
+	g.BoundsMin = ins.BoundsPoint(start)
+	g.BoundsMax = ins.BoundsPoint(start)
+	ins.ChangePen(g, pen)
 	return g
 }
 
 // Create gcode for the plotter's gcode suffix, based on the given gcode body
-func NewGcodeSuffix(runtConf *conf.RuntimeConfig, body *gcode.Gcode) *gcode.Gcode {
-	ins := gcode.NewIns(runtConf)
+func NewGcodeSuffix(runtConf *conf.RuntimeConfig, ins *gcode.Ins, body *gcode.Gcode) *gcode.Gcode {
 	g := gcode.NewGcode()
 	g.StartCoord = body.EndCoord
 	g.EndCoord = body.EndCoord
-	g.BoundsMin = body.EndCoord
-	g.BoundsMax = body.EndCoord
+	g.BoundsMin = ins.BoundsPoint(body.EndCoord)
+	g.BoundsMax = ins.BoundsPoint(body.EndCoord)
 	ins.AddComment(g, "SVGOCODE finished, retracting")
-	ins.Retract(g)
+	if ins.IsDrawing(g) {
+		ins.Retract(g)
+	}
 	ins.AddComment(g, "--- SVGOCODE END ---")
 	g.AppendCode(runtConf.Plotter.GcodeSuffix)
 	return g
@@ -0,0 +1,176 @@
+package svgocode
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/conv"
+	"github.com/abzicht/svgocode/svgocode/ordering"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+const visibilityTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <line id="visible" x1="10" y1="10" x2="90" y2="10" stroke="black"/>
+  <line id="hidden" x1="10" y1="20" x2="90" y2="20" stroke="black" visibility="hidden"/>
+  <g display="none">
+    <line id="undisplayed" x1="10" y1="30" x2="90" y2="30" stroke="black"/>
+  </g>
+  <g opacity="0">
+    <line id="transparent" x1="10" y1="40" x2="90" y2="40" stroke="black"/>
+  </g>
+  <line id="unstroked" x1="10" y1="50" x2="90" y2="50" stroke="none"/>
+  <rect id="filled" x="10" y="60" width="80" height="10" stroke="none" fill="black"/>
+</svg>`
+
+func TestSvg2GcodeVisibility(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(visibilityTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		outlineFills bool
+		ids          []string
+	}{
+		{false, []string{"visible"}},
+		{true, []string{"visible", "unstroked", "filled"}},
+	}
+	all := []string{"visible", "hidden", "undisplayed", "transparent", "unstroked", "filled"}
+	for _, e := range expected {
+		plotter := conf.PlotterConfigLongerLK5ProDefault()
+		plotter.OutlineFills = e.outlineFills
+		code := Svg2Gcode(&s, plotter, conv.NewDirect(), ordering.NewNone()).String()
+		for _, id := range all {
+			want := false
+			for _, id2 := range e.ids {
+				want = want || id == id2
+			}
+			if got := strings.Contains(code, "(ID: "+id+")"); got != want {
+				t.Errorf("outline-fills=%t: element '%s' converted: %t, expected %t", e.outlineFills, id, got, want)
+			}
+		}
+	}
+}
+
+func TestSvg2GcodeJobs(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">`)
+	for i := range 200 {
+		fmt.Fprintf(&b, `<g id="g%d"><circle cx="%d" cy="%d" r="2" stroke="black"/><text x="%d" y="%d" stroke="black">%d</text></g>`,
+			i, i%10*10, i/10*5, i%10*10, i/10*5, i)
+	}
+	b.WriteString(`</svg>`)
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(b.String())).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	var expected string
+	for _, jobs := range []int{1, 2, 8} {
+		plotter := conf.PlotterConfigLongerLK5ProDefault()
+		plotter.Jobs = jobs
+		code := Svg2Gcode(&s, plotter, conv.NewDirect(), ordering.NewNone()).String()
+		if jobs == 1 {
+			expected = code
+		} else if code != expected {
+			t.Errorf("Converting with %d jobs: expected the same GCODE as with 1 job", jobs)
+		}
+	}
+}
+
+const groupsTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <g id="light">
+    <line id="l1" x1="10" y1="10" x2="90" y2="10" stroke="black"/>
+    <line id="l2" x1="10" y1="30" x2="90" y2="30" stroke="black"/>
+  </g>
+  <g id="dark">
+    <line id="d1" x1="90" y1="20" x2="10" y2="20" stroke="black"/>
+    <g>
+      <line id="d2" x1="10" y1="40" x2="90" y2="40" stroke="black"/>
+    </g>
+  </g>
+</svg>`
+
+func TestSvg2GcodeGroups(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(groupsTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		order  ordering.OrderingI
+		groups string // Group (first letter) of each segment, in order
+	}{
+		// Travel is shortest when alternating between the groups
+		{ordering.NewGreedy(), "ldld"},
+		{ordering.NewGrouped(ordering.NewGreedy(), 1), "lldd"},
+	}
+	for _, e := range expected {
+		code := Svg2Gcode(&s, conf.PlotterConfigLongerLK5ProDefault(), conv.NewDirect(), e.order).String()
+		ids := []string{"l1", "l2", "d1", "d2"}
+		slices.SortFunc(ids, func(a, b string) int {
+			return strings.Index(code, "(ID: "+a+")") - strings.Index(code, "(ID: "+b+")")
+		})
+		groups := ""
+		for _, id := range ids {
+			groups += id[:1]
+		}
+		if groups != e.groups {
+			t.Errorf("%T: expected segments of groups %s, got %v", e.order, e.groups, ids)
+		}
+	}
+	// Groups without id are numbered (in the order they are named)
+	gn := newGroupNamer()
+	for path := range svg.PathSeq(&s) {
+		if id := path[len(path)-1].ID(); id == "d2" {
+			if groups := gn.path(path); !slices.Equal(groups, []string{"dark", "#1"}) {
+				t.Errorf("Expected groups %q of 'd2', got %q", []string{"dark", "#1"}, groups)
+			}
+		}
+	}
+}
+
+const mixedGroupsTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <g id="A">
+    <path id="p1" d="M 10 10 H 90" stroke="black"/>
+    <g id="g1">
+      <path id="p2" d="M 10 20 H 90" stroke="black"/>
+    </g>
+    <path id="p3" d="M 10 30 H 90" stroke="black"/>
+  </g>
+</svg>`
+
+// Shapes and groups that are siblings are traversed in document order, such
+// that groups are drawn in the order of their first segments
+func TestSvg2GcodeMixedGroups(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(mixedGroupsTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for path := range svg.PathSeq(&s) {
+		if id := string(path[len(path)-1].ID()); strings.HasPrefix(id, "p") {
+			ids = append(ids, id)
+		}
+	}
+	if expected := []string{"p1", "p2", "p3"}; !slices.Equal(ids, expected) {
+		t.Errorf("Expected traversal %q, got %q", expected, ids)
+	}
+	expected := []struct {
+		depth int
+		ids   []string
+	}{
+		{1, []string{"p1", "p2", "p3"}},
+		{-1, []string{"p1", "p3", "p2"}},
+	}
+	for _, e := range expected {
+		code := Svg2Gcode(&s, conf.PlotterConfigLongerLK5ProDefault(), conv.NewDirect(), ordering.NewGrouped(ordering.NewNone(), e.depth)).String()
+		ids := []string{"p1", "p2", "p3"}
+		slices.SortFunc(ids, func(a, b string) int {
+			return strings.Index(code, "(ID: "+a+")") - strings.Index(code, "(ID: "+b+")")
+		})
+		if !slices.Equal(ids, e.ids) {
+			t.Errorf("Depth %d: expected segments %q, got %q", e.depth, e.ids, ids)
+		}
+	}
+}
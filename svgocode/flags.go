@@ -1,17 +1,36 @@
 package svgocode
 
 import (
+	"time"
+
 	"github.com/abzicht/svgocode/llog"
 	"github.com/jessevdk/go-flags"
 )
 
 type Flags struct {
-	Verbosity             int    `short:"v" long:"verbosity" description:"Verbosity (fatal: 0, error: 1, warn: 2, info: 3, debug: 4)." default:"3"`
-	SvgFile               string `short:"s" long:"svg" description:"SVG file to read from (in place of STDIN)"`
-	GcodeFile             string `short:"g" long:"gcode" description:"File that GCODE will be written to (in place of STDOUT)"`
-	PlotterConfigFile     string `short:"p" long:"plotter-config" description:"YAML-encoded config file for the plotter that is to be used."`
-	PlotterConfigTemplate bool   `long:"plotter-config-template" description:"Print an exemplary plotter configuration file in YAML-encoding (cf. flag --plotter-config)"`
-	Ordering              string `short:"o" long:"ordering-algorithm" description:"Algorithm for finding a GCODE segment order. Available algorithms: '2opt' (perfect result; for small input), 'greedy' (not perfect; for large input), 'reverse' (draw last elements first), 'none' (skip ordering), 'numinstructions' (order segments number of GCODE instructions per segment, descending), and 'numinstructions-asc' ('numinstructions', in ascending order)." default:"2opt"`
+	Verbosity             int           `short:"v" long:"verbosity" description:"Verbosity (fatal: 0, error: 1, warn: 2, info: 3, debug: 4)." default:"3"`
+	SvgFile               string        `short:"s" long:"svg" description:"SVG file to read from (in place of STDIN)"`
+	GcodeFile             string        `short:"g" long:"gcode" description:"File that GCODE will be written to (in place of STDOUT)"`
+	PlotterConfigFile     string        `short:"p" long:"plotter-config" description:"YAML-encoded config file for the plotter that is to be used."`
+	PlotterConfigTemplate bool          `long:"plotter-config-template" description:"Print an exemplary plotter configuration file in YAML-encoding (cf. flag --plotter-config)"`
+	DPI                   float64       `long:"dpi" description:"Dots per inch for converting SVG pixels ('px' and unitless values) to physical units. Overrides the plotter configuration's 'dpi' (default: 96)."`
+	Hatch                 bool          `long:"hatch" description:"Fill the bodies of filled shapes with hatch lines (cf. 'hatch' in the plotter configuration)."`
+	HatchSpacing          float64       `long:"hatch-spacing" description:"Distance between hatch lines in the plotter's unit. Overrides the plotter configuration."`
+	HatchAngle            *float64      `long:"hatch-angle" description:"Angle of hatch lines in degrees. Overrides the plotter configuration (also if 0)."`
+	CrossHatch            bool          `long:"cross-hatch" description:"Add perpendicular hatch lines (implies --hatch)."`
+	CurveTolerance        float64       `long:"curve-tolerance" description:"Maximum deviation of approximated curves from the original shape, in the plotter's unit. Overrides the plotter configuration's 'curve-tolerance' (default: 0.05mm)."`
+	Dialect               string        `long:"dialect" description:"GCODE dialect: 'marlin', 'grbl', 'klipper', 'linuxcnc', or 'smoothieware'. Overrides the plotter configuration's 'dialect' (default: marlin)."`
+	NoArcs                bool          `long:"no-arcs" description:"Approximate arcs and curves with line segments only, i.e., do not emit G2/G3 instructions."`
+	OutlineFills          bool          `long:"outline-fills" description:"Draw the outlines of filled shapes that have no stroke ('stroke: none'), instead of skipping them."`
+	Fonts                 []string      `long:"font" description:"Font file for rendering text: Hershey ('.jhf'), SVG font ('.svg'), or TrueType ('.ttf'). Can be given multiple times. Fonts are selected by font-family. By default, a bundled single-line font is used."`
+	Split                 string        `long:"split" description:"Write one GCODE file per part instead of a single one: 'layer' (per Inkscape layer) or 'color' (per pen, if configured, or stroke color). Files are named <name>.<part>.gcode after the GCODE file (or SVG file)."`
+	RasterMode            string        `long:"raster-mode" description:"How raster images ('image' elements) are drawn: 'hatch' (scanlines), 'stipple' (dithered dots), 'spiral', 'squiggle', or 'none' (skip images). Overrides the plotter configuration's 'raster' mode (default: hatch)."`
+	RasterSpacing         float64       `long:"raster-spacing" description:"Distance between the lines and dots that raster images are drawn with, in the plotter's unit. Overrides the plotter configuration."`
+	OnlyLayer             string        `long:"only-layer" description:"Only convert shapes of the Inkscape layer with the given label (or id)."`
+	Ordering              string        `short:"o" long:"ordering-algorithm" description:"Algorithm for finding a GCODE segment order. Available algorithms: '2opt' (perfect result; for small input), 'greedy' (not perfect; for large input), 'spatial' (near-perfect; for huge input), 'reverse' (draw last elements first), 'none' (skip ordering), 'numinstructions' (order segments number of GCODE instructions per segment, descending), and 'numinstructions-asc' ('numinstructions', in ascending order)." default:"2opt"`
+	OrderingGroups        int           `long:"ordering-groups" description:"Keep the sequence of groups up to the given depth and only order segments within each group (1: top-level groups, such as Inkscape layers; -1: all groups). 0 means ordering all segments freely."`
+	OrderingTimeout       time.Duration `long:"ordering-timeout" description:"Maximum duration of each segment ordering (e.g., '30s' or '2m'). When it expires (or on Ctrl-C), the best order found so far is used. 0 means no limit."`
+	Jobs                  int           `short:"j" long:"jobs" description:"Number of shapes that are converted concurrently. Overrides the plotter configuration's 'jobs' (default: one per CPU). If set, segments are also ordered in as many spatial tiles concurrently."`
 }
 
 func ParseFlags(f *Flags) error {
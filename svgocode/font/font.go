@@ -0,0 +1,140 @@
+package font
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Fonts for rendering text: single-line fonts (Hershey) and outline fonts (SVG
+// fonts, TrueType)
+
+// A glyph's outline (or strokes, for single-line fonts)
+type Glyph struct {
+	// SVG path data in font units. The origin lies on the baseline, the y axis
+	// points downwards.
+	D       string
+	Advance math64.Float // Horizontal advance, in font units
+}
+
+type Font struct {
+	Name       string
+	UnitsPerEm math64.Float
+	// SingleLine: Glyphs consist of open strokes instead of outlines, i.e.,
+	// they are drawn, but never filled.
+	SingleLine bool
+	glyphs     map[rune]Glyph
+	load       func(r rune) (Glyph, bool) // Loads glyphs that are not yet in glyphs (optional)
+	mutex      sync.Mutex
+}
+
+func newFont(name string, unitsPerEm math64.Float, singleLine bool) *Font {
+	f := new(Font)
+	f.Name = name
+	f.UnitsPerEm = unitsPerEm
+	f.SingleLine = singleLine
+	f.glyphs = make(map[rune]Glyph)
+	return f
+}
+
+// The glyph of the given character. Returns false, if the font has none.
+func (f *Font) Glyph(r rune) (Glyph, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if g, ok := f.glyphs[r]; ok {
+		return g, true
+	}
+	if f.load == nil {
+		return Glyph{}, false
+	}
+	g, ok := f.load(r)
+	if ok {
+		f.glyphs[r] = g
+	}
+	return g, ok
+}
+
+// The bundled single-line font, in Hershey format
+//
+//go:embed fonts/sans.jhf
+var defaultFontData string
+
+// Name of the bundled font
+const DefaultName = "svgocode-sans"
+
+// The bundled single-line sans-serif font
+func Default() *Font {
+	f, err := ParseHershey(strings.NewReader(defaultFontData), DefaultName)
+	if err != nil {
+		llog.Panicf("Failed to parse bundled font: %s", err.Error())
+	}
+	return f
+}
+
+// Load a font file: Hershey ('.jhf'), SVG font ('.svg'), or TrueType ('.ttf').
+// Hershey fonts are named after their file, the others by their family name.
+func Load(path string) (*Font, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jhf":
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ParseHershey(file, name)
+	case ".svg":
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ParseSVGFont(file, name)
+	case ".ttf":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return ParseTrueType(data, name)
+	}
+	return nil, fmt.Errorf("unsupported font format '%s' (supported: .jhf, .svg, .ttf)", filepath.Ext(path))
+}
+
+// Fonts that text can be rendered with
+type Fonts []*Font
+
+// Load the given font files, followed by the bundled font. Fonts that cannot
+// be loaded are skipped.
+func LoadFonts(paths []string) Fonts {
+	var fonts Fonts
+	for _, path := range paths {
+		f, err := Load(path)
+		if err != nil {
+			llog.Warnf("Failed to load font '%s': %s. Ignoring it.\n", path, err.Error())
+			continue
+		}
+		llog.Debugf("Loaded font '%s' from '%s'\n", f.Name, path)
+		fonts = append(fonts, f)
+	}
+	return append(fonts, Default())
+}
+
+// The first font of the given 'font-family' list that is available. Falls back
+// to the first font, e.g., for generic families like 'sans-serif'.
+func (fonts Fonts) Lookup(family string) *Font {
+	for _, name := range strings.Split(family, ",") {
+		name = strings.Trim(strings.TrimSpace(name), `"'`)
+		for _, f := range fonts {
+			if strings.EqualFold(f.Name, name) {
+				return f
+			}
+		}
+	}
+	return fonts[0]
+}
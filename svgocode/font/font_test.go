@@ -0,0 +1,106 @@
+package font
+
+import (
+	"strings"
+	"testing"
+)
+
+const svgFontTest = `<svg xmlns="http://www.w3.org/2000/svg">
+  <defs>
+    <font horiz-adv-x="500">
+      <font-face font-family="Test Font" units-per-em="1000"/>
+      <glyph unicode="I" d="M 0 0 L 100 0 L 100 700 L 0 700 Z"/>
+      <glyph unicode="W" horiz-adv-x="800" d="M 0 700 L 400 0 L 800 700"/>
+      <glyph unicode="fi" d="M 0 0 L 1 1"/>
+    </font>
+  </defs>
+</svg>`
+
+func TestDefault(t *testing.T) {
+	f := Default()
+	if !f.SingleLine {
+		t.Error("Expected the bundled font to be a single-line font")
+	}
+	for r := ' '; r <= '~'; r++ {
+		g, ok := f.Glyph(r)
+		if !ok {
+			t.Errorf("No glyph for '%c'", r)
+			continue
+		}
+		if g.Advance <= 0 {
+			t.Errorf("Glyph '%c' does not advance", r)
+		}
+		if (len(g.D) == 0) != (r == ' ') {
+			t.Errorf("Glyph '%c' has unexpected path data '%s'", r, g.D)
+		}
+	}
+	if _, ok := f.Glyph('ä'); ok {
+		t.Error("Expected no glyph for 'ä'")
+	}
+}
+
+func TestParseHershey(t *testing.T) {
+	jhf := "    1  3F^KMYM\n    2  6F^K\nMYM RRFRM\n"
+	f, err := ParseHershey(strings.NewReader(jhf), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[rune]Glyph{
+		' ': {D: "M 5 -14 L 19 -14", Advance: 24},
+		'!': {D: "M 5 -14 L 19 -14 M 12 -21 L 12 -14", Advance: 24},
+	}
+	for r, expected := range testCases {
+		if g, ok := f.Glyph(r); !ok || g != expected {
+			t.Errorf("Glyph '%c': expected %v, got %v", r, expected, g)
+		}
+	}
+	if _, err := ParseHershey(strings.NewReader("    1 12F^KM"), "test"); err == nil {
+		t.Error("Expected an error for a truncated glyph")
+	}
+	for _, jhf := range []string{"   32  0\n", "   32 -1\n"} {
+		if _, err := ParseHershey(strings.NewReader(jhf), "test"); err == nil {
+			t.Errorf("Expected an error for glyph header '%s'", strings.TrimSpace(jhf))
+		}
+	}
+}
+
+func TestParseSVGFont(t *testing.T) {
+	f, err := ParseSVGFont(strings.NewReader(svgFontTest), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "Test Font" || f.UnitsPerEm != 1000 || f.SingleLine {
+		t.Errorf("Unexpected font properties: %s, %f, %t", f.Name, f.UnitsPerEm, f.SingleLine)
+	}
+	testCases := map[rune]Glyph{
+		'I': {D: "M 0 0 L 100 0 L 100 -700 L 0 -700 Z", Advance: 500},
+		'W': {D: "M 0 -700 L 400 0 L 800 -700", Advance: 800},
+	}
+	for r, expected := range testCases {
+		if g, ok := f.Glyph(r); !ok || g != expected {
+			t.Errorf("Glyph '%c': expected %v, got %v", r, expected, g)
+		}
+	}
+	if _, ok := f.Glyph('f'); ok {
+		t.Error("Expected ligatures to be ignored")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	f, err := ParseSVGFont(strings.NewReader(svgFontTest), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fonts := Fonts{f, Default()}
+	testCases := map[string]string{
+		"":                               "Test Font",
+		"sans-serif":                     "Test Font",
+		"'Svgocode-Sans', sans-serif":    DefaultName,
+		`Arial, "test font", sans-serif`: "Test Font",
+	}
+	for family, expected := range testCases {
+		if name := fonts.Lookup(family).Name; name != expected {
+			t.Errorf("Font family '%s': expected '%s', got '%s'", family, expected, name)
+		}
+	}
+}
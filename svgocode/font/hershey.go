@@ -0,0 +1,88 @@
+package font
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Hershey fonts (.jhf), cf. https://paulbourke.net/dataformats/hershey/
+//
+// Every glyph starts with its number (5 characters) and its number of vertices
+// (3 characters), followed by the vertices. A vertex is a pair of characters
+// whose coordinates are their offset from 'R'. The first vertex holds the
+// glyph's left and right boundary, " R" lifts the pen. Glyphs may span
+// multiple lines. Glyphs are assigned to characters in order, starting at ' '.
+
+// Position of the baseline in Hershey coordinates
+const hersheyBaseline = 9
+
+// Nominal height of Hershey glyphs
+const hersheyUnitsPerEm = 32
+
+func ParseHershey(r io.Reader, name string) (*Font, error) {
+	f := newFont(name, hersheyUnitsPerEm, true)
+	scanner := bufio.NewScanner(r)
+	var glyph string
+	char := ' '
+	for scanner.Scan() {
+		glyph += strings.TrimRight(scanner.Text(), "\r\n")
+		if len(glyph) == 0 {
+			continue
+		}
+		if len(glyph) < 8 {
+			return nil, fmt.Errorf("malformed glyph header: '%s'", glyph)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(glyph[5:8]))
+		if err != nil {
+			return nil, fmt.Errorf("malformed glyph header: '%s'", glyph[:8])
+		}
+		if count < 1 {
+			// The first vertex holds the glyph's boundaries
+			return nil, fmt.Errorf("malformed glyph header: '%s' (no vertices)", glyph[:8])
+		}
+		if len(glyph) < 8+2*count {
+			// Continued on the next line
+			continue
+		}
+		g, err := parseHersheyGlyph(glyph[8 : 8+2*count])
+		if err != nil {
+			return nil, fmt.Errorf("glyph %s: %s", strings.TrimSpace(glyph[:5]), err.Error())
+		}
+		f.glyphs[char] = g
+		char++
+		glyph = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(f.glyphs) == 0 {
+		return nil, fmt.Errorf("no glyphs found")
+	}
+	return f, nil
+}
+
+func parseHersheyGlyph(vertices string) (Glyph, error) {
+	coord := func(c byte) math64.Float {
+		return math64.Float(int(c) - 'R')
+	}
+	left, right := coord(vertices[0]), coord(vertices[1])
+	var d strings.Builder
+	command := "M"
+	for i := 2; i+1 < len(vertices); i += 2 {
+		if vertices[i:i+2] == " R" {
+			command = "M"
+			continue
+		}
+		if vertices[i] < ' ' || vertices[i+1] < ' ' {
+			return Glyph{}, fmt.Errorf("invalid vertex '%s'", vertices[i:i+2])
+		}
+		fmt.Fprintf(&d, "%s %g %g ", command, coord(vertices[i])-left, coord(vertices[i+1])-hersheyBaseline)
+		command = "L"
+	}
+	return Glyph{D: strings.TrimSpace(d.String()), Advance: right - left}, nil
+}
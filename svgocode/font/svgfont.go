@@ -0,0 +1,133 @@
+package font
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+// SVG fonts, cf. https://www.w3.org/TR/SVG11/fonts.html
+
+type svgFontGlyph struct {
+	Unicode    string  `xml:"unicode,attr"`
+	HorizAdvX  float64 `xml:"horiz-adv-x,attr"`
+	PathString string  `xml:"d,attr"`
+}
+
+type svgFont struct {
+	HorizAdvX float64 `xml:"horiz-adv-x,attr"`
+	FontFace  struct {
+		FontFamily string  `xml:"font-family,attr"`
+		UnitsPerEm float64 `xml:"units-per-em,attr"`
+	} `xml:"font-face"`
+	Glyphs []svgFontGlyph `xml:"glyph"`
+}
+
+// Parse the first 'font' element of an SVG document. The font is named after
+// its font-family (or name, if it has none).
+func ParseSVGFont(r io.Reader, name string) (*Font, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no font element found")
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Local != "font" {
+			continue
+		}
+		var sf svgFont
+		if err := decoder.DecodeElement(&sf, &start); err != nil {
+			return nil, err
+		}
+		return sf.font(name)
+	}
+}
+
+func (sf *svgFont) font(name string) (*Font, error) {
+	if len(sf.FontFace.FontFamily) > 0 {
+		name = sf.FontFace.FontFamily
+	}
+	unitsPerEm := math64.Float(sf.FontFace.UnitsPerEm)
+	if unitsPerEm <= 0 {
+		unitsPerEm = 1000
+	}
+	f := newFont(name, unitsPerEm, false)
+	for _, sg := range sf.Glyphs {
+		r, size := utf8.DecodeRuneInString(sg.Unicode)
+		if r == utf8.RuneError || size != len(sg.Unicode) {
+			// Ligatures are not supported
+			continue
+		}
+		if _, ok := f.glyphs[r]; ok {
+			// The first matching glyph is used
+			continue
+		}
+		g, err := sf.glyph(sg)
+		if err != nil {
+			return nil, fmt.Errorf("glyph '%s': %s", sg.Unicode, err.Error())
+		}
+		f.glyphs[r] = g
+	}
+	if len(f.glyphs) == 0 {
+		return nil, fmt.Errorf("no glyphs found")
+	}
+	return f, nil
+}
+
+func (sf *svgFont) glyph(sg svgFontGlyph) (Glyph, error) {
+	advance := sg.HorizAdvX
+	if advance == 0 {
+		advance = sf.HorizAdvX
+	}
+	commands, err := svg.ParseSVGPath(sg.PathString)
+	if err != nil {
+		return Glyph{}, err
+	}
+	// The y axis of SVG fonts points upwards
+	subpaths, _ := svg.ParseSubpaths(commands)
+	return Glyph{D: flippedPathStr(subpaths), Advance: math64.Float(advance)}, nil
+}
+
+// Express subpaths as path data, mirrored at the x axis
+func flippedPathStr(subpaths []svg.Subpath) string {
+	var d strings.Builder
+	p := func(v math64.VectorF2) string {
+		return fmt.Sprintf("%g %g", v.X, 0-v.Y)
+	}
+	for _, sp := range subpaths {
+		fmt.Fprintf(&d, "M %s ", p(sp.Start))
+		for _, seg := range sp.Segments {
+			switch seg.Type {
+			case svg.SegLine:
+				fmt.Fprintf(&d, "L %s ", p(seg.To))
+			case svg.SegCubic:
+				fmt.Fprintf(&d, "C %s %s %s ", p(seg.Ctrl[0]), p(seg.Ctrl[1]), p(seg.To))
+			case svg.SegQuadratic:
+				fmt.Fprintf(&d, "Q %s %s ", p(seg.Ctrl[0]), p(seg.To))
+			case svg.SegArc:
+				// Mirroring inverts the rotation and the sweep direction
+				sweep := 1
+				if seg.Arc.Sweep {
+					sweep = 0
+				}
+				large := 0
+				if seg.Arc.Large {
+					large = 1
+				}
+				fmt.Fprintf(&d, "A %g %g %g %d %d %s ", seg.Arc.R.X, seg.Arc.R.Y, -seg.Arc.XAxis, large, sweep, p(seg.To))
+			}
+		}
+		if sp.Closed {
+			d.WriteString("Z ")
+		}
+	}
+	return strings.TrimSpace(d.String())
+}
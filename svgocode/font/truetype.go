@@ -0,0 +1,501 @@
+package font
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// TrueType fonts, cf. https://learn.microsoft.com/en-us/typography/opentype/spec/
+// Only quadratic outlines ('glyf' table) are supported, i.e., no CFF-based
+// OpenType fonts. Hinting and kerning are ignored.
+
+var errTrueTypeTruncated = errors.New("truncated font data")
+
+type trueType struct {
+	data        []byte
+	tables      map[string][]byte
+	numGlyphs   int
+	longLoca    bool
+	numHMetrics int
+	cmap        func(r rune) int // Glyph index of a character (0, if missing)
+}
+
+// Parse a TrueType font. The font is named after its family name (or name, if
+// it has none).
+func ParseTrueType(data []byte, name string) (*Font, error) {
+	tt := &trueType{data: data, tables: make(map[string][]byte)}
+	if err := tt.parseTables(); err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "maxp", "cmap", "loca", "glyf", "hhea", "hmtx"} {
+		if _, ok := tt.tables[tag]; !ok {
+			return nil, fmt.Errorf("missing table '%s' (only TrueType outlines are supported)", tag)
+		}
+	}
+	head, maxp, hhea := tt.tables["head"], tt.tables["maxp"], tt.tables["hhea"]
+	if len(head) < 54 || len(maxp) < 6 || len(hhea) < 36 {
+		return nil, errTrueTypeTruncated
+	}
+	unitsPerEm := math64.Float(binary.BigEndian.Uint16(head[18:]))
+	tt.longLoca = binary.BigEndian.Uint16(head[50:]) != 0
+	tt.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+	tt.numHMetrics = int(binary.BigEndian.Uint16(hhea[34:]))
+	if err := tt.parseCmap(); err != nil {
+		return nil, err
+	}
+	if family := tt.familyName(); len(family) > 0 {
+		name = family
+	}
+	f := newFont(name, unitsPerEm, false)
+	f.load = func(r rune) (Glyph, bool) {
+		index := tt.cmap(r)
+		if index == 0 {
+			return Glyph{}, false
+		}
+		contours, err := tt.contours(index, 0)
+		if err != nil {
+			return Glyph{}, false
+		}
+		return Glyph{D: contoursPathStr(contours), Advance: tt.advance(index)}, true
+	}
+	return f, nil
+}
+
+func (tt *trueType) parseTables() error {
+	if len(tt.data) < 12 {
+		return errTrueTypeTruncated
+	}
+	switch binary.BigEndian.Uint32(tt.data) {
+	case 0x00010000, 0x74727565: // 1.0, 'true'
+	default:
+		return errors.New("not a TrueType font")
+	}
+	numTables := int(binary.BigEndian.Uint16(tt.data[4:]))
+	for i := range numTables {
+		record := 12 + 16*i
+		if len(tt.data) < record+16 {
+			return errTrueTypeTruncated
+		}
+		tag := string(tt.data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(tt.data[record+8:]))
+		length := int(binary.BigEndian.Uint32(tt.data[record+12:]))
+		if offset < 0 || length < 0 || len(tt.data) < offset+length {
+			return errTrueTypeTruncated
+		}
+		tt.tables[tag] = tt.data[offset : offset+length]
+	}
+	return nil
+}
+
+// Use the Unicode subtable of the 'cmap' table, preferring format 12 (full
+// Unicode) over format 4 (BMP only)
+func (tt *trueType) parseCmap() error {
+	cmap := tt.tables["cmap"]
+	if len(cmap) < 4 {
+		return errTrueTypeTruncated
+	}
+	var format4, format12 []byte
+	for i := range int(binary.BigEndian.Uint16(cmap[2:])) {
+		record := 4 + 8*i
+		if len(cmap) < record+8 {
+			return errTrueTypeTruncated
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if len(cmap) < offset+2 || !(platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+	switch {
+	case format12 != nil && len(format12) >= 16:
+		numGroups := int(binary.BigEndian.Uint32(format12[12:]))
+		if len(format12) < 16+12*numGroups {
+			return errTrueTypeTruncated
+		}
+		tt.cmap = func(r rune) int {
+			for i := range numGroups {
+				group := format12[16+12*i:]
+				start, end := rune(binary.BigEndian.Uint32(group)), rune(binary.BigEndian.Uint32(group[4:]))
+				if r >= start && r <= end {
+					return int(binary.BigEndian.Uint32(group[8:])) + int(r-start)
+				}
+			}
+			return 0
+		}
+	case format4 != nil && len(format4) >= 14:
+		segCount := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		if len(format4) < 16+8*segCount {
+			return errTrueTypeTruncated
+		}
+		endCodes := format4[14:]
+		startCodes := format4[16+2*segCount:]
+		idDeltas := format4[16+4*segCount:]
+		idRangeOffsets := format4[16+6*segCount:]
+		tt.cmap = func(r rune) int {
+			if r > 0xFFFF {
+				return 0
+			}
+			c := uint16(r)
+			for i := range segCount {
+				if c > binary.BigEndian.Uint16(endCodes[2*i:]) {
+					continue
+				}
+				start := binary.BigEndian.Uint16(startCodes[2*i:])
+				if c < start {
+					return 0
+				}
+				delta := binary.BigEndian.Uint16(idDeltas[2*i:])
+				rangeOffset := int(binary.BigEndian.Uint16(idRangeOffsets[2*i:]))
+				if rangeOffset == 0 {
+					return int(c + delta)
+				}
+				// The offset is relative to the position of the range offset itself
+				pos := 16 + 6*segCount + 2*i + rangeOffset + 2*int(c-start)
+				if len(format4) < pos+2 {
+					return 0
+				}
+				index := binary.BigEndian.Uint16(format4[pos:])
+				if index == 0 {
+					return 0
+				}
+				return int(index + delta)
+			}
+			return 0
+		}
+	default:
+		return errors.New("no supported Unicode character map found")
+	}
+	return nil
+}
+
+// The font's family name (name id 1) from the 'name' table, if available
+func (tt *trueType) familyName() string {
+	table, ok := tt.tables["name"]
+	if !ok || len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := range count {
+		record := 6 + 12*i
+		if len(table) < record+12 {
+			return ""
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		nameID := binary.BigEndian.Uint16(table[record+6:])
+		length := int(binary.BigEndian.Uint16(table[record+8:]))
+		offset := storage + int(binary.BigEndian.Uint16(table[record+10:]))
+		if nameID != 1 || len(table) < offset+length {
+			continue
+		}
+		value := table[offset : offset+length]
+		switch platform {
+		case 0, 3:
+			// UTF-16BE
+			units := make([]uint16, len(value)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(value[2*j:])
+			}
+			return string(utf16.Decode(units))
+		case 1:
+			return string(value)
+		}
+	}
+	return ""
+}
+
+// Horizontal advance of the glyph with the given index
+func (tt *trueType) advance(index int) math64.Float {
+	hmtx := tt.tables["hmtx"]
+	index = min(index, tt.numHMetrics-1)
+	if index < 0 || len(hmtx) < 4*index+2 {
+		return 0
+	}
+	return math64.Float(binary.BigEndian.Uint16(hmtx[4*index:]))
+}
+
+// Outline data of the glyph with the given index
+func (tt *trueType) glyphData(index int) ([]byte, error) {
+	if index < 0 || index >= tt.numGlyphs {
+		return nil, fmt.Errorf("invalid glyph index %d", index)
+	}
+	loca, glyf := tt.tables["loca"], tt.tables["glyf"]
+	var start, end int
+	if tt.longLoca {
+		if len(loca) < 4*index+8 {
+			return nil, errTrueTypeTruncated
+		}
+		start, end = int(binary.BigEndian.Uint32(loca[4*index:])), int(binary.BigEndian.Uint32(loca[4*index+4:]))
+	} else {
+		if len(loca) < 2*index+4 {
+			return nil, errTrueTypeTruncated
+		}
+		start, end = 2*int(binary.BigEndian.Uint16(loca[2*index:])), 2*int(binary.BigEndian.Uint16(loca[2*index+2:]))
+	}
+	if start > end || len(glyf) < end {
+		return nil, errTrueTypeTruncated
+	}
+	return glyf[start:end], nil
+}
+
+// A point of a glyph's contour
+type ttPoint struct {
+	P       math64.VectorF2
+	OnCurve bool
+}
+
+// Maximum nesting depth of composite glyphs
+const ttMaxDepth = 8
+
+// The contours of the glyph with the given index, in font units (y axis
+// pointing upwards)
+func (tt *trueType) contours(index int, depth int) ([][]ttPoint, error) {
+	if depth > ttMaxDepth {
+		return nil, errors.New("composite glyphs are nested too deeply")
+	}
+	data, err := tt.glyphData(index)
+	if err != nil || len(data) == 0 {
+		// Empty glyphs, e.g., space
+		return nil, err
+	}
+	if len(data) < 10 {
+		return nil, errTrueTypeTruncated
+	}
+	numContours := int(int16(binary.BigEndian.Uint16(data)))
+	if numContours < 0 {
+		return tt.compositeContours(data[10:], depth)
+	}
+	return simpleContours(data[10:], numContours)
+}
+
+func simpleContours(data []byte, numContours int) ([][]ttPoint, error) {
+	const (
+		flagOnCurve = 0x01
+		flagXShort  = 0x02
+		flagYShort  = 0x04
+		flagRepeat  = 0x08
+		flagXSame   = 0x10 // Or positive, if short
+		flagYSame   = 0x20 // Or positive, if short
+	)
+	if len(data) < 2*numContours+2 {
+		return nil, errTrueTypeTruncated
+	}
+	endPoints := make([]int, numContours)
+	for i := range endPoints {
+		endPoints[i] = int(binary.BigEndian.Uint16(data[2*i:]))
+	}
+	numPoints := 0
+	if numContours > 0 {
+		numPoints = endPoints[numContours-1] + 1
+	}
+	pos := 2*numContours + 2 + int(binary.BigEndian.Uint16(data[2*numContours:])) // Skip instructions
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if len(data) <= pos {
+			return nil, errTrueTypeTruncated
+		}
+		flag := data[pos]
+		pos++
+		flags = append(flags, flag)
+		if flag&flagRepeat != 0 {
+			if len(data) <= pos {
+				return nil, errTrueTypeTruncated
+			}
+			for range int(data[pos]) {
+				flags = append(flags, flag)
+			}
+			pos++
+		}
+	}
+	flags = flags[:numPoints]
+	// Coordinates are deltas to the previous point
+	readCoords := func(short, same byte) ([]math64.Float, error) {
+		coords := make([]math64.Float, numPoints)
+		var value int
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if len(data) <= pos {
+					return nil, errTrueTypeTruncated
+				}
+				delta := int(data[pos])
+				pos++
+				if flag&same == 0 {
+					delta = -delta
+				}
+				value += delta
+			case flag&same == 0:
+				if len(data) < pos+2 {
+					return nil, errTrueTypeTruncated
+				}
+				value += int(int16(binary.BigEndian.Uint16(data[pos:])))
+				pos += 2
+			}
+			coords[i] = math64.Float(value)
+		}
+		return coords, nil
+	}
+	xs, err := readCoords(flagXShort, flagXSame)
+	if err != nil {
+		return nil, err
+	}
+	ys, err := readCoords(flagYShort, flagYSame)
+	if err != nil {
+		return nil, err
+	}
+	var contours [][]ttPoint
+	start := 0
+	for _, end := range endPoints {
+		if end < start || end >= numPoints {
+			return nil, errors.New("invalid contour end point")
+		}
+		contour := make([]ttPoint, 0, end-start+1)
+		for i := start; i <= end; i++ {
+			contour = append(contour, ttPoint{P: math64.VectorF2{X: xs[i], Y: ys[i]}, OnCurve: flags[i]&flagOnCurve != 0})
+		}
+		contours = append(contours, contour)
+		start = end + 1
+	}
+	return contours, nil
+}
+
+// Composite glyphs consist of transformed components (other glyphs).
+// Components that are positioned by matching points are not offset.
+func (tt *trueType) compositeContours(data []byte, depth int) ([][]ttPoint, error) {
+	const (
+		flagArgsAreWords  = 0x0001
+		flagArgsAreXY     = 0x0002
+		flagScale         = 0x0008
+		flagMoreComponent = 0x0020
+		flagXYScale       = 0x0040
+		flagTwoByTwo      = 0x0080
+	)
+	var contours [][]ttPoint
+	pos := 0
+	for {
+		if len(data) < pos+4 {
+			return nil, errTrueTypeTruncated
+		}
+		flags := binary.BigEndian.Uint16(data[pos:])
+		index := int(binary.BigEndian.Uint16(data[pos+2:]))
+		pos += 4
+		var dx, dy math64.Float
+		if flags&flagArgsAreWords != 0 {
+			if len(data) < pos+4 {
+				return nil, errTrueTypeTruncated
+			}
+			dx, dy = math64.Float(int16(binary.BigEndian.Uint16(data[pos:]))), math64.Float(int16(binary.BigEndian.Uint16(data[pos+2:])))
+			pos += 4
+		} else {
+			if len(data) < pos+2 {
+				return nil, errTrueTypeTruncated
+			}
+			dx, dy = math64.Float(int8(data[pos])), math64.Float(int8(data[pos+1]))
+			pos += 2
+		}
+		if flags&flagArgsAreXY == 0 {
+			dx, dy = 0, 0
+		}
+		// 2x2 matrix in F2Dot14 format
+		f2dot14 := func() math64.Float {
+			v := math64.Float(int16(binary.BigEndian.Uint16(data[pos:]))) / (1 << 14)
+			pos += 2
+			return v
+		}
+		m := [4]math64.Float{1, 0, 0, 1}
+		switch {
+		case flags&flagScale != 0 && len(data) >= pos+2:
+			m[0] = f2dot14()
+			m[3] = m[0]
+		case flags&flagXYScale != 0 && len(data) >= pos+4:
+			m[0], m[3] = f2dot14(), f2dot14()
+		case flags&flagTwoByTwo != 0 && len(data) >= pos+8:
+			m[0], m[1], m[2], m[3] = f2dot14(), f2dot14(), f2dot14(), f2dot14()
+		}
+		component, err := tt.contours(index, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, contour := range component {
+			for i, p := range contour {
+				contour[i].P = math64.VectorF2{X: m[0]*p.P.X + m[2]*p.P.Y + dx, Y: m[1]*p.P.X + m[3]*p.P.Y + dy}
+			}
+			contours = append(contours, contour)
+		}
+		if flags&flagMoreComponent == 0 {
+			return contours, nil
+		}
+	}
+}
+
+// Express quadratic contours as path data. Consecutive off-curve points imply
+// an on-curve point in-between. The y axis is flipped.
+func contoursPathStr(contours [][]ttPoint) string {
+	var d strings.Builder
+	p := func(v math64.VectorF2) string {
+		return fmt.Sprintf("%g %g", v.X, -v.Y)
+	}
+	mid := func(a, b math64.VectorF2) math64.VectorF2 {
+		return a.Add(b).Scale(0.5)
+	}
+	for _, contour := range contours {
+		if len(contour) == 0 {
+			continue
+		}
+		// Start at an on-curve point
+		first := 0
+		for first < len(contour) && !contour[first].OnCurve {
+			first++
+		}
+		var start math64.VectorF2
+		if first == len(contour) {
+			first = 0
+			start = mid(contour[len(contour)-1].P, contour[0].P)
+		} else {
+			start = contour[first].P
+			first++
+		}
+		fmt.Fprintf(&d, "M %s ", p(start))
+		var ctrl *math64.VectorF2
+		for i := range len(contour) {
+			point := contour[(first+i)%len(contour)]
+			if i == len(contour)-1 && point.OnCurve {
+				// Back at the start, which Z connects to
+				if ctrl != nil {
+					fmt.Fprintf(&d, "Q %s %s ", p(*ctrl), p(start))
+					ctrl = nil
+				}
+				break
+			}
+			switch {
+			case point.OnCurve && ctrl == nil:
+				fmt.Fprintf(&d, "L %s ", p(point.P))
+			case point.OnCurve:
+				fmt.Fprintf(&d, "Q %s %s ", p(*ctrl), p(point.P))
+				ctrl = nil
+			case ctrl != nil:
+				fmt.Fprintf(&d, "Q %s %s ", p(*ctrl), p(mid(*ctrl, point.P)))
+				ctrl = &point.P
+			default:
+				ctrl = &point.P
+			}
+		}
+		if ctrl != nil {
+			fmt.Fprintf(&d, "Q %s %s ", p(*ctrl), p(start))
+		}
+		d.WriteString("Z ")
+	}
+	return strings.TrimSpace(d.String())
+}
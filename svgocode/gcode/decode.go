@@ -0,0 +1,252 @@
+package gcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Modal state of a gcode interpreter
+type ModalState struct {
+	Position math64.VectorF3   // Current position, in Unit
+	Absolute bool              // G90 (absolute) or G91 (relative) positioning
+	Unit     math64.UnitLength // G21 (mm) or G20 (in)
+	Feedrate math64.Speed      // Last feedrate (F), in Unit per minute
+	Motion   math64.Float      // Last motion command (0: G0, 1: G1, 2: G2, 3: G3), repeated for lines without command
+}
+
+func NewModalState() ModalState {
+	return ModalState{Absolute: true, Unit: math64.UnitMM, Motion: 0}
+}
+
+// Parses gcode text into Gcode, tracking the modal state (positioning mode,
+// unit, feedrate) for determining start/end coordinates and bounds.
+type Decoder struct {
+	r     io.Reader
+	Unit  math64.UnitLength // Unit of the decoded coordinates. Defaults to mm.
+	state ModalState
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	d := new(Decoder)
+	d.r = r
+	d.Unit = math64.UnitMM
+	d.state = NewModalState()
+	return d
+}
+
+// The modal state after the last decoded line
+func (d *Decoder) State() ModalState {
+	return d.state
+}
+
+// Read all gcode from the reader into g. Lines that cannot be parsed are kept
+// verbatim. Start and end coordinates as well as bounds are expressed in
+// d.Unit.
+func (d *Decoder) Decode(g *Gcode) error {
+	scanner := bufio.NewScanner(d.r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	moved := false
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		ins, err := ParseInstruction(line)
+		if err != nil {
+			llog.Debugf("Line %d: %s. Keeping it verbatim.\n", lineNum, err.Error())
+		}
+		g.AppendInstructions(ins)
+		points, err := d.apply(ins)
+		if err != nil {
+			return errors.New(fmt.Sprintf("failed to decode gcode in line %d: %s", lineNum, err.Error()))
+		}
+		for i, p := range points {
+			p = d.convert(p)
+			if !moved {
+				g.StartCoord = p
+				g.BoundsMin = p
+				g.BoundsMax = p
+				moved = true
+			}
+			g.BoundsMin = g.BoundsMin.Min(p)
+			g.BoundsMax = g.BoundsMax.Max(p)
+			if i == len(points)-1 {
+				g.EndCoord = p
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Convert a position from the modal unit to the decoder's unit
+func (d *Decoder) convert(p math64.VectorF3) math64.VectorF3 {
+	return math64.VectorF3{
+		X: math64.LengthConvert(p.X, d.state.Unit, d.Unit),
+		Y: math64.LengthConvert(p.Y, d.state.Unit, d.Unit),
+		Z: math64.LengthConvert(p.Z, d.state.Unit, d.Unit),
+	}
+}
+
+// Apply the instruction to the modal state. Returns the positions that are
+// relevant for bounds: for moves, the target (and, for arcs, the extreme
+// points of the arc), in the modal unit.
+func (d *Decoder) apply(ins Instruction) ([]math64.VectorF3, error) {
+	s := &d.state
+	if f, ok := ins.Word('F'); ok {
+		s.Feedrate = math64.Speed(f)
+	}
+	if !ins.IsCode() || len(ins.Raw) > 0 {
+		return nil, nil
+	}
+	switch {
+	case ins.Letter == 0:
+		// Continue the last motion command, if coordinates are given
+		if !hasAxisWord(ins) {
+			return nil, nil
+		}
+		return d.motion(s.Motion, ins)
+	case ins.Letter != 'G':
+		return nil, nil
+	case ins.Number == 0 || ins.Number == 1 || ins.Number == 2 || ins.Number == 3:
+		s.Motion = ins.Number
+		if ins.Number <= 1 && !hasAxisWord(ins) {
+			// Only sets the motion mode (and, e.g., the feedrate)
+			return nil, nil
+		}
+		return d.motion(ins.Number, ins)
+	case ins.Number == 20:
+		s.Position = convertVector(s.Position, s.Unit, math64.UnitIN)
+		s.Unit = math64.UnitIN
+	case ins.Number == 21:
+		s.Position = convertVector(s.Position, s.Unit, math64.UnitMM)
+		s.Unit = math64.UnitMM
+	case ins.Number == 90:
+		s.Absolute = true
+	case ins.Number == 91:
+		s.Absolute = false
+	case ins.Number == 28:
+		// Homing: move to the origin of the given axes (or of all axes)
+		all := !hasAxisWord(ins)
+		for _, w := range ins.Words {
+			switch w.Letter {
+			case 'X':
+				s.Position.X = 0
+			case 'Y':
+				s.Position.Y = 0
+			case 'Z':
+				s.Position.Z = 0
+			}
+		}
+		if all {
+			s.Position = math64.VectorF3{X: 0, Y: 0, Z: 0}
+		}
+		return []math64.VectorF3{s.Position}, nil
+	case ins.Number == 92:
+		// Set position without moving
+		if x, ok := ins.Word('X'); ok {
+			s.Position.X = x
+		}
+		if y, ok := ins.Word('Y'); ok {
+			s.Position.Y = y
+		}
+		if z, ok := ins.Word('Z'); ok {
+			s.Position.Z = z
+		}
+	}
+	return nil, nil
+}
+
+func hasAxisWord(ins Instruction) bool {
+	for _, w := range ins.Words {
+		switch w.Letter {
+		case 'X', 'Y', 'Z':
+			return true
+		}
+	}
+	return false
+}
+
+func convertVector(v math64.VectorF3, from, to math64.UnitLength) math64.VectorF3 {
+	return math64.VectorF3{
+		X: math64.LengthConvert(v.X, from, to),
+		Y: math64.LengthConvert(v.Y, from, to),
+		Z: math64.LengthConvert(v.Z, from, to),
+	}
+}
+
+// Move to the instruction's target, using the given motion command
+func (d *Decoder) motion(number math64.Float, ins Instruction) ([]math64.VectorF3, error) {
+	s := &d.state
+	start := s.Position
+	target := start
+	for _, axis := range []struct {
+		letter byte
+		value  *math64.Float
+	}{{'X', &target.X}, {'Y', &target.Y}, {'Z', &target.Z}} {
+		if v, ok := ins.Word(axis.letter); ok {
+			if s.Absolute {
+				*axis.value = v
+			} else {
+				*axis.value += v
+			}
+		}
+	}
+	s.Position = target
+	if number != 2 && number != 3 {
+		return []math64.VectorF3{target}, nil
+	}
+	start2 := math64.VectorF2{X: start.X, Y: start.Y}
+	target2 := math64.VectorF2{X: target.X, Y: target.Y}
+	clockwise := number == 2
+	var center math64.VectorF2
+	if r, ok := ins.Word('R'); ok {
+		c, err := arcCenterFromRadius(start2, target2, r, clockwise)
+		if err != nil {
+			return nil, err
+		}
+		center = c
+	} else {
+		i, okI := ins.Word('I')
+		j, okJ := ins.Word('J')
+		if !okI && !okJ {
+			return nil, errors.New("arc without I, J, or R")
+		}
+		center = start2.Add(math64.VectorF2{X: i, Y: j})
+	}
+	arc := math64.Arc{Start: start2, End: target2, Center: center, CCW: !clockwise}
+	arcMin, arcMax := arc.Bounds()
+	return []math64.VectorF3{
+		{X: arcMin.X, Y: arcMin.Y, Z: start.Z.Min(target.Z)},
+		{X: arcMax.X, Y: arcMax.Y, Z: start.Z.Max(target.Z)},
+		target,
+	}, nil
+}
+
+// Determine the center of an arc in radius format. Negative radii select the
+// arc that spans more than 180°.
+func arcCenterFromRadius(start, end math64.VectorF2, r math64.Float, clockwise bool) (math64.VectorF2, error) {
+	chord := end.Sub(start)
+	half := chord.Length() / 2
+	if half == 0 {
+		return math64.VectorF2{}, errors.New("arc in radius format with identical start and end")
+	}
+	h2 := r*r - half*half
+	if h2 < 0 {
+		if h2 < -1e-6*r*r {
+			return math64.VectorF2{}, errors.New(fmt.Sprintf("arc radius %f is too small", r))
+		}
+		h2 = 0
+	}
+	h := math64.Float(math.Sqrt(float64(h2)))
+	// Center lies on the chord's perpendicular bisector; to the right of the
+	// chord for clockwise arcs below 180°
+	normal := math64.VectorF2{X: -chord.Y, Y: chord.X}.Normalize()
+	if clockwise == (r > 0) {
+		h = -h
+	}
+	return start.Add(chord.Scale(0.5)).Add(normal.Scale(h)), nil
+}
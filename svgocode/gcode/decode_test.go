@@ -0,0 +1,91 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+func TestDecode(t *testing.T) {
+	text := `; Test
+G21 ; mm
+G90
+G0 X10 Y10 Z5 F3000
+G1 X20 F1000
+X30 Y20
+G91
+G1 Y-5
+G90
+G2 X30 Y15 I0 J5 ; Full circle around (30, 20) from (30, 15)
+G20
+G1 X2 Y1
+M117 Hello world`
+	g := NewGcode()
+	d := NewDecoder(strings.NewReader(text))
+	if err := d.Decode(g); err != nil {
+		t.Fatalf("Failed to decode gcode: %s", err.Error())
+	}
+	if g.Code.NumLines() != 13 || g.Code.NumComments() != 3 {
+		t.Errorf("Unexpected number of lines (%d) or comments (%d)", g.Code.NumLines(), g.Code.NumComments())
+	}
+	expect := func(name string, got, want math64.VectorF3) {
+		if got.DistEuclid(want) > 1e-9 {
+			t.Errorf("Unexpected %s: expected %s, got %s", name, want.String(), got.String())
+		}
+	}
+	expect("start", g.StartCoord, math64.VectorF3{X: 10, Y: 10, Z: 5})
+	expect("end", g.EndCoord, math64.VectorF3{X: 50.8, Y: 25.4, Z: 5})
+	// The circle reaches X25 (left) and Y25 (top); the last move reaches X50.8
+	expect("min", g.BoundsMin, math64.VectorF3{X: 10, Y: 10, Z: 5})
+	expect("max", g.BoundsMax, math64.VectorF3{X: 50.8, Y: 25.4, Z: 5})
+	state := d.State()
+	if state.Unit != math64.UnitIN || !state.Absolute || state.Feedrate != 1000 || state.Motion != 1 {
+		t.Errorf("Unexpected modal state: %+v", state)
+	}
+
+	// Full circle around (0, 0)
+	g = NewGcode()
+	if err := NewDecoder(strings.NewReader("G0 X0 Y-5\nG2 X0 Y-5 I0 J5")).Decode(g); err != nil {
+		t.Fatalf("Failed to decode gcode: %s", err.Error())
+	}
+	expect("min", g.BoundsMin, math64.VectorF3{X: -5, Y: -5, Z: 0})
+	expect("max", g.BoundsMax, math64.VectorF3{X: 5, Y: 5, Z: 0})
+
+	// Moves without coordinates do not move (and do not count as start)
+	g = NewGcode()
+	if err := NewDecoder(strings.NewReader("G21\nG0 F3000\nG0 X10 Y10 Z5\nG1 X20 Y20")).Decode(g); err != nil {
+		t.Fatalf("Failed to decode gcode: %s", err.Error())
+	}
+	expect("start", g.StartCoord, math64.VectorF3{X: 10, Y: 10, Z: 5})
+	expect("min", g.BoundsMin, math64.VectorF3{X: 10, Y: 10, Z: 5})
+	expect("max", g.BoundsMax, math64.VectorF3{X: 20, Y: 20, Z: 5})
+
+	// Arcs in radius format: quarter circle from (0, 0) to (10, 10)
+	g = NewGcode()
+	if err := NewDecoder(strings.NewReader("G0 X0 Y0\nG3 X10 Y10 R10")).Decode(g); err != nil {
+		t.Fatalf("Failed to decode gcode: %s", err.Error())
+	}
+	expect("min", g.BoundsMin, math64.VectorF3{X: 0, Y: 0, Z: 0})
+	expect("max", g.BoundsMax, math64.VectorF3{X: 10, Y: 10, Z: 0})
+}
+
+func TestDecodeEncoded(t *testing.T) {
+	// Decoding encoded gcode reproduces its code and metadata
+	g := NewGcode()
+	g.AppendCode("G0 X1 Y2 Z3 F4000 ; Moving\nG1 X-4 Y2 Z3\nG3 X2 Y2 I3 J0")
+	var b strings.Builder
+	if err := NewEncoder(&b).Encode(g); err != nil {
+		t.Fatalf("Failed to encode gcode: %s", err.Error())
+	}
+	g2 := NewGcode()
+	if err := NewDecoder(strings.NewReader(b.String())).Decode(g2); err != nil {
+		t.Fatalf("Failed to decode gcode: %s", err.Error())
+	}
+	if g2.String() != b.String() {
+		t.Errorf("Decoded gcode differs:\n%s\n%s", g2.String(), b.String())
+	}
+	if !g2.BoundsMin.Equal(math64.VectorF3{X: -4, Y: -1, Z: 3}) || !g2.BoundsMax.Equal(math64.VectorF3{X: 2, Y: 2, Z: 3}) {
+		t.Errorf("Unexpected bounds %s %s", g2.BoundsMin.String(), g2.BoundsMax.String())
+	}
+}
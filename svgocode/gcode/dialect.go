@@ -0,0 +1,125 @@
+package gcode
+
+import (
+	"github.com/abzicht/svgocode/llog"
+)
+
+type DialectName string
+
+const (
+	DialectNameMarlin       = DialectName("marlin")
+	DialectNameGRBL         = DialectName("grbl")
+	DialectNameKlipper      = DialectName("klipper")
+	DialectNameLinuxCNC     = DialectName("linuxcnc")
+	DialectNameSmoothieware = DialectName("smoothieware")
+)
+
+type CommentStyle string
+
+const (
+	CommentSemicolon   = CommentStyle(";")  // '; comment'
+	CommentParentheses = CommentStyle("()") // '(comment)'
+)
+
+// Firmware-specific flavor of gcode, used when serializing instructions.
+type Dialect struct {
+	Name           DialectName
+	OmitWords      string       // Parameter words that the firmware does not understand (e.g., "E"). They are removed.
+	Comments       CommentStyle // How comments are written
+	InlineComments bool         // Comments may follow instructions on the same line. Else, they are placed on a line of their own.
+	RepeatFeedrate bool         // Emit F words, even if the feedrate did not change
+	RepeatAxes     bool         // Emit all axis words of linear moves, even if the axis' position did not change
+	Precision      int          // Maximum number of decimal places of numbers
+	DwellSeconds   bool         // The P word of G4 (dwell) is given in seconds instead of milliseconds
+	Messages       bool         // The firmware displays messages to the user via 'M117 <message>'
+	LineEnding     string
+}
+
+// Dialects return new instances, so that callers may adjust them.
+
+func NewDialectMarlin() *Dialect {
+	return &Dialect{
+		Name:           DialectNameMarlin,
+		Comments:       CommentSemicolon,
+		InlineComments: true,
+		RepeatFeedrate: true,
+		RepeatAxes:     true,
+		Precision:      6,
+		Messages:       true,
+		LineEnding:     "\n",
+	}
+}
+
+func NewDialectGRBL() *Dialect {
+	return &Dialect{
+		Name:           DialectNameGRBL,
+		OmitWords:      "E",
+		Comments:       CommentParentheses,
+		InlineComments: false,
+		RepeatFeedrate: false,
+		RepeatAxes:     false,
+		Precision:      3,
+		DwellSeconds:   true,
+		LineEnding:     "\n",
+	}
+}
+
+func NewDialectKlipper() *Dialect {
+	return &Dialect{
+		Name:           DialectNameKlipper,
+		Comments:       CommentSemicolon,
+		InlineComments: true,
+		RepeatFeedrate: false,
+		RepeatAxes:     false,
+		Precision:      3,
+		Messages:       true,
+		LineEnding:     "\n",
+	}
+}
+
+func NewDialectLinuxCNC() *Dialect {
+	return &Dialect{
+		Name:           DialectNameLinuxCNC,
+		OmitWords:      "E",
+		Comments:       CommentParentheses,
+		InlineComments: true,
+		RepeatFeedrate: false,
+		RepeatAxes:     true,
+		Precision:      4,
+		DwellSeconds:   true,
+		LineEnding:     "\n",
+	}
+}
+
+func NewDialectSmoothieware() *Dialect {
+	return &Dialect{
+		Name:           DialectNameSmoothieware,
+		Comments:       CommentSemicolon,
+		InlineComments: true,
+		RepeatFeedrate: false,
+		RepeatAxes:     false,
+		Precision:      4,
+		Messages:       true,
+		LineEnding:     "\n",
+	}
+}
+
+func ParseDialect(name DialectName) *Dialect {
+	switch name {
+	case DialectName(""): // Default is Marlin
+		fallthrough
+	case DialectNameMarlin:
+		return NewDialectMarlin()
+	case DialectNameGRBL:
+		return NewDialectGRBL()
+	case DialectNameKlipper:
+		return NewDialectKlipper()
+	case DialectNameLinuxCNC:
+		return NewDialectLinuxCNC()
+	case DialectNameSmoothieware:
+		return NewDialectSmoothieware()
+	default:
+		llog.Panicf("Unknown gcode dialect: '%s'", name)
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package gcode
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Renders instructions as text, following a gcode dialect
+type Formatter struct {
+	dialect *Dialect
+}
+
+func NewFormatter(dialect *Dialect) *Formatter {
+	f := new(Formatter)
+	f.dialect = dialect
+	return f
+}
+
+var defaultFormatter = NewFormatter(NewDialectMarlin())
+
+// Format a number with at most the dialect's number of decimal places,
+// without trailing zeros
+func (f *Formatter) FormatFloat(v math64.Float) string {
+	s := strconv.FormatFloat(float64(v), 'f', f.dialect.Precision, 64)
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+func (f *Formatter) formatComment(comment string) string {
+	if f.dialect.Comments == CommentParentheses {
+		// Parentheses cannot be nested
+		comment = strings.NewReplacer("(", "[", ")", "]").Replace(comment)
+		return "(" + comment + ")"
+	}
+	if len(comment) == 0 {
+		return ";"
+	}
+	return "; " + comment
+}
+
+// Format a single instruction. If the dialect does not permit inline comments,
+// the result spans two lines.
+func (f *Formatter) Format(i Instruction) string {
+	var b strings.Builder
+	if len(i.Raw) > 0 {
+		b.WriteString(i.Raw)
+	} else if i.Letter != 0 {
+		b.WriteByte(i.Letter)
+		b.WriteString(strconv.FormatFloat(float64(i.Number), 'f', -1, 64))
+	}
+	for _, w := range i.Words {
+		if strings.IndexByte(f.dialect.OmitWords, w.Letter) >= 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(w.Letter)
+		if !w.Flag {
+			b.WriteString(f.FormatFloat(w.Value))
+		}
+	}
+	if len(i.Text) > 0 {
+		b.WriteByte(' ')
+		b.WriteString(i.Text)
+	}
+	if !i.HasComment {
+		return b.String()
+	}
+	if b.Len() == 0 {
+		return f.formatComment(i.Comment)
+	}
+	if !f.dialect.InlineComments {
+		return f.formatComment(i.Comment) + f.dialect.LineEnding + b.String()
+	}
+	return b.String() + " " + f.formatComment(i.Comment)
+}
+
+// Modal state for omitting words that do not change anything
+type formatState struct {
+	absolute bool
+	axes     map[byte]string // Last formatted value of X, Y, and Z (if known)
+	feedrate string          // Last formatted feedrate (if known)
+}
+
+func (s *formatState) forget() {
+	clear(s.axes)
+	s.feedrate = ""
+}
+
+// Format a sequence of instructions. Words are omitted, if the dialect does
+// not require them to be repeated. Linear moves that are left without effect
+// and instructions whose words are all omitted by the dialect (e.g., 'G92 E0'
+// without extruder, which GRBL rejects) are dropped.
+func (f *Formatter) FormatCode(lines []Instruction) string {
+	var b strings.Builder
+	s := formatState{absolute: true, axes: make(map[byte]string)}
+	for _, i := range lines {
+		i, ok := f.modal(&s, i)
+		if !ok {
+			continue
+		}
+		b.WriteString(f.Format(i))
+		b.WriteString(f.dialect.LineEnding)
+	}
+	return b.String()
+}
+
+// Update the modal state with the instruction and remove words that are
+// omitted by the dialect. Returns false, if the instruction can (or must) be
+// dropped.
+func (f *Formatter) modal(s *formatState, i Instruction) (Instruction, bool) {
+	if len(i.Raw) > 0 {
+		// Who knows what the line does
+		s.forget()
+		return i, true
+	}
+	if i.Letter == 'G' {
+		switch i.Number {
+		case 90:
+			s.absolute = true
+		case 91:
+			s.absolute = false
+			s.forget()
+		case 20, 21, 28, 92:
+			s.forget()
+		}
+	}
+	linear := i.Is('G', 0) || i.Is('G', 1) || (i.Letter == 0 && len(i.Words) > 0)
+	motion := linear || i.Is('G', 2) || i.Is('G', 3)
+	words := make([]Word, 0, len(i.Words))
+	omitted := 0
+	for _, w := range i.Words {
+		if strings.IndexByte(f.dialect.OmitWords, w.Letter) >= 0 {
+			omitted++
+			continue
+		}
+		value := f.FormatFloat(w.Value)
+		switch w.Letter {
+		case 'F':
+			if !w.Flag && !f.dialect.RepeatFeedrate && value == s.feedrate {
+				continue
+			}
+			s.feedrate = value
+		case 'X', 'Y', 'Z':
+			if !motion || w.Flag {
+				break
+			}
+			if !s.absolute {
+				s.forget()
+				break
+			}
+			if last, known := s.axes[w.Letter]; linear && !f.dialect.RepeatAxes && known && last == value {
+				continue
+			}
+			s.axes[w.Letter] = value
+		}
+		words = append(words, w)
+	}
+	if len(i.Words) > 0 && omitted == len(i.Words) {
+		// Without its words, the instruction would be invalid or mean
+		// something else
+		return i, false
+	}
+	if linear && len(i.Words) > 0 && len(words) == 0 {
+		return i, false
+	}
+	i.Words = words
+	if len(words) == 0 {
+		i.Words = nil
+	}
+	return i, true
+}
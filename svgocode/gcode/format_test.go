@@ -0,0 +1,44 @@
+package gcode
+
+import "testing"
+
+func formatLines(dialect *Dialect, lines ...string) string {
+	c := NewCode()
+	c.AppendLines(lines...)
+	return c.Format(NewFormatter(dialect))
+}
+
+func TestFormatDialects(t *testing.T) {
+	lines := []string{
+		"G21 ; Setting unit",
+		"G92 E0",
+		"G0 X1.23456 Y2 Z3 F4000 ; Moving",
+		"G0 X1.23456 Y2 Z3 ; Moving",
+		"G1 Z1 F2000 (Lowering)",
+		"G1 X5 Y2 Z1 F2000",
+		"G1 E5",
+	}
+	expected := map[DialectName]string{
+		DialectNameMarlin: "G21 ; Setting unit\nG92 E0\nG0 X1.23456 Y2 Z3 F4000 ; Moving\nG0 X1.23456 Y2 Z3 ; Moving\n" +
+			"G1 Z1 F2000 ; Lowering\nG1 X5 Y2 Z1 F2000\nG1 E5\n",
+		DialectNameGRBL: "(Setting unit)\nG21\n(Moving)\nG0 X1.235 Y2 Z3 F4000\n" +
+			"(Lowering)\nG1 Z1 F2000\nG1 X5\n",
+		DialectNameLinuxCNC: "G21 (Setting unit)\nG0 X1.2346 Y2 Z3 F4000 (Moving)\nG0 X1.2346 Y2 Z3 (Moving)\n" +
+			"G1 Z1 F2000 (Lowering)\nG1 X5 Y2 Z1\n",
+		DialectNameKlipper: "G21 ; Setting unit\nG92 E0\nG0 X1.235 Y2 Z3 F4000 ; Moving\n" +
+			"G1 Z1 F2000 ; Lowering\nG1 X5\nG1 E5\n",
+	}
+	for name, want := range expected {
+		if got := formatLines(ParseDialect(name), lines...); got != want {
+			t.Errorf("Dialect %s: expected\n%s\ngot\n%s", name, want, got)
+		}
+	}
+}
+
+func TestFormatRelative(t *testing.T) {
+	got := formatLines(NewDialectGRBL(), "G91", "G1 X1 Y1", "G1 X1 Y1", "G90", "G1 X1 Y1", "G1 X1 Y1")
+	want := "G91\nG1 X1 Y1\nG1 X1 Y1\nG90\nG1 X1 Y1\n"
+	if got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
@@ -2,27 +2,17 @@ package gcode
 
 import (
 	"math"
-	"regexp"
 	"slices"
 	"strings"
 
 	"github.com/abzicht/svgocode/llog"
-	"github.com/abzicht/svgocode/svgocode/conf"
 	"github.com/abzicht/svgocode/svgocode/math64"
 )
 
-// Match all lines that start with a comment
-var reLineComment = regexp.MustCompile("^\\s*;(.*)$")
-
-// Match all lines that contain a comment
-var reComment = regexp.MustCompile("^.*;(.*)$")
-
-// Match all lines that contain an instruction
-var reInstruction = regexp.MustCompile("^\\s*([GMTgmt][\\d]+[^;]*)")
-
-// Pure code
+// Pure code, one instruction per line
 type Code struct {
-	lines []string
+	lines     []Instruction
+	formatter *Formatter
 }
 
 func NewCode() *Code {
@@ -32,6 +22,7 @@ func NewCode() *Code {
 func (c *Code) Copy() *Code {
 	c2 := NewCode()
 	c2.lines = slices.Clone(c.lines)
+	c2.formatter = c.formatter
 	return c2
 }
 
@@ -42,7 +33,7 @@ func (c *Code) NumLines() int {
 func (c *Code) NumInstructions() int {
 	counter := 0
 	for _, line := range c.lines {
-		if reInstruction.MatchString(line) {
+		if line.IsCode() {
 			counter += 1
 		}
 	}
@@ -52,7 +43,7 @@ func (c *Code) NumInstructions() int {
 func (c *Code) NumComments() int {
 	counter := 0
 	for _, line := range c.lines {
-		if reComment.MatchString(line) {
+		if line.HasComment {
 			counter += 1
 		}
 	}
@@ -61,28 +52,59 @@ func (c *Code) NumComments() int {
 
 func (c *Code) RemoveComments() *Code {
 	c2 := NewCode()
+	c2.formatter = c.formatter
 	for _, line := range c.lines {
-		if reLineComment.MatchString(line) {
-			continue
+		if line.HasComment {
+			if !line.IsCode() {
+				continue
+			}
+			line = line.WithoutComment()
 		}
-		if reComment.MatchString(line) {
-			line = reInstruction.FindString(line)
-		}
-		c2.AppendLines(line)
+		c2.AppendInstructions(line)
 	}
 	return c2
 }
 
+// The instructions of the code. Must not be modified.
+func (c *Code) Instructions() []Instruction {
+	return c.lines
+}
+
+// Set the formatter that is used by String (e.g., for a specific dialect)
+func (c *Code) SetFormatter(f *Formatter) {
+	c.formatter = f
+}
+
+// Serialize the code with the given formatter
+func (c *Code) Format(f *Formatter) string {
+	return f.FormatCode(c.lines)
+}
+
 func (c *Code) String() string {
-	return strings.Join(c.lines, "\n") + "\n"
+	if c.formatter == nil {
+		return c.Format(defaultFormatter)
+	}
+	return c.Format(c.formatter)
 }
 
+// Parse and append lines of gcode. Lines that cannot be parsed are kept
+// verbatim.
 func (c *Code) AppendLines(lines ...string) {
-	c.lines = append(c.lines, lines...)
+	for _, line := range lines {
+		ins, err := ParseInstruction(line)
+		if err != nil {
+			llog.Debugf("Keeping gcode verbatim: %s\n", err.Error())
+		}
+		c.lines = append(c.lines, ins)
+	}
+}
+
+func (c *Code) AppendInstructions(ins ...Instruction) {
+	c.lines = append(c.lines, ins...)
 }
 
 func (c *Code) Append(c2 *Code) {
-	c.AppendLines(c2.lines...)
+	c.AppendInstructions(c2.lines...)
 }
 
 // Gcode, also holds auxiliary information.
@@ -92,6 +114,9 @@ type Gcode struct {
 	EndCoord   math64.VectorF3 // End coordinates of the given Gcode (if known)
 	BoundsMin  math64.VectorF3 // Minimum coordinates used in Gcode (if known)
 	BoundsMax  math64.VectorF3 // Maximum coordinates used in Gcode (if known)
+	Groups     []string        // Names of the SVG groups that the gcode was converted from, outermost first (if known)
+	rec        *recording      // Strokes drawn by the code, cf. Strokes
+	unrecorded bool            // The code contains instructions that were not recorded
 }
 
 func NewGcode() *Gcode {
@@ -109,6 +134,7 @@ func (g *Gcode) CopyMeta() *Gcode {
 	g2.EndCoord = g.EndCoord
 	g2.BoundsMin = g.BoundsMin
 	g2.BoundsMax = g.BoundsMax
+	g2.Groups = g.Groups
 	return g2
 }
 
@@ -123,6 +149,10 @@ func (g *Gcode) String() string {
 	return g.Code.String()
 }
 
+func (g *Gcode) AppendInstructions(ins ...Instruction) {
+	g.Code.AppendInstructions(ins...)
+}
+
 // Add newline-separated code
 func (g *Gcode) AppendCode(code string) {
 	if len(code) == 0 {
@@ -133,14 +163,16 @@ func (g *Gcode) AppendCode(code string) {
 }
 
 // Join two gcodes, merging their boundaries, start/end coordinates, and code.
-// Adds Retract command in-between both codes, if they end/start at different
-// positions.
-func (g *Gcode) Append(g2 *Gcode, runtConf *conf.RuntimeConfig) {
-	ins := NewIns(runtConf)
+// Lifts the pen (if it is lowered) and moves in-between both codes, if they
+// end/start at different positions.
+func (g *Gcode) Append(g2 *Gcode, ins *Ins) {
+	runtConf := ins.runtime
 	g2StartRetracted := math64.VectorF3{X: g2.StartCoord.X, Y: g2.StartCoord.Y, Z: runtConf.Plotter.RetractHeight}
 	gEndRetracted := math64.VectorF3{X: g.EndCoord.X, Y: g2.EndCoord.Y, Z: runtConf.Plotter.RetractHeight}
 	if !g.EndCoord.Equal(g2.StartCoord) && !g.EndCoord.Equal(g2StartRetracted) && !gEndRetracted.Equal(g2.StartCoord) {
-		ins.Retract(g)
+		if ins.IsDrawing(g) {
+			ins.Retract(g)
+		}
 		ins.Move(g, g2StartRetracted, runtConf.Plotter.RetractSpeed)
 	}
 	g.EndCoord = g2.EndCoord
@@ -149,13 +181,14 @@ func (g *Gcode) Append(g2 *Gcode, runtConf *conf.RuntimeConfig) {
 	g.Code.Append(g2.Code)
 }
 
-func Join(gcodes []*Gcode, runtConf *conf.RuntimeConfig) *Gcode {
+// Join gcodes (cf. Append) with the instructions of ins
+func Join(gcodes []*Gcode, ins *Ins) *Gcode {
 	if len(gcodes) == 0 {
 		llog.Panic("Cannot join gcode segments, provided list is empty")
 	}
 	g := gcodes[0].Copy()
 	for _, g2 := range gcodes[1:] {
-		g.Append(g2, runtConf)
+		g.Append(g2, ins)
 	}
 	return g
 }
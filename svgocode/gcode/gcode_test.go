@@ -28,3 +28,55 @@ func TestCodeNumComments(t *testing.T) {
 		}
 	}
 }
+
+func TestInstructionParseFormat(t *testing.T) {
+	lines := map[string]string{
+		"G1 X1.500000 Y-2 Z20.0 F2000 ; Drawing": "G1 X1.5 Y-2 Z20 F2000 ; Drawing",
+		"g1x1y2":                                 "G1 X1 Y2",
+		"M84 X Y E ;Disable steppers":            "M84 X Y E ; Disable steppers",
+		"G92.1 (reset offsets)":                  "G92.1 ; reset offsets",
+		";comment only":                          "; comment only",
+		"":                                       "",
+		"X10 Y20":                                "X10 Y20",
+		"M117 Hello world ; message":             "M117 Hello world ; message",
+		"m117  Hello  world":                     "M117 Hello  world",
+	}
+	f := NewFormatter(NewDialectMarlin())
+	for line, expected := range lines {
+		ins, _ := ParseInstruction(line)
+		if formatted := f.Format(ins); formatted != expected {
+			t.Errorf("Expected '%s' to be formatted as '%s', got '%s'", line, expected, formatted)
+		}
+	}
+	ins, err := ParseInstruction("G2 X10 Y5 I-2.5 J0")
+	if err != nil {
+		t.Fatalf("Failed to parse instruction: %s", err.Error())
+	}
+	if !ins.Is('G', 2) {
+		t.Errorf("Expected command G2, got %c%f", ins.Letter, ins.Number)
+	}
+	if i, ok := ins.Word('I'); !ok || i != -2.5 {
+		t.Errorf("Expected I-2.5, got %f", i)
+	}
+	if _, ok := ins.Word('Z'); ok {
+		t.Errorf("Expected no Z word")
+	}
+	if _, err := ParseInstruction("G1 X1 #2"); err == nil {
+		t.Errorf("Expected error for unparsable line")
+	}
+	// String arguments
+	texts := map[string]Instruction{
+		"M117 Hello world":      NewTextInstruction('M', 117, "Hello world"),
+		"M0 Insert pen red ; x": NewTextInstruction('M', 0, "Insert pen red").WithComment("x"),
+		"M0 P1000":              NewInstruction('M', 0, Word{Letter: 'P', Value: 1000}),
+	}
+	for line, expected := range texts {
+		ins, err := ParseInstruction(line)
+		if err != nil {
+			t.Errorf("Failed to parse instruction '%s': %s", line, err.Error())
+		}
+		if f.Format(ins) != f.Format(expected) || ins.Text != expected.Text {
+			t.Errorf("Expected '%s' to be parsed as '%s', got '%s'", line, f.Format(expected), f.Format(ins))
+		}
+	}
+}
@@ -2,6 +2,8 @@ package gcode
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/abzicht/svgocode/llog"
 	"github.com/abzicht/svgocode/svgocode/conf"
@@ -12,87 +14,178 @@ import (
 
 type Ins struct {
 	runtime *conf.RuntimeConfig
+	penLift PenLiftI
+	dialect *Dialect
+	laser   conf.LaserSetting // Only applies in laser mode
 }
 
 func NewIns(runtConf *conf.RuntimeConfig) *Ins {
 	ins := new(Ins)
 	ins.runtime = runtConf
+	if runtConf.Plotter.Laser.Enabled {
+		ins.penLift = NewPenLiftLaser(runtConf.Plotter.Laser)
+	} else {
+		ins.penLift = ParsePenLift(runtConf.Plotter.PenLift)
+	}
+	ins.dialect = ParseDialect(DialectName(runtConf.Plotter.Dialect))
+	ins.laser = runtConf.Plotter.Laser.Default
 	return ins
 }
 
+// The dialect that instructions are formatted in
+func (ins *Ins) Dialect() *Dialect {
+	return ins.dialect
+}
+
+// Set laser power and speed for subsequent drawing (only applies in laser
+// mode)
+func (ins *Ins) SetLaser(setting conf.LaserSetting) {
+	ins.laser = setting
+}
+
+// Speed for drawing moves
+func (ins *Ins) drawSpeed() math64.Speed {
+	if ins.runtime.Plotter.Laser.Enabled && ins.laser.Speed > 0 {
+		return ins.laser.Speed
+	}
+	return ins.runtime.Plotter.DrawSpeed
+}
+
+// Command number of moves: G1 for drawing, G0 else
+func moveNumber(forDrawing bool) math64.Float {
+	if forDrawing {
+		return 1
+	}
+	return 0
+}
+
 // Add a comment line
 func (ins *Ins) AddComment(g *Gcode, comment string) *Gcode {
-	g.AppendCode(fmt.Sprintf("; %s", comment))
+	rec := ins.recording(g)
+	g.AppendInstructions(NewComment(comment))
+	if rec != nil {
+		rec.comment(comment)
+	}
+	rec.sync(g)
 	return g
 }
 
 // Set unit
 func (ins *Ins) SetUnit(g *Gcode, u math64.UnitLength) *Gcode {
-	var gcmd string = "G21"
+	var gcmd Instruction
 	switch u {
 	case math64.UnitMM:
-		gcmd = "G21 ; Setting unit (mm)"
+		gcmd = NewInstruction('G', 21).WithComment("Setting unit (mm)")
 	case math64.UnitIN:
-		gcmd = "G20 ; Setting unit (in)"
+		gcmd = NewInstruction('G', 20).WithComment("Setting unit (in)")
 	default:
 		llog.Panicf("Unsupported unit type (%s)", u)
 	}
-	g.AppendCode(gcmd)
+	g.AppendInstructions(gcmd)
 	return g
 }
 
 // Set extrusion speed for a given mode (G0/G1)
 func (ins *Ins) SetExtrusion(g *Gcode, extSpeed math64.Speed, forDrawing bool) *Gcode {
-	var gcmd string = "G0"
-	if forDrawing {
-		gcmd = "G1"
-	}
-	g.AppendCode(fmt.Sprintf("%s E%f ; Setting Extrusion", gcmd, extSpeed))
+	g.AppendInstructions(NewInstruction('G', moveNumber(forDrawing), Word{Letter: 'E', Value: math64.Float(extSpeed)}).WithComment("Setting Extrusion"))
 	return g
 }
 
 // Set the default speed for a given move mode (G0/G1)
 func (ins *Ins) SetSpeed(g *Gcode, speed math64.Speed, forDrawing bool) *Gcode {
-	var gcmd string = "G0"
-	if forDrawing {
-		gcmd = "G1"
+	g.AppendInstructions(NewInstruction('G', moveNumber(forDrawing), Word{Letter: 'F', Value: math64.Float(speed)}).WithComment("Setting Speed"))
+	return g
+}
+
+// Wait for the given number of milliseconds (G4). Does nothing for
+// non-positive durations.
+func (ins *Ins) Dwell(g *Gcode, milliseconds math64.Float) *Gcode {
+	if milliseconds <= 0 {
+		return g
+	}
+	p := milliseconds
+	if ins.dialect.DwellSeconds {
+		p /= 1000
 	}
-	g.AppendCode(fmt.Sprintf("%s F%f ; Setting Speed", gcmd, speed))
+	g.AppendInstructions(NewInstruction('G', 4, Word{Letter: 'P', Value: p}).WithComment("Waiting for pen"))
 	return g
 }
 
-// Retract to preconfigured height using Move
+// Lift the pen (e.g., retract to preconfigured height), following the
+// plotter's pen lift strategy
 func (ins *Ins) Retract(g *Gcode) *Gcode {
-	//g.AppendCode(fmt.Sprintf("; Retracting"))
-	//target := math64.VectorF3{X: g.EndCoord.X, Y: g.EndCoord.Y, Z: ins.runtime.Plotter.RetractHeight}
-	//return ins.move(g, target, ins.runtime.Plotter.RetractSpeed, false)
+	rec := ins.recording(g)
 	g.EndCoord.Z = ins.runtime.Plotter.RetractHeight
-	g.BoundsMin = g.BoundsMin.Min(g.EndCoord)
-	g.BoundsMax = g.BoundsMax.Max(g.EndCoord)
-	g.AppendCode(fmt.Sprintf("G0 Z%f F%f ; Retracting", g.EndCoord.Z, ins.runtime.Plotter.RetractSpeed))
+	g.BoundsMin = g.BoundsMin.Min(ins.BoundsPoint(g.EndCoord))
+	g.BoundsMax = g.BoundsMax.Max(ins.BoundsPoint(g.EndCoord))
+	ins.penLift.Up(ins, g)
+	if rec != nil {
+		rec.penDown = false
+	}
+	rec.sync(g)
 	return g
 }
 
-// Lower pen to draw height
+// Lower pen to draw height, following the plotter's pen lift strategy
 func (ins *Ins) DrawPos(g *Gcode) *Gcode {
+	rec := ins.recording(g)
 	g.EndCoord.Z = ins.runtime.Plotter.DrawHeight
-	g.BoundsMin = g.BoundsMin.Min(g.EndCoord)
-	g.BoundsMax = g.BoundsMax.Max(g.EndCoord)
-	g.AppendCode(fmt.Sprintf("G1 Z%f F%f ; Lowering", g.EndCoord.Z, ins.runtime.Plotter.DrawSpeed))
+	g.BoundsMin = g.BoundsMin.Min(ins.BoundsPoint(g.EndCoord))
+	g.BoundsMax = g.BoundsMax.Max(ins.BoundsPoint(g.EndCoord))
+	ins.penLift.Down(ins, g)
+	if rec != nil {
+		rec.lower(ins.laser)
+	}
+	rec.sync(g)
 	return g
 }
 
+// The position as it is accounted for in the bounds of gcode. If the pen lift
+// does not move the Z axis (cf. PenLiftI), no Z is emitted and Z is 0.
+func (ins *Ins) BoundsPoint(p math64.VectorF3) math64.VectorF3 {
+	if !ins.penLift.MovesZ() {
+		p.Z = 0
+	}
+	return p
+}
+
+// Returns true, if the pen is lowered at the end of g
+func (ins *Ins) IsDrawing(g *Gcode) bool {
+	return g.EndCoord.Z != ins.runtime.Plotter.RetractHeight
+}
+
 // MoveRetracted at retract height to given position.
 func (ins *Ins) MoveRetracted(g *Gcode, target math64.VectorF2) *Gcode {
+	rec := ins.recording(g)
 	g.EndCoord.X = target.X
 	g.EndCoord.Y = target.Y
 	g.EndCoord.Z = ins.runtime.Plotter.RetractHeight
-	return ins.move(g, g.EndCoord, ins.runtime.Plotter.RetractSpeed, false)
+	ins.move(g, g.EndCoord, ins.runtime.Plotter.RetractSpeed, false)
+	if rec != nil {
+		rec.position = target
+		rec.penDown = false
+	}
+	rec.sync(g)
+	return g
 }
 
 // Draw at the given draw height and speed.
 func (ins *Ins) Draw(g *Gcode, target math64.VectorF2) *Gcode {
-	return ins.move(g, math64.VectorF3{X: target.X, Y: target.Y, Z: ins.runtime.Plotter.DrawHeight}, ins.runtime.Plotter.DrawSpeed, true)
+	rec := ins.recording(g)
+	ins.move(g, math64.VectorF3{X: target.X, Y: target.Y, Z: ins.runtime.Plotter.DrawHeight}, ins.drawSpeed(), true)
+	ins.recordMove(g, rec, StrokeMove{To: target})
+	return g
+}
+
+// Add a drawing move to the recording of g (if it is recorded). Moves that
+// are drawn without lowering the pen first cannot be recorded.
+func (ins *Ins) recordMove(g *Gcode, rec *recording, m StrokeMove) {
+	if rec != nil && !rec.move(m) {
+		g.rec = nil
+		g.unrecorded = true
+		return
+	}
+	rec.sync(g)
 }
 
 // Move to given position with given speed. Not configured for drawing
@@ -106,40 +199,99 @@ func (ins *Ins) Move(g *Gcode, target math64.VectorF3, speed math64.Speed) *Gcod
 func (ins *Ins) move(g *Gcode, target math64.VectorF3, speed math64.Speed, isDrawing bool) *Gcode {
 	g.EndCoord = target
 	if g.Code.NumLines() == 0 {
-		g.BoundsMin = target
-		g.BoundsMax = target
+		g.BoundsMin = ins.BoundsPoint(target)
+		g.BoundsMax = ins.BoundsPoint(target)
 	} else {
-		g.BoundsMin = g.BoundsMin.Min(target)
-		g.BoundsMax = g.BoundsMax.Max(target)
+		g.BoundsMin = g.BoundsMin.Min(ins.BoundsPoint(target))
+		g.BoundsMax = g.BoundsMax.Max(ins.BoundsPoint(target))
 	}
+	comment := "Moving"
 	if isDrawing {
-		g.AppendCode(fmt.Sprintf("G1 X%f Y%f Z%f F%f ; Drawing", target.X, target.Y, target.Z, speed))
-	} else {
-		g.AppendCode(fmt.Sprintf("G0 X%f Y%f Z%f F%f ; Moving", target.X, target.Y, target.Z, speed))
+		comment = "Drawing"
 	}
+	g.AppendInstructions(NewInstruction('G', moveNumber(isDrawing), ins.axisWords(target,
+		Word{Letter: 'F', Value: math64.Float(speed)},
+	)...).WithComment(comment))
 	return g
 }
 
+// X, Y, and (if the pen is lifted by moving Z) Z words of the target,
+// followed by the given words
+func (ins *Ins) axisWords(target math64.VectorF3, words ...Word) []Word {
+	axes := []Word{{Letter: 'X', Value: target.X}, {Letter: 'Y', Value: target.Y}}
+	if ins.penLift.MovesZ() {
+		axes = append(axes, Word{Letter: 'Z', Value: target.Z})
+	}
+	return append(axes, words...)
+}
+
 // Draw a circle with the center being measured by an offset to the current
 // position
 func (ins *Ins) DrawCircle(g *Gcode, centerOffset math64.VectorF2, radius math64.Float, clockwise bool) *Gcode {
-	g.AppendCode(fmt.Sprintf("; Drawing circle around offset X%f Y%f with radius $%f from current position", centerOffset.X, centerOffset.Y, radius))
-	if centerOffset.X-radius < g.BoundsMin.X {
-		g.BoundsMin.X = centerOffset.X - radius
-	}
-	if centerOffset.Y-radius < g.BoundsMin.Y {
-		g.BoundsMin.Y = centerOffset.Y - radius
-	}
-	if centerOffset.X+radius > g.BoundsMax.X {
-		g.BoundsMax.X = centerOffset.X + radius
+	ins.AddComment(g, fmt.Sprintf("Drawing circle around offset X%f Y%f with radius %f from current position", centerOffset.X, centerOffset.Y, radius))
+	current := math64.VectorF2{X: g.EndCoord.X, Y: g.EndCoord.Y}
+	return ins.DrawArc(g, current, current.Add(centerOffset), clockwise)
+}
+
+// Draw a circular arc (G2/G3) from the current position to target around
+// center. If target equals the current position, a full circle is drawn.
+func (ins *Ins) DrawArc(g *Gcode, target, center math64.VectorF2, clockwise bool) *Gcode {
+	rec := ins.recording(g)
+	current := math64.VectorF2{X: g.EndCoord.X, Y: g.EndCoord.Y}
+	arc := math64.Arc{Start: current, End: target, Center: center, CCW: !clockwise}
+	arcMin, arcMax := arc.Bounds()
+	z := ins.runtime.Plotter.DrawHeight
+	g.BoundsMin = g.BoundsMin.Min(ins.BoundsPoint(math64.VectorF3{X: arcMin.X, Y: arcMin.Y, Z: z}))
+	g.BoundsMax = g.BoundsMax.Max(ins.BoundsPoint(math64.VectorF3{X: arcMax.X, Y: arcMax.Y, Z: z}))
+	g.EndCoord = math64.VectorF3{X: target.X, Y: target.Y, Z: z}
+	var number math64.Float = 2
+	if !clockwise {
+		number = 3
 	}
-	if centerOffset.Y+radius > g.BoundsMax.Y {
-		g.BoundsMax.Y = centerOffset.Y + radius
+	offset := center.Sub(current)
+	g.AppendInstructions(NewInstruction('G', number, ins.axisWords(g.EndCoord,
+		Word{Letter: 'I', Value: offset.X},
+		Word{Letter: 'J', Value: offset.Y},
+		Word{Letter: 'F', Value: math64.Float(ins.drawSpeed())},
+	)...).WithComment("Drawing arc"))
+	ins.recordMove(g, rec, StrokeMove{To: target, Arc: true, Center: center, Clockwise: clockwise})
+	return g
+}
+
+// Lift the pen and change to the configured pen with the given index, either
+// by pausing or by running the pen change macro. If the dialect supports
+// messages, the prompt is displayed before pausing.
+func (ins *Ins) ChangePen(g *Gcode, index int) *Gcode {
+	if ins.IsDrawing(g) {
+		ins.Retract(g)
 	}
-	var gcmd string = "G2"
-	if !clockwise {
-		gcmd = "G3"
+	pen := ins.runtime.Plotter.Pens[index]
+	c := ins.runtime.Plotter.PenChange
+	replacer := strings.NewReplacer("{pen}", pen.Name, "{index}", strconv.Itoa(index))
+	switch c.Mode {
+	case conf.PenChangeMode(""), conf.PenChangeModePause:
+		command := c.Command
+		if len(command) == 0 {
+			command = "M0"
+		}
+		pause, err := ParseInstruction(command)
+		if err != nil || !pause.IsCode() {
+			llog.Panicf("Invalid pen change command: '%s'", command)
+		}
+		prompt := replacer.Replace(c.Prompt)
+		if len(prompt) == 0 {
+			prompt = fmt.Sprintf("Insert pen %s", pen.Name)
+		}
+		if ins.dialect.Messages {
+			// Comments are not shown to the user (and may be removed)
+			g.AppendInstructions(NewTextInstruction('M', 117, prompt))
+		}
+		g.AppendInstructions(pause.WithComment(prompt))
+	case conf.PenChangeModeMacro:
+		ins.AddComment(g, fmt.Sprintf("Changing to pen %s", pen.Name))
+		g.AppendCode(strings.TrimSpace(replacer.Replace(c.Macro)))
+	default:
+		llog.Panicf("Unknown pen change mode: '%s'", c.Mode)
 	}
-	g.AppendCode(fmt.Sprintf("%s I%f J%f F%f", gcmd, centerOffset.X, centerOffset.Y, ins.runtime.Plotter.DrawSpeed))
 	return g
 }
@@ -0,0 +1,201 @@
+package gcode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// A parameter word of an instruction, e.g., 'X10.5'
+type Word struct {
+	Letter byte
+	Value  math64.Float
+	Flag   bool // Word without value, e.g., 'X' in 'M84 X Y'
+}
+
+// A single line of gcode: a command (e.g., 'G1') with parameter words and an
+// optional comment. Lines without command, words, and comment are blank
+// lines.
+type Instruction struct {
+	Letter     byte         // Command letter ('G', 'M', 'T'), 0 for lines without command
+	Number     math64.Float // Command number, e.g., 1 for 'G1' or 92.1 for 'G92.1'
+	Words      []Word
+	Text       string // String argument of commands such as 'M117 Hello' (cf. textCommand)
+	Raw        string // Code that could not be parsed; kept verbatim
+	Comment    string
+	HasComment bool
+}
+
+// Create an instruction for the given command, e.g., NewInstruction('G', 1)
+func NewInstruction(letter byte, number math64.Float, words ...Word) Instruction {
+	return Instruction{Letter: letter, Number: number, Words: words}
+}
+
+// Create a comment-only line
+func NewComment(comment string) Instruction {
+	return Instruction{Comment: comment, HasComment: true}
+}
+
+// Create a command with a string argument, e.g., NewTextInstruction('M', 117,
+// "Hello")
+func NewTextInstruction(letter byte, number math64.Float, text string) Instruction {
+	return Instruction{Letter: letter, Number: number, Text: text}
+}
+
+// Return a copy of the instruction with the given comment
+func (i Instruction) WithComment(comment string) Instruction {
+	i.Comment = comment
+	i.HasComment = true
+	return i
+}
+
+// Return a copy of the instruction without comment
+func (i Instruction) WithoutComment() Instruction {
+	i.Comment = ""
+	i.HasComment = false
+	return i
+}
+
+// Returns true, if the line contains code (and not only a comment)
+func (i Instruction) IsCode() bool {
+	return i.Letter != 0 || len(i.Words) > 0 || len(i.Raw) > 0
+}
+
+// Returns true, if the instruction is the given command, e.g., Is('G', 1)
+func (i Instruction) Is(letter byte, number math64.Float) bool {
+	return i.Letter == letter && i.Number == number
+}
+
+// Value of the instruction's word with the given letter
+func (i Instruction) Word(letter byte) (math64.Float, bool) {
+	for _, w := range i.Words {
+		if w.Letter == letter && !w.Flag {
+			return w.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Parse a single line of gcode. Comments start with ';' or are enclosed in
+// parentheses. If the code cannot be parsed, it is kept in Raw and an error
+// is returned.
+func ParseInstruction(line string) (Instruction, error) {
+	var ins Instruction
+	code := line
+	if idx := strings.IndexByte(code, ';'); idx >= 0 {
+		ins = ins.WithComment(strings.TrimSpace(code[idx+1:]))
+		code = code[:idx]
+	}
+	if start := strings.IndexByte(code, '('); start >= 0 {
+		end := strings.IndexByte(code[start:], ')')
+		if end < 0 {
+			end = len(code) - start
+		}
+		if !ins.HasComment {
+			ins = ins.WithComment(strings.TrimSpace(code[start+1 : start+end]))
+		}
+		code = code[:start] + code[min(start+end+1, len(code)):]
+	}
+	code = strings.TrimSpace(code)
+	if text, ok := parseTextCommand(code); ok {
+		ins.Letter, ins.Number, ins.Text = text.Letter, text.Number, text.Text
+		return ins, nil
+	}
+	words, err := parseWords(code)
+	if err != nil {
+		ins.Raw = code
+		return ins, fmt.Errorf("failed to parse gcode line '%s': %w", line, err)
+	}
+	if len(words) > 0 && !words[0].Flag {
+		switch words[0].Letter {
+		case 'G', 'M', 'T':
+			ins.Letter = words[0].Letter
+			ins.Number = words[0].Value
+			words = words[1:]
+		}
+	}
+	if len(words) > 0 {
+		ins.Words = words
+	}
+	return ins, nil
+}
+
+// Returns true, if the command takes a string argument instead of words.
+// For M0 and M1 (pause), the argument is optional and may also be words
+// (e.g., 'M0 P1000').
+func textCommand(letter byte, number math64.Float) (text bool, words bool) {
+	if letter != 'M' {
+		return false, false
+	}
+	switch number {
+	case 117, 118: // Display message, serial print
+		return true, false
+	case 0, 1:
+		return true, true
+	}
+	return false, false
+}
+
+// Parse commands with a string argument, e.g., 'M117 Hello world'
+func parseTextCommand(code string) (Instruction, bool) {
+	head, rest, found := strings.Cut(code, " ")
+	rest = strings.TrimSpace(rest)
+	if !found || len(rest) == 0 {
+		return Instruction{}, false
+	}
+	command, err := parseWords(head)
+	if err != nil || len(command) != 1 || command[0].Flag {
+		return Instruction{}, false
+	}
+	text, words := textCommand(command[0].Letter, command[0].Value)
+	if !text {
+		return Instruction{}, false
+	}
+	if _, err := parseWords(rest); words && err == nil {
+		return Instruction{}, false
+	}
+	return NewTextInstruction(command[0].Letter, command[0].Value, rest), true
+}
+
+// Parse words such as 'G1 X10 Y-2.5' (whitespace between words is optional)
+func parseWords(code string) ([]Word, error) {
+	var words []Word
+	for i := 0; i < len(code); {
+		c := code[i]
+		if c == ' ' || c == '\t' {
+			i++
+			continue
+		}
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return nil, errors.New(fmt.Sprintf("unexpected character '%c'", c))
+		}
+		j := i + 1
+		for j < len(code) && strings.IndexByte("+-.0123456789eE", code[j]) >= 0 {
+			j++
+		}
+		// Only permit exponents if followed by digits (e.g., 'X1E' is X1 and E)
+		for j > i+1 && (code[j-1] == 'e' || code[j-1] == 'E') {
+			j--
+		}
+		w := Word{Letter: strings.ToUpper(string(c))[0]}
+		if j == i+1 {
+			w.Flag = true
+		} else {
+			value, err := strconv.ParseFloat(code[i+1:j], 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid number '%s'", code[i+1:j]))
+			}
+			w.Value = math64.Float(value)
+		}
+		// Words without value must be separated by whitespace
+		if j < len(code) && code[j] != ' ' && code[j] != '\t' && (w.Flag || !(code[j] >= 'A' && code[j] <= 'Z' || code[j] >= 'a' && code[j] <= 'z')) {
+			return nil, errors.New(fmt.Sprintf("unexpected character '%c'", code[j]))
+		}
+		words = append(words, w)
+		i = j
+	}
+	return words, nil
+}
@@ -0,0 +1,170 @@
+package gcode
+
+import (
+	"strings"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Strategy for lifting and lowering the pen at the current position
+type PenLiftI interface {
+	Up(ins *Ins, g *Gcode)
+	Down(ins *Ins, g *Gcode)
+	// Returns true, if the pen is lifted by moving the Z axis. Else, moves
+	// do not carry Z words.
+	MovesZ() bool
+}
+
+func ParsePenLift(c conf.PenLiftConfig) PenLiftI {
+	switch c.Mode {
+	case conf.PenLiftMode(""): // Default is Z
+		fallthrough
+	case conf.PenLiftModeZ:
+		return NewPenLiftZ(c.Z)
+	case conf.PenLiftModeServo:
+		return NewPenLiftServo(c.Servo)
+	case conf.PenLiftModeCustom:
+		return NewPenLiftCustom(c.Custom)
+	default:
+		llog.Panicf("Unknown pen lift mode: '%s'", c.Mode)
+		return nil
+	}
+}
+
+// Lift the pen by moving to retract-height, lower it by moving to
+// drawing-height
+type PenLiftZ struct {
+	conf conf.PenLiftZConfig
+}
+
+func NewPenLiftZ(c conf.PenLiftZConfig) *PenLiftZ {
+	p := new(PenLiftZ)
+	p.conf = c
+	return p
+}
+
+func (p *PenLiftZ) Up(ins *Ins, g *Gcode) {
+	g.AppendInstructions(NewInstruction('G', 0,
+		Word{Letter: 'Z', Value: ins.runtime.Plotter.RetractHeight},
+		Word{Letter: 'F', Value: math64.Float(ins.runtime.Plotter.RetractSpeed)},
+	).WithComment("Retracting"))
+	ins.Dwell(g, p.conf.Delay.Up)
+}
+
+func (p *PenLiftZ) Down(ins *Ins, g *Gcode) {
+	g.AppendInstructions(NewInstruction('G', 1,
+		Word{Letter: 'Z', Value: ins.runtime.Plotter.DrawHeight},
+		Word{Letter: 'F', Value: math64.Float(ins.runtime.Plotter.DrawSpeed)},
+	).WithComment("Lowering"))
+	ins.Dwell(g, p.conf.Delay.Down)
+}
+
+func (p *PenLiftZ) MovesZ() bool {
+	return true
+}
+
+// Lift and lower the pen with a servo
+type PenLiftServo struct {
+	conf conf.PenLiftServoConfig
+}
+
+func NewPenLiftServo(c conf.PenLiftServoConfig) *PenLiftServo {
+	switch strings.ToUpper(c.Command) {
+	case "", "M280":
+		c.Command = "M280"
+	case "M3":
+		c.Command = "M3"
+	default:
+		llog.Panicf("Unsupported servo command: '%s'. Must be 'M280' or 'M3'", c.Command)
+	}
+	p := new(PenLiftServo)
+	p.conf = c
+	return p
+}
+
+// Instruction for turning the servo to the given angle
+func (p *PenLiftServo) turn(angle math64.Float) Instruction {
+	if p.conf.Command == "M3" {
+		return NewInstruction('M', 3, Word{Letter: 'S', Value: angle})
+	}
+	return NewInstruction('M', 280, Word{Letter: 'P', Value: math64.Float(p.conf.Index)}, Word{Letter: 'S', Value: angle})
+}
+
+func (p *PenLiftServo) Up(ins *Ins, g *Gcode) {
+	up := p.turn(p.conf.Up)
+	if p.conf.Command == "M3" && p.conf.Up == 0 {
+		up = NewInstruction('M', 5)
+	}
+	g.AppendInstructions(up.WithComment("Lifting pen"))
+	ins.Dwell(g, p.conf.Delay.Up)
+}
+
+func (p *PenLiftServo) Down(ins *Ins, g *Gcode) {
+	g.AppendInstructions(p.turn(p.conf.Down).WithComment("Lowering pen"))
+	ins.Dwell(g, p.conf.Delay.Down)
+}
+
+func (p *PenLiftServo) MovesZ() bool {
+	return false
+}
+
+// Lift and lower the pen with user-defined gcode
+type PenLiftCustom struct {
+	conf conf.PenLiftCustomConfig
+}
+
+func NewPenLiftCustom(c conf.PenLiftCustomConfig) *PenLiftCustom {
+	if len(strings.TrimSpace(c.Up)) == 0 || len(strings.TrimSpace(c.Down)) == 0 {
+		llog.Panicf("Custom pen lift requires gcode for both lifting ('up') and lowering ('down') the pen")
+	}
+	p := new(PenLiftCustom)
+	p.conf = c
+	return p
+}
+
+func (p *PenLiftCustom) Up(ins *Ins, g *Gcode) {
+	g.AppendCode(strings.TrimSpace(p.conf.Up))
+	ins.Dwell(g, p.conf.Delay.Up)
+}
+
+func (p *PenLiftCustom) Down(ins *Ins, g *Gcode) {
+	g.AppendCode(strings.TrimSpace(p.conf.Down))
+	ins.Dwell(g, p.conf.Delay.Down)
+}
+
+func (p *PenLiftCustom) MovesZ() bool {
+	return false
+}
+
+// Switch a laser on for drawing and off for travel moves
+type PenLiftLaser struct {
+	conf conf.LaserConfig
+}
+
+func NewPenLiftLaser(c conf.LaserConfig) *PenLiftLaser {
+	if c.MaxPower <= 0 {
+		llog.Panicf("Laser mode requires a positive max-power")
+	}
+	p := new(PenLiftLaser)
+	p.conf = c
+	return p
+}
+
+func (p *PenLiftLaser) Up(ins *Ins, g *Gcode) {
+	g.AppendInstructions(NewInstruction('M', 5).WithComment("Laser off"))
+}
+
+func (p *PenLiftLaser) Down(ins *Ins, g *Gcode) {
+	var number math64.Float = 3
+	if p.conf.Dynamic {
+		number = 4
+	}
+	power := ins.laser.Power.Max(0).Min(100) / 100 * p.conf.MaxPower
+	g.AppendInstructions(NewInstruction('M', number, Word{Letter: 'S', Value: power}).WithComment("Laser on"))
+}
+
+func (p *PenLiftLaser) MovesZ() bool {
+	return false
+}
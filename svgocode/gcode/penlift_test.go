@@ -0,0 +1,100 @@
+package gcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+func penLiftCode(t *testing.T, plotter *conf.PlotterConfig) string {
+	t.Helper()
+	ins := NewIns(conf.NewRuntimeConfig(plotter, math64.UnitMM, math64.UnitMM))
+	g := NewGcode()
+	ins.Retract(g)
+	ins.MoveRetracted(g, math64.VectorF2{X: 1, Y: 2})
+	ins.DrawPos(g)
+	ins.Draw(g, math64.VectorF2{X: 3, Y: 4})
+	return strings.TrimSpace(g.Code.RemoveComments().String())
+}
+
+func TestPenLift(t *testing.T) {
+	zConf := conf.PlotterConfigLongerLK5ProDefault()
+	zConf.PenLift.Z.Delay.Down = 100
+
+	servoConf := conf.PlotterConfigLongerLK5ProDefault()
+	servoConf.PenLift.Mode = conf.PenLiftModeServo
+
+	grblConf := conf.PlotterConfigLongerLK5ProDefault()
+	grblConf.Dialect = string(DialectNameGRBL)
+	grblConf.PenLift.Mode = conf.PenLiftModeServo
+	grblConf.PenLift.Servo = conf.PenLiftServoConfig{Command: "m3", Up: 0, Down: 1000, Delay: conf.PenLiftDelay{Up: 250}}
+
+	customConf := conf.PlotterConfigLongerLK5ProDefault()
+	customConf.PenLift.Mode = conf.PenLiftModeCustom
+	customConf.PenLift.Custom = conf.PenLiftCustomConfig{Up: "M400\nM42 P4 S0\n", Down: "M400\nM42 P4 S255"}
+
+	laserConf := conf.PlotterConfigLongerLK5ProDefault()
+	laserConf.Laser.Enabled = true
+	laserConf.Laser.Default = conf.LaserSetting{Power: 50, Speed: 700}
+
+	expected := []struct {
+		name    string
+		plotter *conf.PlotterConfig
+		code    string
+	}{
+		{"z", zConf, "G0 Z23 F4000\nG0 X1 Y2 Z23 F4000\nG1 Z20 F2000\nG4 P100\nG1 X3 Y4 Z20 F2000"},
+		{"servo", servoConf, "M280 P0 S90\nG4 P150\nG0 X1 Y2 F4000\nM280 P0 S30\nG4 P150\nG1 X3 Y4 F2000"},
+		{"servo-grbl", grblConf, "M5\nG4 P0.25\nG0 X1 Y2 F4000\nM3 S1000\nG1 X3 Y4 F2000"},
+		{"laser", laserConf, "M5\nG0 X1 Y2 F4000\nM4 S500\nG1 X3 Y4 F700"},
+		{"custom", customConf, "M400\nM42 P4 S0\nG0 X1 Y2 F4000\nM400\nM42 P4 S255\nG1 X3 Y4 F2000"},
+	}
+	for _, e := range expected {
+		if got := penLiftCode(t, e.plotter); got != e.code {
+			t.Errorf("Pen lift '%s': expected\n%s\ngot\n%s", e.name, e.code, got)
+		}
+	}
+}
+
+func TestPenLiftBounds(t *testing.T) {
+	// Without Z lift, the heights do not count towards the bounds
+	servoConf := conf.PlotterConfigLongerLK5ProDefault()
+	servoConf.PenLift.Mode = conf.PenLiftModeServo
+	for _, e := range []struct {
+		plotter    *conf.PlotterConfig
+		minZ, maxZ math64.Float
+	}{
+		{conf.PlotterConfigLongerLK5ProDefault(), 20, 23},
+		{servoConf, 0, 0},
+	} {
+		ins := NewIns(conf.NewRuntimeConfig(e.plotter, math64.UnitMM, math64.UnitMM))
+		g := NewGcode()
+		ins.Retract(g)
+		ins.MoveRetracted(g, math64.VectorF2{X: 1, Y: 2})
+		ins.DrawPos(g)
+		ins.Draw(g, math64.VectorF2{X: 3, Y: 4})
+		g.Append(NewGcode(), ins)
+		if g.BoundsMin.Z != e.minZ || g.BoundsMax.Z != e.maxZ {
+			t.Errorf("Pen lift '%s': unexpected bounds %s %s", e.plotter.PenLift.Mode, g.BoundsMin.String(), g.BoundsMax.String())
+		}
+	}
+}
+
+func TestChangePen(t *testing.T) {
+	expected := map[DialectName]string{
+		DialectNameMarlin: "G0 Z23 F4000\nM117 Insert pen red\nM0",
+		DialectNameGRBL:   "G0 Z23 F4000\nM0",
+	}
+	for dialect, want := range expected {
+		plotter := conf.PlotterConfigLongerLK5ProDefault()
+		plotter.Dialect = string(dialect)
+		plotter.Pens = []conf.PenConfig{{Name: "black"}, {Name: "red"}}
+		ins := NewIns(conf.NewRuntimeConfig(plotter, math64.UnitMM, math64.UnitMM))
+		g := NewGcode()
+		ins.ChangePen(g, 1)
+		if got := strings.TrimSpace(g.Code.RemoveComments().String()); got != want {
+			t.Errorf("Pen change (%s): expected\n%s\ngot\n%s", dialect, want, got)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package gcode
+
+import (
+	"slices"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Strokes, i.e., the lines that gcode draws while the pen is lowered. Ins
+// records the strokes of the gcode that it creates. Gcode whose code was
+// created by Ins only can thus be drawn anew in a different direction or,
+// if it is closed, from a different vertex (cf. Reversed, StartingAt).
+
+// A drawing move along a line or, if Arc is true, along a circular arc
+// around Center
+type StrokeMove struct {
+	To        math64.VectorF2
+	Arc       bool
+	Center    math64.VectorF2
+	Clockwise bool
+}
+
+// A line that is drawn without lifting the pen
+type Stroke struct {
+	Start    math64.VectorF2
+	Moves    []StrokeMove
+	Laser    conf.LaserSetting // Only applies in laser mode
+	comments []string          // Comments that precede the stroke
+}
+
+// The stroke's end position
+func (s Stroke) End() math64.VectorF2 {
+	if len(s.Moves) == 0 {
+		return s.Start
+	}
+	return s.Moves[len(s.Moves)-1].To
+}
+
+// Returns true, if the stroke ends where it starts
+func (s Stroke) IsClosed() bool {
+	return len(s.Moves) > 1 && s.Start.DistEuclid(s.End()) < 1e-9
+}
+
+// The stroke, drawn from its end to its start
+func (s Stroke) Reversed() Stroke {
+	s2 := s
+	s2.Start = s.End()
+	s2.Moves = make([]StrokeMove, len(s.Moves))
+	for i, m := range s.Moves {
+		from := s.Start
+		if i > 0 {
+			from = s.Moves[i-1].To
+		}
+		m.To = from
+		m.Clockwise = !m.Clockwise
+		s2.Moves[len(s.Moves)-1-i] = m
+	}
+	return s2
+}
+
+// The closed stroke, starting at the given vertex (cf. Vertices)
+func (s Stroke) StartingAt(vertex int) Stroke {
+	if vertex == 0 {
+		return s
+	}
+	s2 := s
+	s2.Start = s.Moves[vertex-1].To
+	s2.Moves = slices.Concat(s.Moves[vertex:], s.Moves[:vertex])
+	return s2
+}
+
+// Positions between the stroke's moves, starting with its start. The end of
+// closed strokes is omitted, as it equals the start.
+func (s Stroke) Vertices() []math64.VectorF2 {
+	vertices := []math64.VectorF2{s.Start}
+	for _, m := range s.Moves {
+		vertices = append(vertices, m.To)
+	}
+	if s.IsClosed() {
+		vertices = vertices[:len(vertices)-1]
+	}
+	return vertices
+}
+
+// The strokes of gcode, as recorded by Ins
+type recording struct {
+	ins      *Ins
+	prelude  []string // Comments before the first stroke
+	strokes  []Stroke
+	comments []string // Comments since the last stroke
+	position math64.VectorF2
+	penDown  bool
+	lines    int // Number of lines of code that have been recorded
+}
+
+// The recording of g. Returns nil, if g contains code that was not recorded.
+func (ins *Ins) recording(g *Gcode) *recording {
+	if g.rec == nil && g.Code.NumLines() == 0 && !g.unrecorded {
+		g.rec = &recording{ins: ins}
+	}
+	if g.rec != nil && g.rec.lines != g.Code.NumLines() {
+		g.rec = nil
+		g.unrecorded = true
+	}
+	return g.rec
+}
+
+// Mark all code of g as recorded
+func (r *recording) sync(g *Gcode) {
+	if r != nil {
+		r.lines = g.Code.NumLines()
+	}
+}
+
+func (r *recording) comment(comment string) {
+	if len(r.strokes) == 0 {
+		r.prelude = append(r.prelude, comment)
+		return
+	}
+	r.comments = append(r.comments, comment)
+}
+
+func (r *recording) lower(laser conf.LaserSetting) {
+	r.strokes = append(r.strokes, Stroke{Start: r.position, Laser: laser, comments: r.comments})
+	r.comments = nil
+	r.penDown = true
+}
+
+// Add a move to the current stroke. Returns false, if the pen is not lowered.
+func (r *recording) move(m StrokeMove) bool {
+	r.position = m.To
+	if !r.penDown {
+		return false
+	}
+	stroke := &r.strokes[len(r.strokes)-1]
+	stroke.Moves = append(stroke.Moves, m)
+	return true
+}
+
+// The strokes that g draws. Returns false, if g contains code that was not
+// created by Ins (e.g., joined gcode or custom code).
+func (g *Gcode) Strokes() ([]Stroke, bool) {
+	if g.rec == nil || g.rec.lines != g.Code.NumLines() || len(g.rec.strokes) == 0 {
+		return nil, false
+	}
+	return g.rec.strokes, true
+}
+
+// Returns true, if g can be drawn backwards (cf. Reversed)
+func (g *Gcode) IsReversible() bool {
+	_, ok := g.Strokes()
+	return ok
+}
+
+// Returns true, if g draws a single closed stroke, i.e., if it can start at
+// any of its vertices (cf. StartingAt)
+func (g *Gcode) IsClosed() bool {
+	strokes, ok := g.Strokes()
+	return ok && len(strokes) == 1 && strokes[0].IsClosed()
+}
+
+// The positions that closed gcode can start at (cf. StartingAt). Returns
+// nil, if g is not closed.
+func (g *Gcode) Vertices() []math64.VectorF2 {
+	if !g.IsClosed() {
+		return nil
+	}
+	return g.rec.strokes[0].Vertices()
+}
+
+// The gcode, drawn backwards: its strokes in reverse order, each from its end
+// to its start. Returns g, if it is not reversible.
+func (g *Gcode) Reversed() *Gcode {
+	strokes, ok := g.Strokes()
+	if !ok {
+		return g
+	}
+	// Comments describe the strokes up to the next comment. They stay in
+	// front of these strokes.
+	var reversed []Stroke
+	end := len(strokes)
+	for start := len(strokes) - 1; start >= 0; start-- {
+		if start > 0 && len(strokes[start].comments) == 0 {
+			continue
+		}
+		for i := end - 1; i >= start; i-- {
+			s := strokes[i].Reversed()
+			s.comments = nil
+			if i == end-1 {
+				s.comments = strokes[start].comments
+			}
+			reversed = append(reversed, s)
+		}
+		end = start
+	}
+	return g.redraw(reversed)
+}
+
+// The closed gcode, starting at the given vertex (cf. Vertices). Returns g,
+// if it is not closed.
+func (g *Gcode) StartingAt(vertex int) *Gcode {
+	if vertex == 0 || !g.IsClosed() {
+		return g
+	}
+	return g.redraw([]Stroke{g.rec.strokes[0].StartingAt(vertex)})
+}
+
+// Create gcode that draws the given strokes with the instructions of g
+func (g *Gcode) redraw(strokes []Stroke) *Gcode {
+	// Laser settings change per stroke, the original must not be affected
+	ins := *g.rec.ins
+	g2 := NewGcode()
+	g2.Groups = g.Groups
+	for _, comment := range g.rec.prelude {
+		ins.AddComment(g2, comment)
+	}
+	for i, s := range strokes {
+		if i > 0 {
+			ins.Retract(g2)
+		}
+		for _, comment := range s.comments {
+			ins.AddComment(g2, comment)
+		}
+		ins.SetLaser(s.Laser)
+		ins.MoveRetracted(g2, s.Start)
+		ins.DrawPos(g2)
+		for _, m := range s.Moves {
+			if m.Arc {
+				ins.DrawArc(g2, m.To, m.Center, m.Clockwise)
+			} else {
+				ins.Draw(g2, m.To)
+			}
+		}
+	}
+	for _, comment := range g.rec.comments {
+		ins.AddComment(g2, comment)
+	}
+	if !g.rec.penDown {
+		ins.Retract(g2)
+	}
+	g2.StartCoord = math64.VectorF3{X: strokes[0].Start.X, Y: strokes[0].Start.Y, Z: ins.runtime.Plotter.DrawHeight}
+	return g2
+}
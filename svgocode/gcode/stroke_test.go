@@ -0,0 +1,71 @@
+package gcode
+
+import (
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+func strokeTestIns() *Ins {
+	return NewIns(conf.NewRuntimeConfig(conf.PlotterConfigLongerLK5ProDefault(), math64.UnitMM, math64.UnitMM))
+}
+
+func TestGcodeReversed(t *testing.T) {
+	ins := strokeTestIns()
+	g := NewGcode()
+	ins.AddComment(g, "Open path")
+	ins.MoveRetracted(g, math64.VectorF2{X: 0, Y: 0})
+	ins.DrawPos(g)
+	ins.Draw(g, math64.VectorF2{X: 10, Y: 0})
+	ins.DrawArc(g, math64.VectorF2{X: 20, Y: 0}, math64.VectorF2{X: 15, Y: 0}, true)
+	if !g.IsReversible() || g.IsClosed() {
+		t.Fatalf("Expected an open, reversible path (reversible: %t, closed: %t)", g.IsReversible(), g.IsClosed())
+	}
+	r := g.Reversed()
+	if r.StartCoord.X != 20 || r.EndCoord.X != 0 {
+		t.Errorf("Expected the reversed path to go from x=20 to x=0, got %v to %v", r.StartCoord, r.EndCoord)
+	}
+	strokes, _ := r.Strokes()
+	if len(strokes) != 1 || len(strokes[0].Moves) != 2 || !strokes[0].Moves[0].Arc || strokes[0].Moves[0].Clockwise {
+		t.Errorf("Expected a counterclockwise arc followed by a line, got %v", strokes)
+	}
+	if r.Code.NumLines() != g.Code.NumLines() || r.Code.NumComments() != g.Code.NumComments() {
+		t.Errorf("Expected the reversed path to have as many lines and comments as the original:\n%s\n%s", g.Code, r.Code)
+	}
+	if r2 := r.Reversed(); r2.Code.String() != g.Code.String() {
+		t.Errorf("Expected reversing twice to restore the original:\n%s\n%s", g.Code, r2.Code)
+	}
+
+	// Code that was not created by Ins cannot be reversed
+	g.Code.AppendLines("G4 P100")
+	if g.IsReversible() || g.Reversed() != g {
+		t.Errorf("Expected custom code to make the path irreversible")
+	}
+}
+
+func TestGcodeStartingAt(t *testing.T) {
+	ins := strokeTestIns()
+	g := NewGcode()
+	square := []math64.VectorF2{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 0, Y: 0}}
+	ins.MoveRetracted(g, square[0])
+	ins.DrawPos(g)
+	for _, p := range square[1:] {
+		ins.Draw(g, p)
+	}
+	ins.Retract(g)
+	if !g.IsClosed() || len(g.Vertices()) != 4 {
+		t.Fatalf("Expected a closed path with 4 vertices, got %v", g.Vertices())
+	}
+	r := g.StartingAt(2)
+	want := math64.VectorF2{X: 10, Y: 10}
+	if r.StartCoord.X != want.X || r.StartCoord.Y != want.Y || r.EndCoord.X != want.X || r.EndCoord.Y != want.Y {
+		t.Errorf("Expected the path to start and end at %v, got %v and %v", want, r.StartCoord, r.EndCoord)
+	}
+	if r.EndCoord.Z != g.EndCoord.Z {
+		t.Errorf("Expected the rotated path to end retracted, got %v", r.EndCoord)
+	}
+	if vertices := r.Vertices(); len(vertices) != 4 || vertices[2] != square[0] {
+		t.Errorf("Expected the rotated vertices to wrap around, got %v", vertices)
+	}
+}
@@ -0,0 +1,45 @@
+package svgocode
+
+import (
+	"fmt"
+
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+// Unique names of the SVG's groups (cf. gcode.Gcode's Groups)
+type groupNamer struct {
+	names map[*svg.Grouping]string
+	taken map[string]bool
+}
+
+func newGroupNamer() *groupNamer {
+	gn := new(groupNamer)
+	gn.names = make(map[*svg.Grouping]string)
+	gn.taken = make(map[string]bool)
+	return gn
+}
+
+// Names of the groups that contain the last element of the given path,
+// outermost first. Groups are named by id or, if they have none (or if it is
+// taken, e.g., by another instance of a 'use' reference), by number.
+func (gn *groupNamer) path(path []svg.SVGElement) []string {
+	var names []string
+	for _, element := range path[:len(path)-1] {
+		g, ok := element.(*svg.Grouping)
+		if !ok {
+			continue
+		}
+		name, ok := gn.names[g]
+		if !ok {
+			name = string(g.Id)
+			if len(name) == 0 || gn.taken[name] {
+				// '#' cannot be part of ids
+				name = fmt.Sprintf("%s#%d", name, len(gn.names))
+			}
+			gn.names[g] = name
+			gn.taken[name] = true
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,200 @@
+package math64
+
+import "math"
+
+// A circular arc from Start to End around Center.
+type Arc struct {
+	Start  VectorF2
+	End    VectorF2
+	Center VectorF2
+	CCW    bool // Counter-clockwise (positive angle direction), clockwise else
+}
+
+func (a Arc) Radius() Float {
+	return a.Start.DistEuclid(a.Center)
+}
+
+// Angle that the arc covers, in (-2π, 2π]. Positive for counter-clockwise
+// arcs. Start == End is taken as full circle.
+func (a Arc) Sweep() AngRad {
+	from := Atan2(a.Start.Y-a.Center.Y, a.Start.X-a.Center.X)
+	to := Atan2(a.End.Y-a.Center.Y, a.End.X-a.Center.X)
+	delta := to - from
+	if a.CCW {
+		for delta <= 0 {
+			delta += 2 * math.Pi
+		}
+	} else {
+		for delta >= 0 {
+			delta -= 2 * math.Pi
+		}
+	}
+	return delta
+}
+
+// Point on the arc, t in [0, 1]
+func (a Arc) At(t Float) VectorF2 {
+	from := Atan2(a.Start.Y-a.Center.Y, a.Start.X-a.Center.X)
+	angle := from + AngRad(t)*a.Sweep()
+	r := a.Radius()
+	return VectorF2{X: a.Center.X + r*angle.Cos(), Y: a.Center.Y + r*angle.Sin()}
+}
+
+// Bounding box of the arc
+func (a Arc) Bounds() (VectorF2, VectorF2) {
+	min := a.Start.Min(a.End)
+	max := a.Start.Max(a.End)
+	from := Atan2(a.Start.Y-a.Center.Y, a.Start.X-a.Center.X)
+	sweep := a.Sweep()
+	r := a.Radius()
+	// Check whether the arc passes the extreme points of its circle
+	for k := -8; k <= 8; k++ {
+		angle := AngRad(k) * math.Pi / 2
+		rel := angle - from
+		if (sweep > 0 && rel > 0 && rel < sweep) || (sweep < 0 && rel < 0 && rel > sweep) {
+			p := VectorF2{X: a.Center.X + r*angle.Cos(), Y: a.Center.Y + r*angle.Sin()}
+			min = min.Min(p)
+			max = max.Max(p)
+		}
+	}
+	return min, max
+}
+
+// Arc from p with tangent t (normalized) at p to q. Returns false, if p, t,
+// and q are (almost) collinear, i.e., the arc is a straight line.
+func arcFromTangent(p, t, q VectorF2) (Arc, bool) {
+	n := VectorF2{X: -t.Y, Y: t.X}
+	chord := q.Sub(p)
+	denom := 2 * n.Dot(chord)
+	if denom.Abs() < 1e-12*chord.Length() || chord.Length() == 0 {
+		return Arc{}, false
+	}
+	s := chord.Dot(chord) / denom
+	return Arc{Start: p, End: q, Center: p.Add(n.Scale(s)), CCW: s > 0}, true
+}
+
+// Distance of p to the arc's circle
+func (a Arc) distCircle(p VectorF2) Float {
+	return (p.DistEuclid(a.Center) - a.Radius()).Abs()
+}
+
+// Fit two arcs to the cubic Bézier curve c. The arcs meet with a common
+// tangent and share the curve's end tangents. Returns false, if no biarc
+// deviates less than tolerance from the curve.
+func fitBiarc(c [4]VectorF2, tolerance Float) ([]Arc, bool) {
+	p1, p2 := c[0], c[3]
+	t1 := c[1].Sub(c[0])
+	if t1.Length() < 1e-12 {
+		t1 = c[2].Sub(c[0])
+	}
+	t2 := c[3].Sub(c[2])
+	if t2.Length() < 1e-12 {
+		t2 = c[3].Sub(c[1])
+	}
+	t1, t2 = t1.Normalize(), t2.Normalize()
+	v := p2.Sub(p1)
+	if v.Length() < 1e-12 || t1.Length() == 0 || t2.Length() == 0 {
+		return nil, false
+	}
+	// Equal tangent length construction of the joint point
+	t := t1.Add(t2)
+	denom := 2 * (1 - t1.Dot(t2))
+	var d Float
+	if denom.Abs() < 1e-12 {
+		vt2 := v.Dot(t2)
+		if vt2.Abs() < 1e-12 {
+			return nil, false
+		}
+		d = v.Dot(v) / (4 * vt2)
+	} else {
+		disc := v.Dot(t)*v.Dot(t) + denom*v.Dot(v)
+		d = (-v.Dot(t) + disc.Sqrt()) / denom
+	}
+	if d <= 0 {
+		return nil, false
+	}
+	pm := p1.Add(p2).Add(t1.Sub(t2).Scale(d)).Scale(0.5)
+	arc1, ok1 := arcFromTangent(p1, t1, pm)
+	u := pm.Sub(p1).Normalize()
+	tm := u.Scale(2 * t1.Dot(u)).Sub(t1)
+	arc2, ok2 := arcFromTangent(pm, tm, p2)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	arcs := []Arc{arc1, arc2}
+	// Measure the deviation at sample points
+	const samples = 16
+	for i := 1; i < samples; i++ {
+		ti := Float(i) / samples
+		q := CubicBezier(ti, c[0], c[1], c[2], c[3])
+		if arc1.distCircle(q).Min(arc2.distCircle(q)) > tolerance {
+			return nil, false
+		}
+	}
+	for _, a := range arcs {
+		if q := a.At(0.5); curveDist(c, q) > tolerance {
+			return nil, false
+		}
+	}
+	return arcs, true
+}
+
+// Distance of point p to the cubic Bézier curve c. Samples the curve and
+// refines the closest sample via ternary search.
+func curveDist(c [4]VectorF2, p VectorF2) Float {
+	const samples = 32
+	dist := func(t Float) Float {
+		return p.DistEuclid(CubicBezier(t, c[0], c[1], c[2], c[3]))
+	}
+	best := Float(0)
+	for i := 1; i <= samples; i++ {
+		if t := Float(i) / samples; dist(t) < dist(best) {
+			best = t
+		}
+	}
+	lo, hi := (best - 1.0/samples).Max(0), (best + 1.0/samples).Min(1)
+	for range 40 {
+		m1, m2 := lo+(hi-lo)/3, hi-(hi-lo)/3
+		if dist(m1) < dist(m2) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	return dist((lo + hi) / 2)
+}
+
+// Approximate a cubic Bézier curve with arcs that deviate at most tolerance
+// from the curve, by recursively splitting the curve and fitting biarcs.
+// Returns false, if the curve could not be approximated within maxDepth
+// splits (e.g., due to cusps or degenerate control points).
+func CubicBezierToArcs(c [4]VectorF2, tolerance Float, maxDepth int) ([]Arc, bool) {
+	if arcs, ok := fitBiarc(c, tolerance); ok {
+		return arcs, true
+	}
+	if maxDepth <= 0 {
+		return nil, false
+	}
+	left, right := SplitCubicBezier(0.5, c)
+	arcsL, okL := CubicBezierToArcs(left, tolerance, maxDepth-1)
+	if !okL {
+		return nil, false
+	}
+	arcsR, okR := CubicBezierToArcs(right, tolerance, maxDepth-1)
+	if !okR {
+		return nil, false
+	}
+	return append(arcsL, arcsR...), true
+}
+
+// Number of chords that are needed to approximate an arc of the given radius
+// and sweep angle, such that no chord deviates more than tolerance from the
+// arc.
+func ArcSegments(radius Float, sweep AngRad, tolerance Float) int {
+	if radius <= tolerance || tolerance <= 0 {
+		return 1
+	}
+	// Sweep angle of a chord with sagitta == tolerance
+	step := 2 * math.Acos(float64(1-tolerance/radius))
+	return max(1, int(math.Ceil(math.Abs(float64(sweep))/step)))
+}
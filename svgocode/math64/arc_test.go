@@ -0,0 +1,40 @@
+package math64
+
+import "testing"
+
+func TestArcBounds(t *testing.T) {
+	// Quarter circle from (1,0) to (0,1), counter-clockwise, passes no extreme point
+	arc := Arc{Start: VectorF2{X: 1, Y: 0}, End: VectorF2{X: 0, Y: 1}, Center: VectorF2{X: 0, Y: 0}, CCW: true}
+	min, max := arc.Bounds()
+	if min.DistEuclid(VectorF2{X: 0, Y: 0}) > 1e-9 || max.DistEuclid(VectorF2{X: 1, Y: 1}) > 1e-9 {
+		t.Errorf("Unexpected bounds of quarter circle: %v %v", min, max)
+	}
+	// Clockwise, the arc covers three quarters of the circle
+	arc.CCW = false
+	min, max = arc.Bounds()
+	if min.DistEuclid(VectorF2{X: -1, Y: -1}) > 1e-9 || max.DistEuclid(VectorF2{X: 1, Y: 1}) > 1e-9 {
+		t.Errorf("Unexpected bounds of three-quarter circle: %v %v", min, max)
+	}
+}
+
+func TestCubicBezierToArcs(t *testing.T) {
+	c := [4]VectorF2{{X: 0, Y: 0}, {X: 10, Y: 20}, {X: 30, Y: -10}, {X: 40, Y: 10}}
+	tolerance := Float(0.01)
+	arcs, ok := CubicBezierToArcs(c, tolerance, 8)
+	if !ok {
+		t.Fatalf("Failed to fit arcs to curve")
+	}
+	if !arcs[0].Start.Equal(c[0]) || !arcs[len(arcs)-1].End.Equal(c[3]) {
+		t.Errorf("Arcs do not start and end at the curve's end points")
+	}
+	for i, arc := range arcs {
+		if i > 0 && !arc.Start.Equal(arcs[i-1].End) {
+			t.Errorf("Arc %d does not start where its predecessor ends", i)
+		}
+		for j := 0; j <= 10; j++ {
+			if d := curveDist(c, arc.At(Float(j)/10)); d > 2*tolerance {
+				t.Errorf("Arc %d deviates by %f from curve", i, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package math64
+
+// Cubic Bézier interpolation
+func CubicBezier(t Float, p0, p1, p2, p3 VectorF2) VectorF2 {
+	u := 1 - t
+	a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return VectorF2{
+		X: a*p0.X + b*p1.X + c*p2.X + d*p3.X,
+		Y: a*p0.Y + b*p1.Y + c*p2.Y + d*p3.Y,
+	}
+}
+
+// Split a cubic Bézier curve at t via De Casteljau's algorithm
+func SplitCubicBezier(t Float, c [4]VectorF2) ([4]VectorF2, [4]VectorF2) {
+	lerp := func(a, b VectorF2) VectorF2 {
+		return a.Add(b.Sub(a).Scale(t))
+	}
+	p01, p12, p23 := lerp(c[0], c[1]), lerp(c[1], c[2]), lerp(c[2], c[3])
+	p012, p123 := lerp(p01, p12), lerp(p12, p23)
+	p0123 := lerp(p012, p123)
+	return [4]VectorF2{c[0], p01, p012, p0123}, [4]VectorF2{p0123, p123, p23, c[3]}
+}
+
+// Maximum number of times that a curve is subdivided when flattening it
+const maxFlattenDepth = 16
+
+// Approximate the cubic Bézier curve c with line segments that deviate at
+// most tolerance from the curve. Returns the end points of the segments, i.e.,
+// all points except c[0].
+func FlattenCubicBezier(c [4]VectorF2, tolerance Float) []VectorF2 {
+	return flattenCubicBezier(c, tolerance, maxFlattenDepth, nil)
+}
+
+func flattenCubicBezier(c [4]VectorF2, tolerance Float, depth int, points []VectorF2) []VectorF2 {
+	// The curve lies within the convex hull of its control points, hence the
+	// control points' distance to the chord bounds the curve's deviation.
+	if depth <= 0 || (DistLine(c[1], c[0], c[3]).Max(DistLine(c[2], c[0], c[3])) <= tolerance) {
+		return append(points, c[3])
+	}
+	left, right := SplitCubicBezier(0.5, c)
+	points = flattenCubicBezier(left, tolerance, depth-1, points)
+	return flattenCubicBezier(right, tolerance, depth-1, points)
+}
@@ -0,0 +1,41 @@
+package math64
+
+import "testing"
+
+func TestFlattenCubicBezier(t *testing.T) {
+	c := [4]VectorF2{{X: 0, Y: 0}, {X: 10, Y: 20}, {X: 30, Y: -10}, {X: 40, Y: 10}}
+	for _, tolerance := range []Float{1, 0.1, 0.01} {
+		points := FlattenCubicBezier(c, tolerance)
+		if !points[len(points)-1].Equal(c[3]) {
+			t.Errorf("Flattened curve does not end at the curve's end point")
+		}
+		prev := c[0]
+		for _, p := range points {
+			for i := 0; i <= 10; i++ {
+				q := prev.Add(p.Sub(prev).Scale(Float(i) / 10))
+				if d := curveDist(c, q); d > tolerance {
+					t.Errorf("Segment deviates by %f from curve (tolerance %f)", d, tolerance)
+				}
+			}
+			prev = p
+		}
+	}
+	// Smaller curves need fewer segments
+	small := [4]VectorF2{c[0], c[1].Scale(0.01), c[2].Scale(0.01), c[3].Scale(0.01)}
+	if n, m := len(FlattenCubicBezier(small, 0.05)), len(FlattenCubicBezier(c, 0.05)); n >= m {
+		t.Errorf("Expected fewer segments for smaller curve, got %d and %d", n, m)
+	}
+}
+
+func TestArcSegments(t *testing.T) {
+	if n := ArcSegments(0.01, 3, 0.05); n != 1 {
+		t.Errorf("Expected 1 segment for tiny arc, got %d", n)
+	}
+	// A chord of a half circle with radius 1 has a sagitta of 1
+	if n := ArcSegments(1, 3.14159, 1); n != 1 {
+		t.Errorf("Expected 1 segment, got %d", n)
+	}
+	if n := ArcSegments(100, 6.283, 0.05); n < 50 {
+		t.Errorf("Expected many segments for large circle, got %d", n)
+	}
+}
@@ -53,3 +53,20 @@ func (v VectorF3) DistEuclid(v2 VectorF3) Float {
 func (v VectorF3) DistManhattan(v2 VectorF3) Float {
 	return Float(math.Abs(float64(v.X-v2.X)) + math.Abs(float64(v.Y-v2.Y)) + math.Abs(float64(v.Z-v2.Z)))
 }
+
+// Rotate the vector around the origin
+func (v VectorF2) Rotate(a AngRad) VectorF2 {
+	c, s := a.Cos(), a.Sin()
+	return VectorF2{X: c*v.X - s*v.Y, Y: s*v.X + c*v.Y}
+}
+
+// Distance of point p to the line segment from a to b
+func DistLine(p, a, b VectorF2) Float {
+	ab := b.Sub(a)
+	l2 := ab.Dot(ab)
+	if l2 == 0 {
+		return p.DistEuclid(a)
+	}
+	t := (p.Sub(a).Dot(ab) / l2).Max(0).Min(1)
+	return p.DistEuclid(a.Add(ab.Scale(t)))
+}
@@ -26,6 +26,10 @@ func (f Float) Sqrt() Float {
 	return Float(math.Sqrt(float64(f)))
 }
 
+func (f Float) Abs() Float {
+	return Float(math.Abs(float64(f)))
+}
+
 func (f Float) Min(f2 Float) Float {
 	if f < f2 {
 		return f
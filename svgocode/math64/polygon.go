@@ -0,0 +1,127 @@
+package math64
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/abzicht/svgocode/llog"
+)
+
+// A sequence of connected points. When used as polygon, the last point is
+// implicitly connected to the first one.
+type Polyline []VectorF2
+
+// Determines which areas are inside of (self-)intersecting polygons, cf.
+// https://www.w3.org/TR/SVG2/painting.html#FillRuleProperty
+type FillRule string
+
+const (
+	FillRuleNonZero = FillRule("nonzero") // Default
+	FillRuleEvenOdd = FillRule("evenodd")
+)
+
+func FillRuleFromString(s string) FillRule {
+	switch FillRule(s) {
+	case FillRuleNonZero, FillRuleEvenOdd:
+		return FillRule(s)
+	case "":
+		return FillRuleNonZero
+	}
+	llog.Warnf("Unknown fill rule '%s', assuming '%s'\n", s, FillRuleNonZero)
+	return FillRuleNonZero
+}
+
+// Returns true, if a point with the given winding number is inside.
+func (r FillRule) Inside(winding int) bool {
+	if r == FillRuleEvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// Bounding box of all points of the given polylines
+func Bounds(polylines ...Polyline) (VectorF2, VectorF2) {
+	min := VectorF2{X: math.MaxFloat64, Y: math.MaxFloat64}
+	max := VectorF2{X: -math.MaxFloat64, Y: -math.MaxFloat64}
+	for _, pl := range polylines {
+		for _, p := range pl {
+			min = min.Min(p)
+			max = max.Max(p)
+		}
+	}
+	return min, max
+}
+
+// Produce parallel lines, spacing apart and rotated by angle, that cover the
+// area enclosed by the given polygons (as per fill rule). Every returned
+// polyline consists of two points. Subsequent rows alternate in direction,
+// such that travel in-between lines is short.
+func Hatch(polygons []Polyline, rule FillRule, spacing Float, angle AngRad) []Polyline {
+	if spacing <= 0 {
+		llog.Panicf("Hatch spacing must be positive, got %f", spacing)
+	}
+	// Rotate everything, such that hatch lines become horizontal
+	rotated := make([]Polyline, len(polygons))
+	for i, poly := range polygons {
+		rotated[i] = make(Polyline, len(poly))
+		for j, p := range poly {
+			rotated[i][j] = p.Rotate(-angle)
+		}
+	}
+	min, max := Bounds(rotated...)
+
+	type crossing struct {
+		x   Float
+		dir int // +1: edge goes up, -1: edge goes down
+	}
+	var lines []Polyline
+	reverse := false
+	for y := min.Y + spacing/2; y < max.Y; y += spacing {
+		var crossings []crossing
+		for _, poly := range rotated {
+			for i := range poly {
+				a, b := poly[i], poly[(i+1)%len(poly)]
+				// Half-open rule: vertices on the line are counted once
+				if (a.Y <= y) == (b.Y <= y) {
+					continue
+				}
+				x := a.X + (y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+				dir := 1
+				if b.Y < a.Y {
+					dir = -1
+				}
+				crossings = append(crossings, crossing{x: x, dir: dir})
+			}
+		}
+		slices.SortFunc(crossings, func(a, b crossing) int {
+			return cmp.Compare(a.x, b.x)
+		})
+		var row []Polyline
+		winding := 0
+		for i := 0; i+1 < len(crossings); i++ {
+			winding += crossings[i].dir
+			x0, x1 := crossings[i].x, crossings[i+1].x
+			if !rule.Inside(winding) || x0 == x1 {
+				continue
+			}
+			if len(row) > 0 && row[len(row)-1][1].X == x0 {
+				// Adjacent spans (e.g., of overlapping polygons) form one line
+				row[len(row)-1][1].X = x1
+				continue
+			}
+			row = append(row, Polyline{{X: x0, Y: y}, {X: x1, Y: y}})
+		}
+		if reverse {
+			slices.Reverse(row)
+			for _, line := range row {
+				slices.Reverse(line)
+			}
+		}
+		reverse = !reverse
+		for _, line := range row {
+			lines = append(lines, Polyline{line[0].Rotate(angle), line[1].Rotate(angle)})
+		}
+	}
+	return lines
+}
@@ -0,0 +1,40 @@
+package math64
+
+import "testing"
+
+func TestHatch(t *testing.T) {
+	outer := Polyline{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	inner := Polyline{{X: 4, Y: 4}, {X: 6, Y: 4}, {X: 6, Y: 6}, {X: 4, Y: 6}}
+	// Both squares share the same orientation: nonzero fills the hole,
+	// evenodd does not.
+	lines := Hatch([]Polyline{outer, inner}, FillRuleNonZero, 1, 0)
+	if len(lines) != 10 {
+		t.Errorf("Expected 10 hatch lines for nonzero fill rule, got %d", len(lines))
+	}
+	lines = Hatch([]Polyline{outer, inner}, FillRuleEvenOdd, 1, 0)
+	if len(lines) != 12 {
+		t.Errorf("Expected 12 hatch lines for evenodd fill rule, got %d", len(lines))
+	}
+	var length Float
+	for _, line := range lines {
+		if len(line) != 2 {
+			t.Fatalf("Hatch lines must consist of two points, got %d", len(line))
+		}
+		length += line[0].DistEuclid(line[1])
+	}
+	if (length - 96).Abs() > 1e-9 {
+		t.Errorf("Expected total hatch length of 96, got %f", length)
+	}
+	// Rows alternate in direction
+	if lines[0][0].X > lines[0][1].X || lines[1][0].X < lines[1][1].X {
+		t.Errorf("Expected alternating hatch directions, got %v and %v", lines[0], lines[1])
+	}
+	// Rotated hatching covers the same area
+	length = 0
+	for _, line := range Hatch([]Polyline{outer}, FillRuleNonZero, 0.01, AngDeg(45).Rad()) {
+		length += line[0].DistEuclid(line[1])
+	}
+	if area := length * 0.01; (area - 100).Abs() > 0.5 {
+		t.Errorf("Expected hatched area of ~100, got %f", area)
+	}
+}
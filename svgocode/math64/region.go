@@ -0,0 +1,188 @@
+package math64
+
+import (
+	"cmp"
+	"slices"
+)
+
+// An area of the plane, e.g., a clip region. Regions can be combined via
+// RegionUnion and RegionIntersection.
+type Region interface {
+	// Returns true, if p lies inside of the region
+	Contains(p VectorF2) bool
+	// Boundary segments of the region. A path only enters or leaves the
+	// region where it crosses one of them.
+	Edges() [][2]VectorF2
+}
+
+// The area enclosed by polygons, as per fill rule
+type PolygonRegion struct {
+	Polygons []Polyline
+	Rule     FillRule
+	min, max VectorF2
+}
+
+func NewPolygonRegion(polygons []Polyline, rule FillRule) *PolygonRegion {
+	r := new(PolygonRegion)
+	r.Polygons = polygons
+	r.Rule = rule
+	r.min, r.max = Bounds(polygons...)
+	return r
+}
+
+// Winding number of the polygons around p
+func Winding(polygons []Polyline, p VectorF2) int {
+	winding := 0
+	for _, poly := range polygons {
+		for i := range poly {
+			a, b := poly[i], poly[(i+1)%len(poly)]
+			if (a.Y <= p.Y) == (b.Y <= p.Y) {
+				continue
+			}
+			// Does the edge pass p on its right-hand side?
+			x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if x <= p.X {
+				continue
+			}
+			if b.Y > a.Y {
+				winding++
+			} else {
+				winding--
+			}
+		}
+	}
+	return winding
+}
+
+// Distance up to which points are considered to lie on a region's border
+const regionEpsilon Float = 1e-9
+
+// Points on the border (cf. regionEpsilon) lie inside of the region, such
+// that strokes along the border, e.g., of a viewport, are kept.
+func (r *PolygonRegion) Contains(p VectorF2) bool {
+	if p.X < r.min.X-regionEpsilon || p.Y < r.min.Y-regionEpsilon || p.X > r.max.X+regionEpsilon || p.Y > r.max.Y+regionEpsilon {
+		return false
+	}
+	if r.onBorder(p) {
+		return true
+	}
+	return r.Rule.Inside(Winding(r.Polygons, p))
+}
+
+// Returns true, if p lies on one of the polygons' edges
+func (r *PolygonRegion) onBorder(p VectorF2) bool {
+	for _, poly := range r.Polygons {
+		for i := range poly {
+			if DistLine(p, poly[i], poly[(i+1)%len(poly)]) <= regionEpsilon {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *PolygonRegion) Edges() [][2]VectorF2 {
+	var edges [][2]VectorF2
+	for _, poly := range r.Polygons {
+		for i := range poly {
+			edges = append(edges, [2]VectorF2{poly[i], poly[(i+1)%len(poly)]})
+		}
+	}
+	return edges
+}
+
+// All points that are inside of at least one of the regions
+type RegionUnion []Region
+
+func (u RegionUnion) Contains(p VectorF2) bool {
+	return slices.ContainsFunc(u, func(r Region) bool { return r.Contains(p) })
+}
+
+func (u RegionUnion) Edges() [][2]VectorF2 {
+	var edges [][2]VectorF2
+	for _, r := range u {
+		edges = append(edges, r.Edges()...)
+	}
+	return edges
+}
+
+// All points that are inside of all of the regions
+type RegionIntersection []Region
+
+func (n RegionIntersection) Contains(p VectorF2) bool {
+	for _, r := range n {
+		if !r.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n RegionIntersection) Edges() [][2]VectorF2 {
+	return RegionUnion(n).Edges()
+}
+
+// Parameter t (in [0, 1]) at which segment a-b intersects segment c-d.
+// Returns false, if the segments do not intersect or are parallel.
+func segmentIntersection(a, b, c, d VectorF2) (Float, bool) {
+	r := b.Sub(a)
+	s := d.Sub(c)
+	denom := r.X*s.Y - r.Y*s.X
+	if denom == 0 {
+		return 0, false
+	}
+	ac := c.Sub(a)
+	t := (ac.X*s.Y - ac.Y*s.X) / denom
+	u := (ac.X*r.Y - ac.Y*r.X) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// Clip a polyline to a region: returns the parts of the polyline that lie
+// inside of the region, in the order of the polyline.
+func ClipPolyline(pl Polyline, region Region) []Polyline {
+	edges := region.Edges()
+	var result []Polyline
+	var current Polyline
+	// Add the piece from a to b, continuing the current polyline, if possible
+	add := func(a, b VectorF2) {
+		if len(current) > 0 && current[len(current)-1].Equal(a) {
+			current = append(current, b)
+			return
+		}
+		if len(current) > 1 {
+			result = append(result, current)
+		}
+		current = Polyline{a, b}
+	}
+	for i := 0; i+1 < len(pl); i++ {
+		a, b := pl[i], pl[i+1]
+		segMin, segMax := a.Min(b), a.Max(b)
+		ts := []Float{0, 1}
+		for _, e := range edges {
+			if e[0].Max(e[1]).X < segMin.X || e[0].Max(e[1]).Y < segMin.Y || e[0].Min(e[1]).X > segMax.X || e[0].Min(e[1]).Y > segMax.Y {
+				continue
+			}
+			if t, ok := segmentIntersection(a, b, e[0], e[1]); ok {
+				ts = append(ts, t)
+			}
+		}
+		slices.SortFunc(ts, cmp.Compare[Float])
+		ts = slices.Compact(ts)
+		d := b.Sub(a)
+		for j := 0; j+1 < len(ts); j++ {
+			p0 := a.Add(d.Scale(ts[j]))
+			p1 := a.Add(d.Scale(ts[j+1]))
+			// Pieces along the region's border are kept (cf. PolygonRegion)
+			if region.Contains(p0.Add(p1).Scale(0.5)) {
+				add(p0, p1)
+			}
+		}
+	}
+	if len(current) > 1 {
+		result = append(result, current)
+	}
+	return result
+}
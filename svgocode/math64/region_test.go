@@ -0,0 +1,63 @@
+package math64
+
+import "testing"
+
+func TestClipPolyline(t *testing.T) {
+	square := func(x, y, size Float) Polyline {
+		return Polyline{{X: x, Y: y}, {X: x + size, Y: y}, {X: x + size, Y: y + size}, {X: x, Y: y + size}}
+	}
+	ring := NewPolygonRegion([]Polyline{square(0, 0, 10), square(4, 4, 2)}, FillRuleEvenOdd)
+	line := Polyline{{X: -5, Y: 5}, {X: 15, Y: 5}}
+	clipped := ClipPolyline(line, ring)
+	if len(clipped) != 2 {
+		t.Fatalf("Expected 2 pieces, got %d: %v", len(clipped), clipped)
+	}
+	if !clipped[0][0].Equal(VectorF2{X: 0, Y: 5}) || !clipped[0][1].Equal(VectorF2{X: 4, Y: 5}) ||
+		!clipped[1][0].Equal(VectorF2{X: 6, Y: 5}) || !clipped[1][1].Equal(VectorF2{X: 10, Y: 5}) {
+		t.Errorf("Unexpected pieces: %v", clipped)
+	}
+
+	// Pieces stay connected across polyline vertices
+	zigzag := Polyline{{X: 1, Y: 1}, {X: 2, Y: 8}, {X: 3, Y: 1}, {X: 3, Y: 20}}
+	clipped = ClipPolyline(zigzag, NewPolygonRegion([]Polyline{square(0, 0, 10)}, FillRuleNonZero))
+	if len(clipped) != 1 || len(clipped[0]) != 4 || !clipped[0][3].Equal(VectorF2{X: 3, Y: 10}) {
+		t.Errorf("Expected a single piece ending at the border, got %v", clipped)
+	}
+
+	intersection := RegionIntersection{
+		NewPolygonRegion([]Polyline{square(0, 0, 10)}, FillRuleNonZero),
+		RegionUnion{
+			NewPolygonRegion([]Polyline{square(5, 0, 10)}, FillRuleNonZero),
+			NewPolygonRegion([]Polyline{square(-3, 3, 4)}, FillRuleNonZero),
+		},
+	}
+	clipped = ClipPolyline(line, intersection)
+	var length Float
+	for _, pl := range clipped {
+		for i := 0; i+1 < len(pl); i++ {
+			length += pl[i].DistEuclid(pl[i+1])
+		}
+	}
+	if (length - 6).Abs() > 1e-9 {
+		t.Errorf("Expected clipped length 6, got %f (%v)", length, clipped)
+	}
+
+	// Strokes on the border are kept, strokes next to it are not
+	clip := NewPolygonRegion([]Polyline{square(0, 0, 10)}, FillRuleNonZero)
+	borders := []Polyline{
+		{{X: 0, Y: 0}, {X: 10, Y: 0}},
+		{{X: 10, Y: 0}, {X: 10, Y: 10}},
+		{{X: 10, Y: 10}, {X: 0, Y: 10}},
+		{{X: 0, Y: 10}, {X: 0, Y: 0}},
+		{{X: -2, Y: 10 + 1e-12}, {X: 12, Y: 10 + 1e-12}},
+	}
+	for _, border := range borders {
+		clipped = ClipPolyline(border, clip)
+		if len(clipped) != 1 || clipped[0][0].DistEuclid(clipped[0][1]) < 10-1e-9 {
+			t.Errorf("Expected stroke %v on the border to be kept, got %v", border, clipped)
+		}
+	}
+	if clipped = ClipPolyline(Polyline{{X: 0, Y: 10.5}, {X: 10, Y: 10.5}}, clip); len(clipped) != 0 {
+		t.Errorf("Expected stroke outside of the border to be removed, got %v", clipped)
+	}
+}
@@ -1,7 +1,9 @@
 package math64
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -11,14 +13,32 @@ import (
 type UnitLength string
 
 const (
-	UnitMM = UnitLength("mm") // Millimeter
-	UnitCM = UnitLength("cm") // Centimeter
-	UnitIN = UnitLength("in") // Inches
-	UnitPT = UnitLength("pt") // Points (Requires DPI)
-	UnitPX = UnitLength("px") // Pixels (let's figure that one out later)
+	UnitMM      = UnitLength("mm") // Millimeter
+	UnitCM      = UnitLength("cm") // Centimeter
+	UnitIN      = UnitLength("in") // Inches
+	UnitPT      = UnitLength("pt") // Points (1/72 in)
+	UnitPC      = UnitLength("pc") // Picas (12 pt)
+	UnitPX      = UnitLength("px") // Pixels (Requires DPI, cf. LengthConvertDPI)
+	UnitEM      = UnitLength("em") // Relative to the font size
+	UnitPercent = UnitLength("%")  // Relative to the viewport
+	UnitNone    = UnitLength("")   // No unit given
 )
 
-var UnitLengths []UnitLength = []UnitLength{UnitMM, UnitCM, UnitIN, UnitPT, UnitPX}
+var UnitLengths []UnitLength = []UnitLength{UnitMM, UnitCM, UnitIN, UnitPT, UnitPC, UnitPX, UnitEM, UnitPercent}
+
+// Returns true, if the unit describes a fixed physical length (i.e., it can
+// be converted via LengthConvert).
+func (u UnitLength) IsAbsolute() bool {
+	switch u {
+	case UnitMM, UnitCM, UnitIN, UnitPT, UnitPC, UnitPX:
+		return true
+	}
+	return false
+}
+
+// Dots per inch that pixels are converted with, unless configured otherwise
+// (cf. LengthConvertDPI)
+const DefaultDPI Float = 96
 
 func UnitLengthFromString(s string) UnitLength {
 	unitT := UnitLength(strings.ToLower(s))
@@ -53,8 +73,39 @@ func NumberUnit(s string) (Float, UnitLength) {
 	return Float(value), unit
 }
 
-// Convert a length l from unit 'from' to unit 'to'
+var lengthMatcher *regexp.Regexp = regexp.MustCompile(`^\s*([+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?)\s*([a-zA-Z%]*)\s*$`)
+
+// Given an input string such as "32mm", "-1.5e2", or "50%", determine its
+// value and unit. Other than NumberUnit, the unit is optional (UnitNone) and
+// errors are returned instead of panicking.
+func ParseLength(s string) (Float, UnitLength, error) {
+	matches := lengthMatcher.FindStringSubmatch(s)
+	if len(matches) != 3 {
+		return 0, UnitNone, fmt.Errorf("unknown length format: '%s'", s)
+	}
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, UnitNone, fmt.Errorf("failed to parse length '%s': %w", s, err)
+	}
+	unit := UnitLength(strings.ToLower(matches[2]))
+	if unit != UnitNone && !slices.Contains(UnitLengths, unit) {
+		return 0, UnitNone, fmt.Errorf("unknown unit type in length '%s'", s)
+	}
+	return Float(value), unit, nil
+}
+
+// Convert a length l from unit 'from' to unit 'to', with pixels at
+// DefaultDPI. Only absolute units are supported; em and percentages need to
+// be resolved beforehand.
 func LengthConvert(l Float, from, to UnitLength) Float {
+	return LengthConvertDPI(l, from, to, DefaultDPI)
+}
+
+// Same as LengthConvert, with pixels at the given dots per inch
+func LengthConvertDPI(l Float, from, to UnitLength, dpi Float) Float {
+	if from == to {
+		return l
+	}
 	var tmp Float
 	switch from {
 	case UnitMM:
@@ -63,6 +114,12 @@ func LengthConvert(l Float, from, to UnitLength) Float {
 		tmp = l * 10
 	case UnitIN:
 		tmp = l * 25.4
+	case UnitPT:
+		tmp = l * 25.4 / 72
+	case UnitPC:
+		tmp = l * 25.4 / 6
+	case UnitPX:
+		tmp = l * 25.4 / dpi
 	default:
 		llog.Panicf("Conversion of type %s is not supported", from)
 	}
@@ -74,6 +131,12 @@ func LengthConvert(l Float, from, to UnitLength) Float {
 		return tmp / 10.0
 	case UnitIN:
 		return tmp / 25.4
+	case UnitPT:
+		return tmp * 72 / 25.4
+	case UnitPC:
+		return tmp * 6 / 25.4
+	case UnitPX:
+		return tmp * dpi / 25.4
 	default:
 		llog.Panicf("Conversion of type %s is not supported", to)
 	}
@@ -0,0 +1,36 @@
+package math64
+
+import "testing"
+
+func TestParseLength(t *testing.T) {
+	inputs := []string{"10", "-1.5e2mm", "50%", " 2em ", ".5in", "12PT", "3px"}
+	values := []Float{10, -150, 50, 2, 0.5, 12, 3}
+	units := []UnitLength{UnitNone, UnitMM, UnitPercent, UnitEM, UnitIN, UnitPT, UnitPX}
+	for i, input := range inputs {
+		v, u, err := ParseLength(input)
+		if err != nil {
+			t.Errorf("Failed to parse '%s': %s", input, err.Error())
+			continue
+		}
+		if v != values[i] || u != units[i] {
+			t.Errorf("Parsed '%s' as %f%s, expected %f%s", input, v, u, values[i], units[i])
+		}
+	}
+	for _, input := range []string{"", "mm", "10 foo", "1,5"} {
+		if _, _, err := ParseLength(input); err == nil {
+			t.Errorf("Expected error for malformed length '%s'", input)
+		}
+	}
+}
+
+func TestLengthConvert(t *testing.T) {
+	if l := LengthConvert(96, UnitPX, UnitIN); (l - 1).Abs() > 1e-9 {
+		t.Errorf("96px should be 1in at 96 DPI, got %f", l)
+	}
+	if l := LengthConvert(1, UnitPC, UnitPT); (l - 12).Abs() > 1e-9 {
+		t.Errorf("1pc should be 12pt, got %f", l)
+	}
+	if l := LengthConvertDPI(1, UnitPX, UnitPT, 72); (l - 1).Abs() > 1e-9 {
+		t.Errorf("1px should be 1pt at 72 DPI, got %f", l)
+	}
+}
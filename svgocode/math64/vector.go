@@ -1,6 +1,9 @@
 package math64
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type VectorT2[T comparable] struct {
 	X T
@@ -48,6 +51,31 @@ func (v VectorF4) Add(v2 VectorF4) VectorF4 {
 	return VectorF4{X: v.X + v2.X, Y: v.Y + v2.Y, Z: v.Z + v2.Z, W: v.W + v2.W}
 }
 
+func (v VectorF2) Sub(v2 VectorF2) VectorF2 {
+	return VectorF2{X: v.X - v2.X, Y: v.Y - v2.Y}
+}
+
+func (v VectorF2) Scale(f Float) VectorF2 {
+	return VectorF2{X: v.X * f, Y: v.Y * f}
+}
+
+func (v VectorF2) Dot(v2 VectorF2) Float {
+	return v.X*v2.X + v.Y*v2.Y
+}
+
+func (v VectorF2) Length() Float {
+	return Float(math.Hypot(float64(v.X), float64(v.Y)))
+}
+
+// Vector of length 1 with the same direction (or the zero vector)
+func (v VectorF2) Normalize() VectorF2 {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return v.Scale(1 / l)
+}
+
 func (v VectorF2) Min(v2 VectorF2) VectorF2 {
 	return VectorF2{X: v.X.Min(v2.X), Y: v.Y.Min(v2.Y)}
 }
@@ -2,14 +2,15 @@ package svgocode
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/abzicht/svgocode/svgocode/conf"
 	"github.com/abzicht/svgocode/svgocode/gcode"
 )
 
-// Create metadata for gcode output
-
-func GcodeAddSummary(g *gcode.Gcode, runtConf *conf.RuntimeConfig) *gcode.Gcode {
+// Create metadata for gcode output. pens lists the pens in order of use (if
+// multiple pens are configured).
+func GcodeAddSummary(g *gcode.Gcode, runtConf *conf.RuntimeConfig, pens []conf.PenConfig) *gcode.Gcode {
 	if !runtConf.Plotter.RemoveComments {
 		gmeta := g.CopyMeta()
 		ins := gcode.NewIns(runtConf)
@@ -18,6 +19,9 @@ func GcodeAddSummary(g *gcode.Gcode, runtConf *conf.RuntimeConfig) *gcode.Gcode
 		ins.AddComment(gmeta, fmt.Sprintf("Coordinates (min): %s", g.BoundsMin.String()))
 		ins.AddComment(gmeta, fmt.Sprintf("Coordinates (max): %s", g.BoundsMax.String()))
 		ins.AddComment(gmeta, fmt.Sprintf("Number of instructions: %d", g.Code.NumInstructions()))
+		for i, pen := range pens {
+			ins.AddComment(gmeta, fmt.Sprintf("Pen %d: %s (%s)", i+1, pen.Name, strings.Join(pen.Colors, ", ")))
+		}
 		gmeta.Code.Append(g.Code)
 		return gmeta
 	}
@@ -0,0 +1,89 @@
+package ordering
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// An orderer that can be stopped early. When ctx is done, it returns the best
+// order that it found so far.
+type OrderingContextI interface {
+	OrderingI
+	OrderContext(ctx context.Context, gcodes []*gcode.Gcode) []*gcode.Gcode
+}
+
+// Cause of contexts whose ordering timeout expired
+var ErrOrderingTimeout = errors.New("ordering timeout expired")
+
+// An orderer that stops when the given context is done or, if timeout is
+// positive, when an ordering takes longer than timeout. Orderers that cannot
+// be stopped (cf. OrderingContextI) run until they are finished.
+type Bounded struct {
+	ctx     context.Context
+	order   OrderingI
+	timeout time.Duration
+}
+
+func NewBounded(ctx context.Context, order OrderingI, timeout time.Duration) *Bounded {
+	b := new(Bounded)
+	b.ctx = ctx
+	b.order = order
+	b.timeout = timeout
+	return b
+}
+
+func (b *Bounded) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	ctx := b.ctx
+	if b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, b.timeout, ErrOrderingTimeout)
+		defer cancel()
+	}
+	return orderContext(ctx, b.order, gcodes)
+}
+
+// Minimum time between progress reports
+const progressInterval = time.Second
+
+// Limits progress reports of long orderings to one per progressInterval. The
+// first report is due after progressInterval, so quick orderings report
+// nothing.
+type progress struct {
+	last time.Time
+}
+
+func newProgress() *progress {
+	return &progress{last: time.Now()}
+}
+
+// Returns true, if progress should be reported now
+func (p *progress) due() bool {
+	if time.Since(p.last) < progressInterval {
+		return false
+	}
+	p.last = time.Now()
+	return true
+}
+
+// Returns true (and logs why), if the ordering has to stop
+func stopped(ctx context.Context, alg OrderingAlg) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	llog.Infof("Ordering (%s) stopped: %s. Using the best order found so far.\n", alg, context.Cause(ctx))
+	return true
+}
+
+// Report the current travel distance of an improving orderer
+func reportDistance(alg OrderingAlg, dist, initial math64.Float, details string) {
+	var percent math64.Float = 100
+	if initial > 0 {
+		percent = 100 * dist / initial
+	}
+	llog.Infof("Ordering (%s): travel distance %.0f (%.0f%% of initial), %s\n", alg, dist, percent, details)
+}
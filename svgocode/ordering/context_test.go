@@ -0,0 +1,45 @@
+package ordering
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abzicht/svgocode/svgocode/gcode"
+)
+
+func numInstructions(gcodes []*gcode.Gcode) int {
+	n := 0
+	for _, g := range gcodes {
+		n += g.Code.NumInstructions()
+	}
+	return n
+}
+
+func TestBoundedStopped(t *testing.T) {
+	gcodes := randomGcodes(500)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, o := range []OrderingI{NewTwoOpt(), NewGreedy(), NewSpatial(), NewLifo()} {
+		start := time.Now()
+		ordered := NewBounded(ctx, o, 0).Order(gcodes)
+		if time.Since(start) > time.Second {
+			t.Errorf("%T: expected a stopped ordering to return immediately, took %v", o, time.Since(start))
+		}
+		if numInstructions(ordered) != numInstructions(gcodes) {
+			t.Errorf("%T: expected %d instructions, got %d", o, numInstructions(gcodes), numInstructions(ordered))
+		}
+	}
+}
+
+func TestBoundedTimeout(t *testing.T) {
+	gcodes := randomGcodes(3000)
+	start := time.Now()
+	ordered := NewBounded(context.Background(), NewTwoOpt(), 100*time.Millisecond).Order(gcodes)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the ordering to stop after its timeout, took %v", elapsed)
+	}
+	if len(ordered) != len(gcodes) {
+		t.Errorf("Expected %d segments, got %d", len(gcodes), len(ordered))
+	}
+}
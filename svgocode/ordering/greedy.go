@@ -1,6 +1,9 @@
 package ordering
 
 import (
+	"context"
+	"math"
+
 	"github.com/abzicht/svgocode/llog"
 	"github.com/abzicht/svgocode/svgocode/gcode"
 	"github.com/abzicht/svgocode/svgocode/math64"
@@ -15,40 +18,53 @@ func NewGreedy() *Greedy {
 	return new(Greedy)
 }
 
-func remove(g []*gcode.Gcode, i int) []*gcode.Gcode {
-	g[i] = g[len(g)-1]
-	return g[:len(g)-1]
+func remove(s []*segment, i int) []*segment {
+	s[i] = s[len(s)-1]
+	return s[:len(s)-1]
 }
 
-// Time: O(n^2), Space: O(n)
 func (gr *Greedy) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	return gr.OrderContext(context.Background(), gcodes)
+}
+
+// Time: O(n^2*k) for segments with up to k orientations, Space: O(n*k). If
+// stopped, the remaining segments follow in no particular order.
+func (gr *Greedy) OrderContext(ctx context.Context, gcodes []*gcode.Gcode) []*gcode.Gcode {
 	if len(gcodes) == 0 {
 		return gcodes
 	}
-	var ordered []*gcode.Gcode
-
-	candidates := make([]*gcode.Gcode, len(gcodes))
-	if n := copy(candidates, gcodes); n < len(gcodes) {
-		llog.Panicf("Failed to copy gcode list for ordering (only copied %d).", n)
-	}
+	var ordered []*segment
 
+	candidates := newSegments(gcodes)
 	current := candidates[0]
 	ordered = append(ordered, current)
 	candidates = candidates[1:]
 
+	p := newProgress()
 	for len(candidates) > 0 {
-		var bestIndex int = 0
-		var bestDist math64.Float = current.EndCoord.DistEuclid(candidates[bestIndex].StartCoord)
+		if stopped(ctx, OrderingAlgGreedy) {
+			ordered = append(ordered, candidates...)
+			break
+		}
+		if p.due() {
+			llog.Infof("Ordering (%s): %d of %d segments\n", OrderingAlgGreedy, len(ordered), len(gcodes))
+		}
+		var bestIndex, bestOrientation int = 0, 0
+		var bestDist math64.Float = math64.Float(math.Inf(1))
 		for j, candidate := range candidates {
-			dist := current.EndCoord.DistEuclid(candidate.StartCoord)
+			o, dist := candidate.closestEntry(current.exit())
 			if bestDist > dist {
 				bestIndex = j
+				bestOrientation = o
 				bestDist = dist
 			}
 		}
 		current = candidates[bestIndex]
+		current.o = bestOrientation
 		ordered = append(ordered, current)
 		candidates = remove(candidates, bestIndex)
 	}
-	return ordered
+	// Choosing the nearest entry does not consider where segments are left
+	orient(ordered)
+	return segmentsToGcode(ordered)
 }
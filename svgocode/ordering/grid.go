@@ -0,0 +1,124 @@
+package ordering
+
+import (
+	"math"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// A uniform grid over points, for finding nearest neighbors. Points can be
+// removed from the grid.
+type grid struct {
+	min        math64.VectorF2
+	cellSize   math64.Float
+	cols, rows int
+	cells      [][]int // Indices of the points in each cell
+	slots      []int   // Index of each point within its cell (-1: removed)
+	points     []math64.VectorF2
+}
+
+// Create a grid with roughly perCell points per cell
+func newGrid(points []math64.VectorF2, perCell int) *grid {
+	gr := &grid{points: points, slots: make([]int, len(points)), cols: 1, rows: 1, cellSize: 1}
+	if len(points) == 0 {
+		gr.cells = make([][]int, 1)
+		return gr
+	}
+	gr.min = points[0]
+	gridMax := points[0]
+	for _, p := range points {
+		gr.min = gr.min.Min(p)
+		gridMax = gridMax.Max(p)
+	}
+	size := gridMax.Sub(gr.min)
+	numCells := math64.Float(len(points)/perCell + 1)
+	gr.cellSize = math64.Float(math.Sqrt(float64(size.X * size.Y / numCells)))
+	// Points on a line
+	gr.cellSize = math64.Float(math.Max(float64(gr.cellSize), float64(size.X+size.Y)/float64(numCells)))
+	if gr.cellSize <= 0 {
+		gr.cellSize = 1
+	}
+	gr.cols = int(size.X/gr.cellSize) + 1
+	gr.rows = int(size.Y/gr.cellSize) + 1
+	gr.cells = make([][]int, gr.cols*gr.rows)
+	for i, p := range points {
+		c := gr.cell(p)
+		gr.slots[i] = len(gr.cells[c])
+		gr.cells[c] = append(gr.cells[c], i)
+	}
+	return gr
+}
+
+func (gr *grid) coords(p math64.VectorF2) (int, int) {
+	x := int((p.X - gr.min.X) / gr.cellSize)
+	y := int((p.Y - gr.min.Y) / gr.cellSize)
+	return min(max(x, 0), gr.cols-1), min(max(y, 0), gr.rows-1)
+}
+
+func (gr *grid) cell(p math64.VectorF2) int {
+	x, y := gr.coords(p)
+	return y*gr.cols + x
+}
+
+// Remove the point with the given index
+func (gr *grid) remove(i int) {
+	if gr.slots[i] < 0 {
+		return
+	}
+	cell := gr.cells[gr.cell(gr.points[i])]
+	last := cell[len(cell)-1]
+	cell[gr.slots[i]] = last
+	gr.slots[last] = gr.slots[i]
+	gr.cells[gr.cell(gr.points[i])] = cell[:len(cell)-1]
+	gr.slots[i] = -1
+}
+
+// The indices of the k points that are nearest to p, nearest first. Points
+// for which skip returns true are ignored.
+func (gr *grid) nearest(p math64.VectorF2, k int, skip func(i int) bool) []int {
+	var found []int
+	var dists []math64.Float
+	cx, cy := gr.coords(p)
+	// Search rings of cells around p's cell. Points outside of ring r are
+	// at least r cells away.
+	for r := 0; r < max(gr.cols, gr.rows); r++ {
+		if len(found) == k && dists[k-1] <= math64.Float(r-1)*gr.cellSize {
+			break
+		}
+		for y := cy - r; y <= cy+r; y++ {
+			if y < 0 || y >= gr.rows {
+				continue
+			}
+			step := 1
+			if y != cy-r && y != cy+r {
+				// Only the ring's left and right cells
+				step = 2 * r
+			}
+			for x := cx - r; x <= cx+r; x += max(step, 1) {
+				if x < 0 || x >= gr.cols {
+					continue
+				}
+				for _, i := range gr.cells[y*gr.cols+x] {
+					if skip != nil && skip(i) {
+						continue
+					}
+					d := p.DistEuclid(gr.points[i])
+					if len(found) == k && d >= dists[k-1] {
+						continue
+					}
+					// Insert, keeping the candidates sorted
+					if len(found) < k {
+						found = append(found, i)
+						dists = append(dists, d)
+					}
+					j := len(found) - 1
+					for ; j > 0 && dists[j-1] > d; j-- {
+						found[j], dists[j] = found[j-1], dists[j-1]
+					}
+					found[j], dists[j] = i, d
+				}
+			}
+		}
+	}
+	return found
+}
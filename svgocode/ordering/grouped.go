@@ -0,0 +1,65 @@
+package ordering
+
+import (
+	"context"
+	"strings"
+
+	"github.com/abzicht/svgocode/svgocode/gcode"
+)
+
+// An orderer that keeps the sequence of the segments' groups (cf.
+// gcode.Gcode's Groups) and orders the segments within each group with the
+// given orderer. Groups are nested up to the given depth (1: top-level
+// groups, such as Inkscape layers; negative: all groups); deeper groups are
+// ordered as part of their ancestors. Groups are drawn in the order of their
+// first segments.
+type Grouped struct {
+	order OrderingI
+	depth int
+}
+
+func NewGrouped(order OrderingI, depth int) *Grouped {
+	gr := new(Grouped)
+	gr.order = order
+	gr.depth = depth
+	return gr
+}
+
+func (gr *Grouped) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	return gr.OrderContext(context.Background(), gcodes)
+}
+
+func (gr *Grouped) OrderContext(ctx context.Context, gcodes []*gcode.Gcode) []*gcode.Gcode {
+	var keys []string
+	groups := make(map[string][]*gcode.Gcode)
+	for _, g := range gcodes {
+		key := gr.key(g)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], g)
+	}
+	ordered := make([]*gcode.Gcode, 0, len(gcodes))
+	done := false
+	for _, key := range keys {
+		if done {
+			// Stopped (and reported) while ordering a previous group: the
+			// remaining groups keep their order
+			ordered = append(ordered, groups[key]...)
+			continue
+		}
+		ordered = append(ordered, orderContext(ctx, gr.order, groups[key])...)
+		done = ctx.Err() != nil
+	}
+	return ordered
+}
+
+// Identifies the group of the segment, up to the orderer's depth
+func (gr *Grouped) key(g *gcode.Gcode) string {
+	groups := g.Groups
+	if gr.depth >= 0 && len(groups) > gr.depth {
+		groups = groups[:gr.depth]
+	}
+	// Group names cannot contain null characters
+	return strings.Join(groups, "\x00")
+}
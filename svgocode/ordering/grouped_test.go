@@ -0,0 +1,56 @@
+package ordering
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/gcode"
+)
+
+func TestGrouped(t *testing.T) {
+	gcodes := randomGcodes(300)
+	paths := [][]string{{"b"}, {"a", "x"}, {"a"}, {"a", "y"}, nil}
+	for i, g := range gcodes {
+		g.Groups = paths[i%len(paths)]
+	}
+	expected := []struct {
+		depth  int
+		groups []string
+	}{
+		{1, []string{"b", "a", ""}},
+		{2, []string{"b", "a\x00x", "a", "a\x00y", ""}},
+		{-1, []string{"b", "a\x00x", "a", "a\x00y", ""}},
+	}
+	for _, e := range expected {
+		gr := NewGrouped(NewGreedy(), e.depth)
+		ordered := gr.Order(gcodes)
+		if numInstructions(ordered) != numInstructions(gcodes) {
+			t.Errorf("Depth %d: expected %d instructions, got %d", e.depth, numInstructions(gcodes), numInstructions(ordered))
+		}
+		// Every group is drawn in one go, in the order of first occurrence
+		var groups []string
+		for _, g := range ordered {
+			if key := gr.key(g); len(groups) == 0 || groups[len(groups)-1] != key {
+				groups = append(groups, key)
+			}
+		}
+		if !slices.Equal(groups, e.groups) {
+			t.Errorf("Depth %d: expected groups %q, got %q", e.depth, e.groups, groups)
+		}
+	}
+}
+
+// Shapes and subgroups that are siblings: the group's own segments are drawn
+// before the subgroup's, if the group's first segment precedes it
+func TestGroupedMixed(t *testing.T) {
+	gcodes := randomGcodes(3)
+	groups := [][]string{{"A"}, {"A", "g1"}, {"A"}}
+	for i, g := range gcodes {
+		g.Groups = groups[i]
+	}
+	ordered := NewGrouped(NewNone(), -1).Order(gcodes)
+	expected := []*gcode.Gcode{gcodes[0], gcodes[2], gcodes[1]}
+	if !slices.Equal(ordered, expected) {
+		t.Errorf("Expected segments in the order 0, 2, 1")
+	}
+}
@@ -10,6 +10,7 @@ type OrderingAlg string
 const (
 	OrderingAlgTwoOpt                   = OrderingAlg("2opt")
 	OrderingAlgGreedy                   = OrderingAlg("greedy")
+	OrderingAlgSpatial                  = OrderingAlg("spatial")
 	OrderingAlgNone                     = OrderingAlg("none")
 	OrderingAlgLifo                     = OrderingAlg("reverse")
 	OrderingAlgNumInstructions          = OrderingAlg("numinstructions")
@@ -20,6 +21,17 @@ type OrderingI interface {
 	Order([]*gcode.Gcode) []*gcode.Gcode
 }
 
+// Returns true, if the algorithm minimizes travel distance. Such algorithms
+// can order spatial tiles of segments independently (cf. Tiled).
+func (alg OrderingAlg) MinimizesTravel() bool {
+	switch alg {
+	case OrderingAlg(""), OrderingAlgTwoOpt, OrderingAlgGreedy, OrderingAlgSpatial:
+		return true
+	default:
+		return false
+	}
+}
+
 func ParseOrdering(alg OrderingAlg) OrderingI {
 	switch alg {
 	case OrderingAlg(""): // Default is 2opt
@@ -28,6 +40,8 @@ func ParseOrdering(alg OrderingAlg) OrderingI {
 		return NewTwoOpt()
 	case OrderingAlgGreedy:
 		return NewGreedy()
+	case OrderingAlgSpatial:
+		return NewSpatial()
 	case OrderingAlgNone:
 		return NewNone()
 	case OrderingAlgLifo:
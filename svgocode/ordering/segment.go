@@ -0,0 +1,160 @@
+package ordering
+
+import (
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// A way of drawing a segment: forwards or backwards and, if the segment is
+// closed, starting at one of its vertices
+type orientation struct {
+	reversed bool
+	vertex   int
+	entry    math64.VectorF3 // Where drawing starts
+	exit     math64.VectorF3 // Where drawing ends
+}
+
+// A gcode segment and the ways it can be drawn. Open segments can be drawn
+// backwards, closed segments can start at any vertex.
+type segment struct {
+	g            *gcode.Gcode
+	orientations []orientation
+	o            int // Index of the chosen orientation
+}
+
+func newSegment(g *gcode.Gcode) *segment {
+	s := &segment{g: g}
+	forwards := orientation{entry: g.StartCoord, exit: g.EndCoord}
+	switch {
+	case g.IsClosed():
+		for i, v := range g.Vertices() {
+			s.orientations = append(s.orientations, orientation{
+				vertex: i,
+				entry:  math64.VectorF3{X: v.X, Y: v.Y, Z: g.StartCoord.Z},
+				exit:   math64.VectorF3{X: v.X, Y: v.Y, Z: g.EndCoord.Z},
+			})
+		}
+	case g.IsReversible():
+		s.orientations = []orientation{forwards, {
+			reversed: true,
+			entry:    math64.VectorF3{X: g.EndCoord.X, Y: g.EndCoord.Y, Z: g.StartCoord.Z},
+			exit:     math64.VectorF3{X: g.StartCoord.X, Y: g.StartCoord.Y, Z: g.EndCoord.Z},
+		}}
+	default:
+		s.orientations = []orientation{forwards}
+	}
+	return s
+}
+
+func newSegments(gcodes []*gcode.Gcode) []*segment {
+	segments := make([]*segment, len(gcodes))
+	for i, g := range gcodes {
+		segments[i] = newSegment(g)
+	}
+	return segments
+}
+
+func (s *segment) entry() math64.VectorF3 {
+	return s.orientations[s.o].entry
+}
+
+func (s *segment) exit() math64.VectorF3 {
+	return s.orientations[s.o].exit
+}
+
+// The orientation whose entry is closest to the given position
+func (s *segment) closestEntry(pos math64.VectorF3) (int, math64.Float) {
+	best, bestDist := 0, pos.DistEuclid(s.orientations[0].entry)
+	for i, o := range s.orientations[1:] {
+		if dist := pos.DistEuclid(o.entry); dist < bestDist {
+			best, bestDist = i+1, dist
+		}
+	}
+	return best, bestDist
+}
+
+// Flip the direction of open segments
+func (s *segment) reverse() {
+	if len(s.orientations) == 2 && s.orientations[1].reversed {
+		s.o = 1 - s.o
+	}
+}
+
+// The segment's gcode, drawn in the chosen orientation
+func (s *segment) gcode() *gcode.Gcode {
+	o := s.orientations[s.o]
+	g := s.g
+	if o.reversed {
+		g = g.Reversed()
+	}
+	if o.vertex > 0 {
+		g = g.StartingAt(o.vertex)
+	}
+	return g
+}
+
+func segmentsToGcode(segments []*segment) []*gcode.Gcode {
+	gcodes := make([]*gcode.Gcode, len(segments))
+	for i, s := range segments {
+		gcodes[i] = s.gcode()
+	}
+	return gcodes
+}
+
+// Travel distance between the segments, as per their chosen orientations
+func distanceInBetween(segments []*segment) math64.Float {
+	var dist math64.Float = 0
+	for i := range segments[1:] {
+		dist += segments[i].exit().DistEuclid(segments[i+1].entry())
+	}
+	return dist
+}
+
+// Choose the orientation of every segment such that the travel distance in
+// between them is minimal, keeping their order.
+// Time: O(n*k^2) for segments with up to k orientations, Space: O(n*k)
+func orient(segments []*segment) {
+	if len(segments) == 0 {
+		return
+	}
+	// dist[i]: minimal distance up to the current segment, if it is drawn in
+	// orientation i. prev[n][i]: the preceding segment's orientation in that
+	// case.
+	dist := make([]math64.Float, len(segments[0].orientations))
+	prev := make([][]int, len(segments))
+	for n := 1; n < len(segments); n++ {
+		last, current := segments[n-1], segments[n]
+		dist2 := make([]math64.Float, len(current.orientations))
+		prev[n] = make([]int, len(current.orientations))
+		for i, o := range current.orientations {
+			for j, o2 := range last.orientations {
+				d := dist[j] + o2.exit.DistEuclid(o.entry)
+				if j == 0 || d < dist2[i] {
+					dist2[i] = d
+					prev[n][i] = j
+				}
+			}
+		}
+		dist = dist2
+	}
+	best := 0
+	for i := range dist {
+		if dist[i] < dist[best] {
+			best = i
+		}
+	}
+	for n := len(segments) - 1; n > 0; n-- {
+		segments[n].o = best
+		best = prev[n][best]
+	}
+	segments[0].o = best
+}
+
+// Draw the given gcode segments in the order given, but reverse open
+// segments and rotate closed ones such that the travel distance in between
+// them is minimal.
+func Orient(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	segments := newSegments(gcodes)
+	orient(segments)
+	return segmentsToGcode(segments)
+}
@@ -0,0 +1,50 @@
+package ordering
+
+import (
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Gcode that draws the given polyline
+func polylineGcode(ins *gcode.Ins, points ...math64.VectorF2) *gcode.Gcode {
+	g := gcode.NewGcode()
+	ins.MoveRetracted(g, points[0])
+	ins.DrawPos(g)
+	for _, p := range points[1:] {
+		ins.Draw(g, p)
+	}
+	g.StartCoord = math64.VectorF3{X: points[0].X, Y: points[0].Y, Z: g.EndCoord.Z}
+	return g
+}
+
+func TestOrient(t *testing.T) {
+	ins := gcode.NewIns(conf.NewRuntimeConfig(conf.PlotterConfigLongerLK5ProDefault(), math64.UnitMM, math64.UnitMM))
+	gcodes := []*gcode.Gcode{
+		polylineGcode(ins, math64.VectorF2{X: 10, Y: 0}, math64.VectorF2{X: 0, Y: 0}),
+		polylineGcode(ins, math64.VectorF2{X: 20, Y: 0}, math64.VectorF2{X: 11, Y: 0}),
+		// A closed square whose nearest vertex is its third
+		polylineGcode(ins, math64.VectorF2{X: 30, Y: 10}, math64.VectorF2{X: 40, Y: 10},
+			math64.VectorF2{X: 40, Y: 0}, math64.VectorF2{X: 30, Y: 0}, math64.VectorF2{X: 30, Y: 10}),
+	}
+	before := gcode.TotalDistanceInBetween(gcodes)
+	oriented := Orient(gcodes)
+	if after := gcode.TotalDistanceInBetween(oriented); after > 11 || after >= before {
+		t.Errorf("Expected a travel distance of at most 11 (was %.2f before), got %.2f", before, after)
+	}
+	if start := oriented[0].StartCoord; start.X != 0 || start.Y != 0 {
+		t.Errorf("Expected the first path to be reversed, starting at 0/0, got %v", start)
+	}
+	if start := oriented[2].StartCoord; start.X != 30 || start.Y != 0 {
+		t.Errorf("Expected the square to start at 30/0, got %v", start)
+	}
+
+	for _, o := range []OrderingI{NewGreedy(), NewTwoOpt()} {
+		ordered := o.Order(gcodes)
+		if dist := gcode.TotalDistanceInBetween(ordered); dist > 11 {
+			t.Errorf("%T: expected a travel distance of at most 11, got %.2f", o, dist)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package ordering
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+const (
+	// Number of nearest ends that are considered for improving moves
+	spatialNeighbors = 10
+	// Maximum number of segments that Or-opt moves at once
+	spatialMaxChain = 3
+	// Maximum number of start vertices per closed segment that are indexed
+	// for the nearest neighbor construction
+	spatialMaxVertices = 16
+	// Maximum number of segments that are reversed at once (or 10% of the
+	// segments, if more). Limits the time spent on huge input.
+	spatialMaxReverse = 50000
+	// Number of rounds of local search and choosing closed segments' vertices
+	spatialRounds = 3
+)
+
+// An orderer for large input. It builds a tour via nearest neighbors, found
+// via a grid index, and improves it with 2-opt and Or-opt moves between
+// nearby segments. Open segments may be drawn backwards, closed segments may
+// start at any vertex. Not optimal, but close.
+type Spatial struct {
+}
+
+func NewSpatial() *Spatial {
+	return new(Spatial)
+}
+
+func (sp *Spatial) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	return sp.OrderContext(context.Background(), gcodes)
+}
+
+// Time: O(n*log(n)) in practice, Space: O(n)
+func (sp *Spatial) OrderContext(ctx context.Context, gcodes []*gcode.Gcode) []*gcode.Gcode {
+	if len(gcodes) < 2 {
+		return Orient(gcodes)
+	}
+	segments := newSegments(gcodes)
+	ordered := nearestNeighbors(segments)
+	t := newSpatialTour(ordered)
+	t.maxReverse = max(spatialMaxReverse, len(ordered)/10)
+	initial := t.length()
+	p := newProgress()
+	for round := 1; round <= spatialRounds; round++ {
+		complete := t.improve(ctx, t.neighbors(), func() {
+			reportDistance(OrderingAlgSpatial, t.length(), initial, fmt.Sprintf("round %d", round))
+		}, p)
+		if !complete || !t.relax(ordered) {
+			break
+		}
+	}
+
+	nodes, flipped := t.nodes()
+	result := make([]*gcode.Gcode, len(nodes))
+	for i, v := range nodes {
+		s := ordered[v]
+		if flipped[i] {
+			s.reverse()
+		}
+		result[i] = s.gcode()
+	}
+	return result
+}
+
+// XY position of an entry or exit
+func xy(p math64.VectorF3) math64.VectorF2 {
+	return math64.VectorF2{X: p.X, Y: p.Y}
+}
+
+// Order the segments greedily, always continuing with the segment whose
+// entry is nearest to the current exit. Chooses the segments' orientations.
+func nearestNeighbors(segments []*segment) []*segment {
+	var points []math64.VectorF2
+	var pointSegment, pointOrientation []int
+	for i, s := range segments {
+		step := max(len(s.orientations)/spatialMaxVertices, 1)
+		for o := 0; o < len(s.orientations); o += step {
+			points = append(points, xy(s.orientations[o].entry))
+			pointSegment = append(pointSegment, i)
+			pointOrientation = append(pointOrientation, o)
+		}
+	}
+	index := newGrid(points, 2)
+	// The points of each segment are consecutive
+	firstPoint := make([]int, len(segments)+1)
+	for i := len(points) - 1; i >= 0; i-- {
+		firstPoint[pointSegment[i]] = i
+	}
+	firstPoint[len(segments)] = len(points)
+	use := func(i int) {
+		for p := firstPoint[i]; p < firstPoint[i+1]; p++ {
+			index.remove(p)
+		}
+	}
+
+	ordered := make([]*segment, 0, len(segments))
+	current := segments[0]
+	use(0)
+	ordered = append(ordered, current)
+	for len(ordered) < len(segments) {
+		p := index.nearest(xy(current.exit()), 1, nil)[0]
+		current = segments[pointSegment[p]]
+		current.o = pointOrientation[p]
+		use(pointSegment[p])
+		ordered = append(ordered, current)
+	}
+	return ordered
+}
+
+// A tour over the segments (in their current orientations), in the given
+// order
+func newSpatialTour(segments []*segment) *tour {
+	entries := make([]math64.VectorF2, len(segments))
+	exits := make([]math64.VectorF2, len(segments))
+	fixed := make([]bool, len(segments))
+	for i, s := range segments {
+		entries[i] = xy(s.entry())
+		exits[i] = xy(s.exit())
+		fixed[i] = len(s.orientations) == 1
+	}
+	return newTour(entries, exits, fixed)
+}
+
+// The nearest ends of other nodes, for each end
+func (t *tour) neighbors() [][]int {
+	points := t.ends[:2*t.dummy]
+	index := newGrid(points, 2)
+	neighbors := make([][]int, len(t.ends))
+	for e := range points {
+		neighbors[e] = index.nearest(points[e], spatialNeighbors, func(e2 int) bool {
+			return e2/2 == e/2
+		})
+	}
+	return neighbors
+}
+
+// Number of nodes that are processed between checks for whether the ordering
+// has to stop (and progress has to be reported)
+const spatialCheckInterval = 1024
+
+// Apply improving 2-opt and Or-opt moves until there are none. Returns false,
+// if stopped early (via ctx). report is called when progress is due.
+func (t *tour) improve(ctx context.Context, neighbors [][]int, report func(), p *progress) bool {
+	queue := make([]int, 0, t.dummy)
+	queued := make([]bool, len(t.order))
+	push := func(nodes ...int) {
+		for _, v := range nodes {
+			if v != t.dummy && !queued[v] {
+				queued[v] = true
+				queue = append(queue, v)
+			}
+		}
+	}
+	for v := range t.dummy {
+		push(v)
+	}
+	for i := 0; len(queue) > 0; i++ {
+		if i%spatialCheckInterval == 0 {
+			if stopped(ctx, OrderingAlgSpatial) {
+				return false
+			}
+			if p.due() {
+				report()
+			}
+		}
+		v := queue[0]
+		queue = queue[1:]
+		queued[v] = false
+		if changed := t.twoOpt(v, neighbors); changed != nil {
+			push(changed...)
+		} else if changed := t.orOpt(v, neighbors); changed != nil {
+			push(changed...)
+		}
+	}
+	return true
+}
+
+// Minimum gain of a move
+const spatialEpsilon = 1e-9
+
+// Apply the first improving 2-opt move that replaces one of the edges of v
+// with an edge to a nearby node. Returns the nodes whose edges changed.
+func (t *tour) twoOpt(v int, neighbors [][]int) []int {
+	// Connect the tail of v to the tail of c, reversing next(v)..c
+	vNext := t.next(v)
+	g1 := t.edge(v)
+	for _, e := range neighbors[t.tail(v)] {
+		d1 := t.dist(t.tail(v), e)
+		if d1 >= g1 {
+			break
+		}
+		c := e / 2
+		if e != t.tail(c) {
+			continue
+		}
+		cNext := t.next(c)
+		delta := d1 + t.dist(t.head(vNext), t.head(cNext)) - g1 - t.edge(c)
+		if delta < -spatialEpsilon && t.canReverse(vNext, c) {
+			t.reverse(vNext, c)
+			return []int{v, vNext, c, cNext}
+		}
+	}
+	// Connect the head of v to the head of c, reversing v..prev(c)
+	vPrev := t.prev(v)
+	g1 = t.edge(vPrev)
+	for _, e := range neighbors[t.head(v)] {
+		d1 := t.dist(t.head(v), e)
+		if d1 >= g1 {
+			break
+		}
+		c := e / 2
+		if e != t.head(c) {
+			continue
+		}
+		cPrev := t.prev(c)
+		delta := d1 + t.dist(t.tail(vPrev), t.tail(cPrev)) - g1 - t.edge(cPrev)
+		if delta < -spatialEpsilon && t.canReverse(v, cPrev) {
+			t.reverse(v, cPrev)
+			return []int{v, vPrev, c, cPrev}
+		}
+	}
+	return nil
+}
+
+// Apply the first improving Or-opt move that moves a chain of nodes, starting
+// with v, between two nodes near the chain's ends. Returns the nodes whose
+// edges changed.
+func (t *tour) orOpt(v int, neighbors [][]int) []int {
+	if len(t.order) < spatialMaxChain+3 {
+		return nil
+	}
+	chain := []int{v}
+	for len(chain) <= spatialMaxChain {
+		first, last := chain[0], chain[len(chain)-1]
+		if last == t.dummy {
+			return nil
+		}
+		prev, next := t.prev(first), t.next(last)
+		removed := t.edge(prev) + t.edge(last) - t.dist(t.tail(prev), t.head(next))
+		// Insert the chain between u and next(u), drawing it backwards, if
+		// reversed
+		try := func(u int, reversed bool) []int {
+			if u == prev || containsNode(chain, u) || containsNode(chain, t.next(u)) {
+				return nil
+			}
+			uNext := t.next(u)
+			added := t.dist(t.tail(u), t.head(first)) + t.dist(t.tail(last), t.head(uNext))
+			if reversed {
+				added = t.dist(t.tail(u), t.tail(last)) + t.dist(t.head(first), t.head(uNext))
+			}
+			if added-t.edge(u)-removed >= -spatialEpsilon || !t.isShort(first, u) || (reversed && !t.canFlip(chain)) {
+				return nil
+			}
+			t.reverse(first, u)
+			t.reverse(u, next)
+			if !reversed {
+				t.reverse(last, first)
+			}
+			return []int{prev, next, first, last, u, uNext}
+		}
+		for _, e := range neighbors[t.head(first)] {
+			if t.dist(t.head(first), e) >= removed {
+				break
+			}
+			c := e / 2
+			if e == t.tail(c) {
+				// tail(c) -> head(first)
+				if changed := try(c, false); changed != nil {
+					return changed
+				}
+			} else if changed := try(t.prev(c), true); changed != nil {
+				// head(first) -> head(c)
+				return changed
+			}
+		}
+		for _, e := range neighbors[t.tail(last)] {
+			if t.dist(t.tail(last), e) >= removed {
+				break
+			}
+			c := e / 2
+			if e == t.head(c) {
+				// tail(last) -> head(c)
+				if changed := try(t.prev(c), false); changed != nil {
+					return changed
+				}
+			} else if changed := try(c, true); changed != nil {
+				// tail(c) -> tail(last)
+				return changed
+			}
+		}
+		chain = append(chain, next)
+	}
+	return nil
+}
+
+func containsNode(nodes []int, v int) bool {
+	for _, v2 := range nodes {
+		if v == v2 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true, if the given nodes can be flipped without flipping only a
+// part of the fixed nodes
+func (t *tour) canFlip(nodes []int) bool {
+	numFixed := 0
+	for _, v := range nodes {
+		if t.fixed[v] {
+			numFixed++
+		}
+	}
+	return numFixed == 0 || numFixed == t.numFixed
+}
+
+// Choose the best vertex of closed segments and the best direction of open
+// ones, given their neighbors in the tour. Returns true, if a vertex changed.
+func (t *tour) relax(segments []*segment) bool {
+	changed := false
+	for v, s := range segments {
+		prev, next := t.prev(v), t.next(v)
+		cost := func(head, tail int) math64.Float {
+			return t.dist(t.tail(prev), head) + t.dist(tail, t.head(next))
+		}
+		if !s.g.IsClosed() {
+			if !t.fixed[v] && cost(t.tail(v), t.head(v)) < cost(t.head(v), t.tail(v))-spatialEpsilon {
+				t.reverse(v, v)
+			}
+			continue
+		}
+		best, bestCost := s.o, cost(t.head(v), t.tail(v))
+		for i, o := range s.orientations {
+			t.ends[2*v], t.ends[2*v+1] = xy(o.entry), xy(o.exit)
+			if c := cost(2*v, 2*v+1); c < bestCost-spatialEpsilon {
+				best, bestCost = i, c
+			}
+		}
+		changed = changed || best != s.o
+		s.o = best
+		t.ends[2*v], t.ends[2*v+1] = xy(s.entry()), xy(s.exit())
+	}
+	return changed
+}
@@ -0,0 +1,88 @@
+package ordering
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Random lines and squares. Every tenth segment cannot be reversed.
+func randomGcodes(n int) []*gcode.Gcode {
+	r := rand.New(rand.NewSource(1))
+	ins := gcode.NewIns(conf.NewRuntimeConfig(conf.PlotterConfigLongerLK5ProDefault(), math64.UnitMM, math64.UnitMM))
+	var gcodes []*gcode.Gcode
+	for i := range n {
+		p := math64.VectorF2{X: math64.Float(r.Float64() * 1000), Y: math64.Float(r.Float64() * 1000)}
+		var g *gcode.Gcode
+		if i%3 == 0 {
+			g = polylineGcode(ins, p, p.Add(math64.VectorF2{X: 5, Y: 0}), p.Add(math64.VectorF2{X: 5, Y: 5}),
+				p.Add(math64.VectorF2{X: 0, Y: 5}), p)
+		} else {
+			g = polylineGcode(ins, p, p.Add(math64.VectorF2{X: math64.Float(r.Float64() * 20), Y: math64.Float(r.Float64() * 20)}))
+		}
+		if i%10 == 0 {
+			g.Code.AppendLines("G4 P1")
+		}
+		gcodes = append(gcodes, g)
+	}
+	return gcodes
+}
+
+func TestGridNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := make([]math64.VectorF2, 500)
+	for i := range points {
+		points[i] = math64.VectorF2{X: math64.Float(r.NormFloat64() * 100), Y: math64.Float(r.Float64() * 10)}
+	}
+	index := newGrid(points, 2)
+	for i := 0; i < len(points); i += 2 {
+		index.remove(i)
+	}
+	for range 100 {
+		p := math64.VectorF2{X: math64.Float(r.Float64()*400 - 200), Y: math64.Float(r.Float64()*40 - 20)}
+		nearest := index.nearest(p, 5, nil)
+		// Brute force
+		var expected []int
+		for i := 1; i < len(points); i += 2 {
+			expected = append(expected, i)
+		}
+		slices.SortFunc(expected, func(a, b int) int {
+			return cmp.Compare(p.DistEuclid(points[a]), p.DistEuclid(points[b]))
+		})
+		for k := range nearest {
+			if p.DistEuclid(points[nearest[k]]) != p.DistEuclid(points[expected[k]]) {
+				t.Fatalf("Nearest points of %v: expected %v, got %v", p, expected[:5], nearest)
+			}
+		}
+	}
+}
+
+func TestSpatial(t *testing.T) {
+	gcodes := randomGcodes(2000)
+	ordered := NewSpatial().Order(gcodes)
+	if len(ordered) != len(gcodes) {
+		t.Fatalf("Expected %d segments, got %d", len(gcodes), len(ordered))
+	}
+	// Every segment is drawn once, possibly reversed or rotated
+	for i, g := range ordered {
+		if g.IsReversible() {
+			continue
+		}
+		if !slices.Contains(gcodes, g) {
+			t.Errorf("Segment %d cannot be reversed, but was redrawn", i)
+		}
+	}
+	if numInstructions(ordered) != numInstructions(gcodes) {
+		t.Errorf("Expected %d instructions, got %d", numInstructions(gcodes), numInstructions(ordered))
+	}
+	spatial := gcode.TotalDistanceInBetween(ordered)
+	greedy := gcode.TotalDistanceInBetween(NewGreedy().Order(gcodes))
+	if spatial > greedy {
+		t.Errorf("Expected a shorter travel distance than greedy ordering (%.0f), got %.0f", greedy, spatial)
+	}
+}
@@ -0,0 +1,129 @@
+package ordering
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Minimum number of segments per tile. Tiling less segments does not pay off.
+const tiledMinSegments = 1000
+
+// An orderer that partitions the segments into spatial tiles, orders the
+// tiles concurrently with the given orderer, and stitches them together.
+// Only suitable for orderers that minimize travel distance (cf.
+// OrderingAlg.MinimizesTravel).
+type Tiled struct {
+	order OrderingI
+	tiles int
+}
+
+func NewTiled(order OrderingI, tiles int) *Tiled {
+	ti := new(Tiled)
+	ti.order = order
+	ti.tiles = tiles
+	return ti
+}
+
+func (ti *Tiled) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	return ti.OrderContext(context.Background(), gcodes)
+}
+
+func (ti *Tiled) OrderContext(ctx context.Context, gcodes []*gcode.Gcode) []*gcode.Gcode {
+	numTiles := min(ti.tiles, len(gcodes)/tiledMinSegments)
+	if numTiles < 2 {
+		return orderContext(ctx, ti.order, gcodes)
+	}
+	tiles := partition(gcodes, numTiles)
+	var wg sync.WaitGroup
+	for i, tile := range tiles {
+		wg.Go(func() {
+			tiles[i] = orderContext(ctx, ti.order, tile)
+		})
+	}
+	wg.Wait()
+	return stitch(tiles)
+}
+
+// Order with ctx, if the orderer supports it (cf. OrderingContextI)
+func orderContext(ctx context.Context, order OrderingI, gcodes []*gcode.Gcode) []*gcode.Gcode {
+	if o, ok := order.(OrderingContextI); ok {
+		return o.OrderContext(ctx, gcodes)
+	}
+	return order.Order(gcodes)
+}
+
+// Split the segments into n tiles of similar size, recursively splitting them
+// at the median of their longer extent
+func partition(gcodes []*gcode.Gcode, n int) [][]*gcode.Gcode {
+	if n < 2 {
+		return [][]*gcode.Gcode{gcodes}
+	}
+	center := func(g *gcode.Gcode) math64.VectorF2 {
+		return math64.VectorF2{X: (g.StartCoord.X + g.EndCoord.X) / 2, Y: (g.StartCoord.Y + g.EndCoord.Y) / 2}
+	}
+	boundsMin, boundsMax := center(gcodes[0]), center(gcodes[0])
+	for _, g := range gcodes {
+		boundsMin = boundsMin.Min(center(g))
+		boundsMax = boundsMax.Max(center(g))
+	}
+	size := boundsMax.Sub(boundsMin)
+	sorted := slices.Clone(gcodes)
+	slices.SortStableFunc(sorted, func(a, b *gcode.Gcode) int {
+		if size.X >= size.Y {
+			return cmp.Compare(center(a).X, center(b).X)
+		}
+		return cmp.Compare(center(a).Y, center(b).Y)
+	})
+	nLeft := n / 2
+	split := len(sorted) * nLeft / n
+	return append(partition(sorted[:split], nLeft), partition(sorted[split:], n-nLeft)...)
+}
+
+// Join ordered tiles, always continuing with the tile whose start (or end,
+// if the tile can be drawn backwards) is nearest to the current position
+func stitch(tiles [][]*gcode.Gcode) []*gcode.Gcode {
+	reversible := make([]bool, len(tiles))
+	for i, tile := range tiles {
+		reversible[i] = !slices.ContainsFunc(tile, func(g *gcode.Gcode) bool { return !g.IsReversible() })
+	}
+	used := make([]bool, len(tiles))
+	var stitched []*gcode.Gcode
+	for range tiles {
+		best, bestReversed := -1, false
+		var bestDist math64.Float = math64.Float(math.Inf(1))
+		for i, tile := range tiles {
+			if used[i] || len(tile) == 0 {
+				continue
+			}
+			if len(stitched) == 0 {
+				best = i
+				break
+			}
+			pos := stitched[len(stitched)-1].EndCoord
+			if dist := pos.DistEuclid(tile[0].StartCoord); dist < bestDist {
+				best, bestReversed, bestDist = i, false, dist
+			}
+			if dist := pos.DistEuclid(tile[len(tile)-1].EndCoord); reversible[i] && dist < bestDist {
+				best, bestReversed, bestDist = i, true, dist
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		if !bestReversed {
+			stitched = append(stitched, tiles[best]...)
+			continue
+		}
+		for i := len(tiles[best]) - 1; i >= 0; i-- {
+			stitched = append(stitched, tiles[best][i].Reversed())
+		}
+	}
+	return stitched
+}
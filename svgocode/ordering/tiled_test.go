@@ -0,0 +1,44 @@
+package ordering
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/gcode"
+)
+
+func TestTiled(t *testing.T) {
+	gcodes := randomGcodes(8000)
+	tiles := partition(gcodes, 5)
+	if len(tiles) != 5 {
+		t.Fatalf("Expected 5 tiles, got %d", len(tiles))
+	}
+	for i, tile := range tiles {
+		if len(tile) < len(gcodes)/5-1 || len(tile) > len(gcodes)/5+1 {
+			t.Errorf("Tile %d: expected about %d segments, got %d", i, len(gcodes)/5, len(tile))
+		}
+	}
+
+	ordered := NewTiled(NewSpatial(), 4).Order(gcodes)
+	if len(ordered) != len(gcodes) {
+		t.Fatalf("Expected %d segments, got %d", len(gcodes), len(ordered))
+	}
+	for i, g := range ordered {
+		if !g.IsReversible() && !slices.Contains(gcodes, g) {
+			t.Errorf("Segment %d cannot be reversed, but was redrawn", i)
+		}
+	}
+	if numInstructions(ordered) != numInstructions(gcodes) {
+		t.Errorf("Expected %d instructions, got %d", numInstructions(gcodes), numInstructions(ordered))
+	}
+	// Concurrent ordering is deterministic
+	if again := NewTiled(NewSpatial(), 4).Order(gcodes); gcode.TotalDistanceInBetween(again) != gcode.TotalDistanceInBetween(ordered) {
+		t.Errorf("Expected the same order on every run")
+	}
+	tiled := gcode.TotalDistanceInBetween(ordered)
+	// Stitching costs little
+	untiled := gcode.TotalDistanceInBetween(NewSpatial().Order(gcodes))
+	if tiled > 1.1*untiled {
+		t.Errorf("Expected a travel distance close to untiled ordering (%.0f), got %.0f", untiled, tiled)
+	}
+}
@@ -0,0 +1,198 @@
+package ordering
+
+import "github.com/abzicht/svgocode/svgocode/math64"
+
+// A cyclic tour over segments (nodes), stored as array. A dummy node that is
+// zero distance away from all nodes marks where the tour starts and ends.
+// Every node has two ends (entry and exit, unless the node is flipped).
+// Reversing a part of the tour flips its nodes. The shorter part of the
+// cycle is reversed physically, so the tour may be stored backwards (cf.
+// reversed).
+type tour struct {
+	order    []int             // Node at each position
+	pos      []int             // Position of each node
+	flip     []bool            // Physically flipped nodes
+	reversed bool              // The tour is stored backwards
+	ends     []math64.VectorF2 // Entry (2*node) and exit (2*node+1) of each node
+	fixed    []bool            // Nodes that must not be flipped
+	numFixed int
+	dummy    int
+	// Maximum number of nodes that are reversed at once (cf. canReverse)
+	maxReverse int
+}
+
+// Create a tour from the nodes' entries and exits, visiting them in order.
+// The dummy node is the last node.
+func newTour(entries, exits []math64.VectorF2, fixed []bool) *tour {
+	n := len(entries) + 1
+	t := &tour{
+		order:      make([]int, n),
+		pos:        make([]int, n),
+		flip:       make([]bool, n),
+		ends:       make([]math64.VectorF2, 2*n),
+		fixed:      make([]bool, n),
+		dummy:      n - 1,
+		maxReverse: n,
+	}
+	for v := range n {
+		t.order[v] = v
+		t.pos[v] = v
+	}
+	for v := range entries {
+		t.ends[2*v] = entries[v]
+		t.ends[2*v+1] = exits[v]
+		t.fixed[v] = fixed[v]
+		if fixed[v] {
+			t.numFixed++
+		}
+	}
+	return t
+}
+
+func (t *tour) next(v int) int {
+	n := len(t.order)
+	if t.reversed {
+		return t.order[(t.pos[v]-1+n)%n]
+	}
+	return t.order[(t.pos[v]+1)%n]
+}
+
+func (t *tour) prev(v int) int {
+	n := len(t.order)
+	if t.reversed {
+		return t.order[(t.pos[v]+1)%n]
+	}
+	return t.order[(t.pos[v]-1+n)%n]
+}
+
+// Returns true, if the node is drawn from its exit to its entry
+func (t *tour) flipped(v int) bool {
+	return t.flip[v] != t.reversed
+}
+
+// The end where the node is entered
+func (t *tour) head(v int) int {
+	if t.flipped(v) {
+		return 2*v + 1
+	}
+	return 2 * v
+}
+
+// The end where the node is left
+func (t *tour) tail(v int) int {
+	return t.head(v) ^ 1
+}
+
+// Distance between two ends
+func (t *tour) dist(e1, e2 int) math64.Float {
+	if e1/2 == t.dummy || e2/2 == t.dummy {
+		return 0
+	}
+	return t.ends[e1].DistEuclid(t.ends[e2])
+}
+
+// Distance between the node and its successor
+func (t *tour) edge(v int) math64.Float {
+	return t.dist(t.tail(v), t.head(t.next(v)))
+}
+
+// Total distance between the nodes
+func (t *tour) length() math64.Float {
+	var length math64.Float = 0
+	for _, v := range t.order {
+		length += t.edge(v)
+	}
+	return length
+}
+
+// The physical positions (first and last) of the shorter part of the cycle
+// that has to be reversed for reversing the path from..to. Returns true, if
+// that part is the complement of from..to.
+func (t *tour) span(from, to int) (i, j, length int, complement bool) {
+	if t.reversed {
+		from, to = to, from
+	}
+	n := len(t.order)
+	i, j = t.pos[from], t.pos[to]
+	length = (j-i+n)%n + 1
+	if 2*length > n {
+		return (j + 1) % n, (i - 1 + n) % n, n - length, true
+	}
+	return i, j, length, false
+}
+
+// Returns true, if the path from..to is short enough to be reversed (cf.
+// maxReverse)
+func (t *tour) isShort(from, to int) bool {
+	_, _, length, _ := t.span(from, to)
+	return length <= t.maxReverse
+}
+
+// Returns true, if reversing the path from..to is short enough and does not
+// flip fixed nodes, i.e., if the fixed nodes all stay on one side
+func (t *tour) canReverse(from, to int) bool {
+	i, _, length, _ := t.span(from, to)
+	if length > t.maxReverse {
+		return false
+	}
+	if t.numFixed == 0 {
+		return true
+	}
+	numFixed := 0
+	for k := range length {
+		if t.fixed[t.order[(i+k)%len(t.order)]] {
+			numFixed++
+		}
+	}
+	return numFixed == 0 || numFixed == t.numFixed
+}
+
+// Reverse the path from..to (following next), flipping its nodes.
+// Time: O(n) in the worst case, proportional to the shorter side otherwise
+func (t *tour) reverse(from, to int) {
+	n := len(t.order)
+	i, j, length, complement := t.span(from, to)
+	if complement {
+		t.reversed = !t.reversed
+	}
+	for k := range length / 2 {
+		a, b := i+k, j-k
+		if a >= n {
+			a -= n
+		}
+		if b < 0 {
+			b += n
+		}
+		va, vb := t.order[a], t.order[b]
+		t.order[a], t.order[b] = vb, va
+		t.pos[va], t.pos[vb] = b, a
+		t.flip[va], t.flip[vb] = !t.flip[va], !t.flip[vb]
+	}
+	if length%2 == 1 {
+		v := t.order[(i+length/2)%n]
+		t.flip[v] = !t.flip[v]
+	}
+}
+
+// The nodes in the order of the tour, starting after the dummy, and whether
+// they are flipped. The tour is read such that fixed nodes are not flipped.
+func (t *tour) nodes() (nodes []int, flipped []bool) {
+	backwards := false
+	for v := range t.dummy {
+		if t.fixed[v] {
+			backwards = t.flipped(v)
+			break
+		}
+	}
+	v := t.dummy
+	for range t.dummy {
+		if backwards {
+			v = t.prev(v)
+		} else {
+			v = t.next(v)
+		}
+		nodes = append(nodes, v)
+		flipped = append(flipped, t.flipped(v) != backwards)
+	}
+	return nodes, flipped
+}
@@ -1,7 +1,10 @@
 package ordering
 
 import (
-	"github.com/abzicht/svgocode/llog"
+	"context"
+	"fmt"
+	"slices"
+
 	"github.com/abzicht/svgocode/svgocode/gcode"
 )
 
@@ -15,37 +18,53 @@ func NewTwoOpt() *TwoOpt {
 }
 
 func (tO *TwoOpt) Order(gcodes []*gcode.Gcode) []*gcode.Gcode {
+	return tO.OrderContext(context.Background(), gcodes)
+}
+
+func (tO *TwoOpt) OrderContext(ctx context.Context, gcodes []*gcode.Gcode) []*gcode.Gcode {
 	if len(gcodes) == 0 {
 		return gcodes
 	}
-	ordered := make([]*gcode.Gcode, len(gcodes))
-	if n := copy(ordered, gcodes); n < len(gcodes) {
-		llog.Panicf("Failed to copy gcode list for ordering (only copied %d).", n)
-	}
+	ordered := newSegments(gcodes)
+	orient(ordered)
 
+	initialDistance := distanceInBetween(ordered)
+	p := newProgress()
 	improved := true
-	for improved {
+	for pass := 1; improved; pass++ {
 		improved = false
-		bestDistance := gcode.TotalDistanceInBetween(ordered)
+		bestDistance := distanceInBetween(ordered)
 		for i := 1; i < len(gcodes)-2; i++ {
+			if p.due() {
+				reportDistance(OrderingAlgTwoOpt, bestDistance, initialDistance, fmt.Sprintf("pass %d", pass))
+			}
 			for j := i + 1; j < len(gcodes)-1; j++ {
-				tmpOrdered := make([]*gcode.Gcode, len(ordered))
-				if n := copy(tmpOrdered, ordered); n < len(gcodes) {
-					llog.Panicf("Failed to copy gcode list for ordering (only copied %d).", n)
+				if stopped(ctx, OrderingAlgTwoOpt) {
+					return segmentsToGcode(ordered)
 				}
-
-				// reverse the section between i and j
-				for k := 0; k <= (j-i)/2; k++ {
-					tmpOrdered[i+k], tmpOrdered[j-k] = tmpOrdered[j-k], tmpOrdered[i+k]
-				}
-				currentDistance := gcode.TotalDistanceInBetween(tmpOrdered)
+				// reverse the section between i and j, drawing its open
+				// segments backwards
+				reverseSection(ordered, i, j)
+				currentDistance := distanceInBetween(ordered)
 				if currentDistance < bestDistance {
 					bestDistance = currentDistance
-					ordered = tmpOrdered
 					improved = true
+				} else {
+					reverseSection(ordered, i, j)
 				}
 			}
 		}
+		if improved {
+			// Reversing sections leaves closed segments' vertices as they were
+			orient(ordered)
+		}
+	}
+	return segmentsToGcode(ordered)
+}
+
+func reverseSection(segments []*segment, i, j int) {
+	slices.Reverse(segments[i : j+1])
+	for _, s := range segments[i : j+1] {
+		s.reverse()
 	}
-	return ordered
 }
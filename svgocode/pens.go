@@ -0,0 +1,105 @@
+package svgocode
+
+import (
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+// Assigns SVG elements to the configured pens, based on their color
+type penMatcher struct {
+	colors  [][]svg.Color // Colors per pen
+	match   conf.PenMatch
+	unknown map[string]bool // Colors that have been warned about
+}
+
+func newPenMatcher(runtConf *conf.RuntimeConfig) *penMatcher {
+	p := new(penMatcher)
+	p.match = runtConf.Plotter.PenMatch
+	switch p.match {
+	case conf.PenMatch(""):
+		p.match = conf.PenMatchNearest
+	case conf.PenMatchExact, conf.PenMatchNearest:
+	default:
+		llog.Panicf("Unknown pen match: '%s'. Must be 'exact' or 'nearest'", p.match)
+	}
+	p.unknown = make(map[string]bool)
+	for _, pen := range runtConf.Plotter.Pens {
+		var colors []svg.Color
+		for _, value := range pen.Colors {
+			c, err := svg.ParseColor(value)
+			if err != nil {
+				llog.Panicf("Invalid color of pen '%s': %s", pen.Name, err.Error())
+			}
+			colors = append(colors, c)
+		}
+		p.colors = append(p.colors, colors)
+	}
+	return p
+}
+
+// Number of pens (at least one)
+func (p *penMatcher) Len() int {
+	return max(1, len(p.colors))
+}
+
+// The color that an element is drawn in: its stroke or, if it has no stroke,
+// its fill
+func elementColor(style svg.Style) string {
+	if stroke := style.Get("stroke"); stroke != "none" {
+		return stroke
+	}
+	if fill := style.Get("fill"); fill != "none" {
+		return fill
+	}
+	// Initial value of fill
+	return "black"
+}
+
+// Index of the pen that draws the element with the given id and computed
+// style. Elements whose color cannot be matched are drawn with the first pen.
+func (p *penMatcher) Pen(id svg.SvgId, style svg.Style) int {
+	if len(p.colors) < 2 {
+		return 0
+	}
+	value := elementColor(style)
+	c, err := svg.ParseColor(value)
+	if err != nil {
+		p.warn(value, "Element '%s': %s. Drawing it with the first pen.\n", id, err.Error())
+		return 0
+	}
+	best, bestDist := 0, -1
+	for i, colors := range p.colors {
+		for _, c2 := range colors {
+			dist := colorDist(c, c2)
+			if dist == 0 {
+				return i
+			}
+			if bestDist < 0 || dist < bestDist {
+				best, bestDist = i, dist
+			}
+		}
+	}
+	if p.match == conf.PenMatchExact {
+		p.warn(value, "No pen for color '%s'. Drawing it with the first pen.\n", value)
+		return 0
+	}
+	return best
+}
+
+// Warn once per color
+func (p *penMatcher) warn(color string, format string, args ...any) {
+	if p.unknown[color] {
+		return
+	}
+	p.unknown[color] = true
+	llog.Warnf(format, args...)
+}
+
+// Squared euclidean distance in RGB space
+func colorDist(c, c2 svg.Color) int {
+	dr := int(c.R) - int(c2.R)
+	dg := int(c.G) - int(c2.G)
+	db := int(c.B) - int(c2.B)
+	return dr*dr + dg*dg + db*db
+}
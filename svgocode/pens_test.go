@@ -0,0 +1,54 @@
+package svgocode
+
+import (
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+func TestPenMatcher(t *testing.T) {
+	plotter := conf.PlotterConfigLongerLK5ProDefault()
+	plotter.Pens = []conf.PenConfig{
+		{Name: "black", Colors: []string{"black"}},
+		{Name: "red", Colors: []string{"#f00", "maroon"}},
+		{Name: "blue", Colors: []string{"blue"}},
+	}
+	runtConf := conf.NewRuntimeConfig(plotter, math64.UnitMM, math64.UnitMM)
+	style := func(stroke, fill string) svg.Style {
+		s := svg.Style{}
+		if len(stroke) > 0 {
+			s["stroke"] = stroke
+		}
+		if len(fill) > 0 {
+			s["fill"] = fill
+		}
+		return s
+	}
+	expected := []struct {
+		style   svg.Style
+		nearest int
+		exact   int
+	}{
+		{style("#ff0000", ""), 1, 1},
+		{style("#800000", "blue"), 1, 1},
+		{style("#e01010", ""), 1, 0},
+		{style("none", "#0000f0"), 2, 0},
+		{style("", ""), 0, 0},
+		{style("url(#gradient)", ""), 0, 0},
+	}
+	for _, match := range []conf.PenMatch{conf.PenMatchNearest, conf.PenMatchExact} {
+		plotter.PenMatch = match
+		pens := newPenMatcher(runtConf)
+		for _, e := range expected {
+			want := e.nearest
+			if match == conf.PenMatchExact {
+				want = e.exact
+			}
+			if got := pens.Pen("", e.style); got != want {
+				t.Errorf("Pen match '%s' for stroke '%s' and fill '%s': expected pen %d, got %d", match, e.style.Get("stroke"), e.style.Get("fill"), want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package svgocode
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/conv"
+	"github.com/abzicht/svgocode/svgocode/gcode"
+	"github.com/abzicht/svgocode/svgocode/ordering"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+// Decides whether the shape at the end of an element path (from root to
+// shape) is converted
+type ElementFilter func(path []svg.SVGElement) bool
+
+// Combine filters: shapes must pass all of them
+func AllFilters(filters ...ElementFilter) ElementFilter {
+	return func(path []svg.SVGElement) bool {
+		for _, filter := range filters {
+			if filter != nil && !filter(path) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Only convert shapes that are part of the Inkscape layer with the given name
+// (label or id)
+func LayerFilter(name string) ElementFilter {
+	return func(path []svg.SVGElement) bool {
+		return slices.ContainsFunc(svg.LayersForPath(path), func(layer *svg.Grouping) bool {
+			return layer.LayerName() == name
+		})
+	}
+}
+
+type SplitMode string
+
+const (
+	SplitModeLayer = SplitMode("layer") // One part per (top-level) Inkscape layer
+	SplitModeColor = SplitMode("color") // One part per pen (if configured) or stroke color
+)
+
+// Name of shapes that are not part of any layer
+const splitNameNoLayer = "nolayer"
+
+// GCODE for a subset of the SVG's shapes
+type SplitPart struct {
+	Name  string
+	Gcode *gcode.Gcode
+}
+
+// Determines the part that a shape belongs to
+type splitter struct {
+	mode    SplitMode
+	pens    *penMatcher
+	cascade *svg.Cascade
+	conf    *conf.PlotterConfig
+}
+
+func newSplitter(s *svg.SVG, plotterConf *conf.PlotterConfig, mode SplitMode) *splitter {
+	switch mode {
+	case SplitModeLayer, SplitModeColor:
+	default:
+		llog.Panicf("Unknown split mode: '%s'. Must be 'layer' or 'color'", mode)
+	}
+	sp := new(splitter)
+	sp.mode = mode
+	sp.conf = plotterConf
+	sp.cascade = svg.NewCascade(s)
+	sp.pens = newPenMatcher(conf.NewRuntimeConfig(plotterConf, plotterConf.UnitLength, s.Unit()))
+	return sp
+}
+
+func (sp *splitter) name(path []svg.SVGElement) string {
+	if sp.mode == SplitModeLayer {
+		layers := svg.LayersForPath(path)
+		if len(layers) == 0 {
+			return splitNameNoLayer
+		}
+		return layers[0].LayerName()
+	}
+	style := sp.cascade.ComputedStyle(path)
+	if len(sp.conf.Pens) > 1 {
+		return sp.conf.Pens[sp.pens.Pen(path[len(path)-1].ID(), style)].Name
+	}
+	value := elementColor(style)
+	if c, err := svg.ParseColor(value); err == nil {
+		return c.Hex()[1:]
+	}
+	return value
+}
+
+// Convert the SVG to one GCODE per part (layer or color), in the order in
+// which shapes are traversed. Only shapes that pass the filter (if not nil) are considered.
+// The SVG is converted once and its segments are distributed among the parts.
+// Each part has its own prefix, suffix, and summary.
+func Svg2GcodeSplit(s *svg.SVG, plotterConf *conf.PlotterConfig, converter conv.ConverterI, order ordering.OrderingI, mode SplitMode, filter ElementFilter) []SplitPart {
+	sp := newSplitter(s, plotterConf, mode)
+	runtConf := newRuntimeConfig(s, plotterConf)
+	var names []string
+	segments := make(map[string][]segment)
+	for _, seg := range convertSegments(s, runtConf, converter, filter) {
+		name := sp.name(seg.path)
+		if _, ok := segments[name]; !ok {
+			names = append(names, name)
+		}
+		segments[name] = append(segments[name], seg)
+	}
+	var parts []SplitPart
+	for _, name := range names {
+		llog.Infof("Ordering part '%s'\n", name)
+		parts = append(parts, SplitPart{Name: name, Gcode: assembleGcode(segments[name], runtConf, order)})
+	}
+	return parts
+}
+
+var fileNameUnsafe *regexp.Regexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Make a part's name usable in file names
+func SafeFileName(name string) string {
+	name = fileNameUnsafe.ReplaceAllString(name, "_")
+	if len(name) == 0 {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,54 @@
+package svgocode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/conf"
+	"github.com/abzicht/svgocode/svgocode/conv"
+	"github.com/abzicht/svgocode/svgocode/ordering"
+	"github.com/abzicht/svgocode/svgocode/svg"
+)
+
+const splitTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="100mm" height="100mm" viewBox="0 0 100 100">
+  <g inkscape:groupmode="layer" inkscape:label="Outline">
+    <line x1="10" y1="10" x2="90" y2="10" stroke="black"/>
+    <g inkscape:groupmode="layer" inkscape:label="Details">
+      <line x1="10" y1="20" x2="90" y2="20" stroke="#f00"/>
+    </g>
+  </g>
+  <g inkscape:groupmode="layer" id="layer2">
+    <line x1="10" y1="30" x2="90" y2="30" stroke="red"/>
+  </g>
+  <line x1="10" y1="40" x2="90" y2="40" stroke="black"/>
+  <line x1="10" y1="50" x2="90" y2="50" stroke="red" display="none"/>
+</svg>`
+
+func TestSvg2GcodeSplit(t *testing.T) {
+	var s svg.SVG
+	if err := svg.NewDecoder(strings.NewReader(splitTestSvg)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		mode      SplitMode
+		filter    ElementFilter
+		numShapes map[string]int
+	}{
+		{SplitModeLayer, nil, map[string]int{"Outline": 2, "layer2": 1, "nolayer": 1}},
+		{SplitModeColor, nil, map[string]int{"000000": 2, "ff0000": 2}},
+		{SplitModeColor, LayerFilter("Details"), map[string]int{"ff0000": 1}},
+	}
+	for _, e := range expected {
+		parts := Svg2GcodeSplit(&s, conf.PlotterConfigLongerLK5ProDefault(), conv.NewDirect(), ordering.NewNone(), e.mode, e.filter)
+		if len(parts) != len(e.numShapes) {
+			t.Errorf("Split by %s: expected %d parts, got %d", e.mode, len(e.numShapes), len(parts))
+		}
+		for _, part := range parts {
+			// Each line is drawn with exactly one drawing move
+			numShapes := strings.Count(part.Gcode.String(), "; Drawing\n")
+			if numShapes != e.numShapes[part.Name] {
+				t.Errorf("Split by %s: expected %d shapes in part '%s', got %d", e.mode, e.numShapes[part.Name], part.Name, numShapes)
+			}
+		}
+	}
+}
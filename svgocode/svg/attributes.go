@@ -3,8 +3,8 @@ package svg
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
-	"github.com/abzicht/svgocode/llog"
 	"github.com/abzicht/svgocode/svgocode/math64"
 	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
 )
@@ -19,12 +19,105 @@ type SVGCoreAttributes struct {
 	Id    SvgId  `xml:"id,attr"`
 	Class string `xml:"class,attr"`
 	Style string `xml:"style,attr"`
+	SVGPresentationAttributes
+	SVGConditionalAttributes
 }
 
 func (s SVGCoreAttributes) ID() SvgId {
 	return s.Id
 }
 
+func (s SVGCoreAttributes) Attributes() SVGCoreAttributes {
+	return s
+}
+
+// Return the value of a presentation property, as declared by the element
+// itself. Declarations in the style attribute take precedence over
+// presentation attributes. Returns an empty string, if it is not declared.
+// Style sheets and inheritance are not considered (cf. Cascade).
+func (s SVGCoreAttributes) Property(name string) string {
+	if value, ok := ParseStyle(s.Style)[name]; ok {
+		return value
+	}
+	return s.SVGPresentationAttributes.Attribute(name)
+}
+
+// Parse an inline style declaration list ("fill: red; stroke: none") into a
+// map of property names and values.
+func ParseStyle(style string) map[string]string {
+	declarations := make(map[string]string)
+	for _, declaration := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		declarations[strings.TrimSpace(name)] = value
+	}
+	return declarations
+}
+
+// Presentation attributes, i.e., style properties that can also be set as XML
+// attributes.
+type SVGPresentationAttributes struct {
+	Fill          string `xml:"fill,attr"`
+	FillRule      string `xml:"fill-rule,attr"`
+	FillOpacity   string `xml:"fill-opacity,attr"`
+	Stroke        string `xml:"stroke,attr"`
+	StrokeWidth   string `xml:"stroke-width,attr"`
+	StrokeOpacity string `xml:"stroke-opacity,attr"`
+	Display       string `xml:"display,attr"`
+	Visibility    string `xml:"visibility,attr"`
+	Opacity       string `xml:"opacity,attr"`
+	ClipPath      string `xml:"clip-path,attr"`
+	ClipRule      string `xml:"clip-rule,attr"`
+	Mask          string `xml:"mask,attr"`
+	Overflow      string `xml:"overflow,attr"`
+	FontFamily    string `xml:"font-family,attr"`
+	FontSize      string `xml:"font-size,attr"`
+	TextAnchor    string `xml:"text-anchor,attr"`
+}
+
+// Return the value of the presentation attribute with the given name, or an
+// empty string.
+func (s SVGPresentationAttributes) Attribute(name string) string {
+	switch name {
+	case "fill":
+		return s.Fill
+	case "fill-rule":
+		return s.FillRule
+	case "fill-opacity":
+		return s.FillOpacity
+	case "stroke":
+		return s.Stroke
+	case "stroke-width":
+		return s.StrokeWidth
+	case "stroke-opacity":
+		return s.StrokeOpacity
+	case "display":
+		return s.Display
+	case "visibility":
+		return s.Visibility
+	case "opacity":
+		return s.Opacity
+	case "clip-path":
+		return s.ClipPath
+	case "clip-rule":
+		return s.ClipRule
+	case "mask":
+		return s.Mask
+	case "overflow":
+		return s.Overflow
+	case "font-family":
+		return s.FontFamily
+	case "font-size":
+		return s.FontSize
+	case "text-anchor":
+		return s.TextAnchor
+	}
+	return ""
+}
+
 type SVGLinkAttributes struct {
 	Href  string `xml:"href,attr"`
 	XHref string `xml:"xlink:href,attr"`
@@ -39,12 +132,14 @@ func (s SVGLinkAttributes) GetHref() string {
 }
 
 type SVGPresentationLocation struct {
-	X math64.Float `xml:"x,attr"`
-	Y math64.Float `xml:"y,attr"`
+	X Length `xml:"x,attr"`
+	Y Length `xml:"y,attr"`
 }
 
 type SVGPresentation interface {
 	Transform() svgtransform.TransformChain
+	TransformAround(origin math64.VectorF2) svgtransform.TransformChain
+	TransformReference() (origin, box string)
 	AppendTransform(function string, inFront bool)
 }
 
@@ -54,13 +149,29 @@ type SVGPresentationTransform struct {
 	TransformBox    string `xml:"transform-box,attr"`
 }
 
+// The parsed transform attribute. transform-origin is not considered, i.e.,
+// the transformation takes place around the user space's origin (cf.
+// TransformAround and TransformChainForPath).
 func (spt *SVGPresentationTransform) Transform() svgtransform.TransformChain {
-	if len(spt.TransformBox) != 0 || len(spt.TransformOrigin) != 0 {
-		llog.Panic("Transformation based on transform-origin / transform-box is not (yet) implemented. Try to make the SVG compatible, e.g., by ungrouping elements")
-	}
 	return svgtransform.ParseTransform(spt.TransformStr)
 }
 
+// The parsed transform attribute, taking place around the given origin, i.e.,
+// wrapped in a translation to origin and back.
+func (spt *SVGPresentationTransform) TransformAround(origin math64.VectorF2) svgtransform.TransformChain {
+	chain := spt.Transform()
+	if len(chain) == 0 || origin.Equal(math64.VectorF2{X: 0, Y: 0}) {
+		return chain
+	}
+	chain = append(svgtransform.TransformChain{svgtransform.NewTranslate(origin)}, chain...)
+	return append(chain, svgtransform.NewTranslate(math64.VectorF2{X: -origin.X, Y: -origin.Y}))
+}
+
+// The declared transform-origin and transform-box attributes
+func (spt *SVGPresentationTransform) TransformReference() (origin, box string) {
+	return spt.TransformOrigin, spt.TransformBox
+}
+
 var transformMatch *regexp.Regexp = regexp.MustCompile(`(?i)\b(matrix|translate|scale|rotate|skew|skewX|skewY)\s*\(`)
 
 func (spt *SVGPresentationTransform) AppendTransform(function string, inFront bool) {
@@ -0,0 +1,147 @@
+package svg
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Clipping and masking, cf. https://www.w3.org/TR/css-masking-1/
+
+type ClipPath struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGElements
+	ClipPathUnits string `xml:"clipPathUnits,attr"`
+}
+
+func (c *ClipPath) Clone() *ClipPath {
+	c2 := new(ClipPath)
+	c2.SVGCoreAttributes = c.SVGCoreAttributes
+	c2.SVGPresentationTransform = c.SVGPresentationTransform
+	c2.SVGElements = *c.SVGElements.Clone()
+	c2.ClipPathUnits = c.ClipPathUnits
+	return c2
+}
+
+func (c *ClipPath) CloneSVGElement() SVGElement {
+	return c.Clone()
+}
+
+// Masks are treated as clip paths: the shapes of their content form the clip
+// region, regardless of their luminance.
+type Mask struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGElements
+	MaskContentUnits string `xml:"maskContentUnits,attr"`
+}
+
+func (m *Mask) Clone() *Mask {
+	m2 := new(Mask)
+	m2.SVGCoreAttributes = m.SVGCoreAttributes
+	m2.SVGPresentationTransform = m.SVGPresentationTransform
+	m2.SVGElements = *m.SVGElements.Clone()
+	m2.MaskContentUnits = m.MaskContentUnits
+	return m2
+}
+
+func (m *Mask) CloneSVGElement() SVGElement {
+	return m.Clone()
+}
+
+// A clip path or mask that applies to an element
+type ClipRef struct {
+	// The *ClipPath or *Mask
+	Element SVGElement
+	// Transformations of the user space of the referencing element, i.e.,
+	// the coordinate system of the clip path's content
+	TransformChain svgtransform.TransformChain
+	// Length context of the referencing element
+	Lengths LengthContext
+}
+
+var urlRefMatch *regexp.Regexp = regexp.MustCompile(`^\s*url\(\s*['"]?#([^'")]+)['"]?\s*\)\s*$`)
+
+// Parse a reference of the form 'url(#id)'. Returns false for 'none' and
+// unsupported values.
+func ParseURLRef(value string) (SvgId, bool) {
+	m := urlRefMatch.FindStringSubmatch(value)
+	if m == nil {
+		return "", false
+	}
+	return SvgId(m[1]), true
+}
+
+// The clip region of a nested svg element's viewport, unless its overflow is
+// visible. parentPath leads to the svg element's parent.
+func (c *Cascade) viewportClip(s *SVG, parentPath []SVGElement) (ClipRef, bool) {
+	// Nested viewports do not show overflow by default, cf.
+	// https://www.w3.org/TR/SVG2/render.html#OverflowAndClipProperties
+	switch overflow, _ := c.declared(s, "overflow"); overflow {
+	case "visible", "auto":
+		return ClipRef{}, false
+	}
+	pos, size := s.Viewport(LengthContextForPath(parentPath))
+	if size.X <= 0 || size.Y <= 0 {
+		return ClipRef{}, false
+	}
+	viewport := new(ClipPath)
+	viewport.Id = s.Id
+	viewport.Rects = []*Rect{{X: UserLength(pos.X), Y: UserLength(pos.Y), Width: UserLength(size.X), Height: UserLength(size.Y)}}
+	origin := c.TransformOrigin(slices.Concat(parentPath, []SVGElement{s}))
+	chain := append(c.TransformChainForPath(parentPath), s.SVGPresentationTransform.TransformAround(origin)...)
+	return ClipRef{Element: viewport, TransformChain: chain, Lengths: LengthContextForPath(parentPath)}, true
+}
+
+// The clip paths and masks that apply to the last element of the given path,
+// i.e., those referenced by the element itself or by one of its ancestors, and
+// the viewports of nested svg elements. The element is only visible inside of
+// all of their regions.
+func (c *Cascade) ClipsForPath(path []SVGElement, sMap SvgIdMap) []ClipRef {
+	var clips []ClipRef
+	for i, element := range path {
+		if s, ok := element.(*SVG); ok && !s.IsRoot() {
+			if clip, ok := c.viewportClip(s, path[:i]); ok {
+				clips = append(clips, clip)
+			}
+		}
+		for _, property := range []string{"clip-path", "mask"} {
+			value, ok := c.declared(element, property)
+			if !ok || value == "none" {
+				continue
+			}
+			id, ok := ParseURLRef(value)
+			if !ok {
+				llog.Warnf("Element '%s': unsupported %s '%s'. Ignoring it.\n", element.ID(), property, value)
+				continue
+			}
+			ref, ok := sMap[id]
+			if !ok {
+				llog.Warnf("Element '%s': %s '%s' not found. Ignoring it.\n", element.ID(), property, id)
+				continue
+			}
+			switch ref := ref.(type) {
+			case *ClipPath:
+				if ref.ClipPathUnits == "objectBoundingBox" {
+					llog.Warnf("Clip path '%s': clipPathUnits 'objectBoundingBox' is not supported. Ignoring it.\n", id)
+					continue
+				}
+			case *Mask:
+				if ref.MaskContentUnits == "objectBoundingBox" {
+					llog.Warnf("Mask '%s': maskContentUnits 'objectBoundingBox' is not supported. Ignoring it.\n", id)
+					continue
+				}
+			default:
+				llog.Warnf("Element '%s': %s references '%s', which is no %s. Ignoring it.\n", element.ID(), property, id, property)
+				continue
+			}
+			clips = append(clips, ClipRef{Element: ref, TransformChain: c.TransformChainForPath(path[:i+1]), Lengths: LengthContextForPath(path[:i+1])})
+		}
+	}
+	return clips
+}
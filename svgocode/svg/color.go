@@ -0,0 +1,98 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// An sRGB color, cf. https://www.w3.org/TR/css-color-3/
+type Color struct {
+	R, G, B uint8
+}
+
+// Basic color keywords (CSS Color Module Level 3, 4.1) and a few common
+// extended ones
+var namedColors = map[string]Color{
+	"black":   {0, 0, 0},
+	"silver":  {192, 192, 192},
+	"gray":    {128, 128, 128},
+	"grey":    {128, 128, 128},
+	"white":   {255, 255, 255},
+	"maroon":  {128, 0, 0},
+	"red":     {255, 0, 0},
+	"purple":  {128, 0, 128},
+	"fuchsia": {255, 0, 255},
+	"magenta": {255, 0, 255},
+	"green":   {0, 128, 0},
+	"lime":    {0, 255, 0},
+	"olive":   {128, 128, 0},
+	"yellow":  {255, 255, 0},
+	"navy":    {0, 0, 128},
+	"blue":    {0, 0, 255},
+	"teal":    {0, 128, 128},
+	"aqua":    {0, 255, 255},
+	"cyan":    {0, 255, 255},
+	"orange":  {255, 165, 0},
+	"brown":   {165, 42, 42},
+	"pink":    {255, 192, 203},
+	"gold":    {255, 215, 0},
+	"violet":  {238, 130, 238},
+	"indigo":  {75, 0, 130},
+}
+
+// Parse a color value: '#rgb', '#rrggbb', 'rgb(r, g, b)' (numbers or
+// percentages), or a color keyword.
+func ParseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return Color{}, errors.New(fmt.Sprintf("invalid color '%s'", s))
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return Color{}, errors.New(fmt.Sprintf("invalid color '%s'", s))
+		}
+		return Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+	}
+	if args, ok := strings.CutPrefix(s, "rgb("); ok && strings.HasSuffix(args, ")") {
+		parts := strings.Split(strings.TrimSuffix(args, ")"), ",")
+		if len(parts) != 3 {
+			return Color{}, errors.New(fmt.Sprintf("invalid color '%s'", s))
+		}
+		var channels [3]uint8
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			p, percent := strings.CutSuffix(part, "%")
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return Color{}, errors.New(fmt.Sprintf("invalid color '%s'", s))
+			}
+			if percent {
+				v = v / 100 * 255
+			}
+			channels[i] = uint8(min(max(v+0.5, 0), 255))
+		}
+		return Color{R: channels[0], G: channels[1], B: channels[2]}, nil
+	}
+	return Color{}, errors.New(fmt.Sprintf("unsupported color '%s'", s))
+}
+
+// Hexadecimal notation, e.g., '#ff0000'
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Relative luminance between 0 (black) and 1 (white), ignoring gamma
+func (c Color) Luminance() math64.Float {
+	return (0.2126*math64.Float(c.R) + 0.7152*math64.Float(c.G) + 0.0722*math64.Float(c.B)) / 255
+}
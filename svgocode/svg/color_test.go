@@ -0,0 +1,30 @@
+package svg
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	colors := map[string]string{
+		"#F00":               "#ff0000",
+		"#12ab9F":            "#12ab9f",
+		"Red":                "#ff0000",
+		"rgb(0, 128, 255)":   "#0080ff",
+		"rgb(100%, 50%, 0%)": "#ff8000",
+		" navy ":             "#000080",
+		"rgb(300, -10, 0.4)": "#ff0000",
+	}
+	for s, hex := range colors {
+		c, err := ParseColor(s)
+		if err != nil {
+			t.Errorf("Failed to parse color '%s': %s", s, err.Error())
+			continue
+		}
+		if c.Hex() != hex {
+			t.Errorf("Color '%s': expected %s, got %s", s, hex, c.Hex())
+		}
+	}
+	for _, s := range []string{"#12", "rgb(1,2)", "currentColor", "#ggg"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("Expected an error for color '%s'", s)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package svg
+
+import (
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Raster images, cf. https://www.w3.org/TR/SVG2/embedded.html#ImageElement.
+// The image data itself is referenced via href (data URI or file).
+type Image struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGLinkAttributes
+	X      Length `xml:"x,attr"`
+	Y      Length `xml:"y,attr"`
+	Width  Length `xml:"width,attr"`  // 0 (or 'auto'): derived from the image
+	Height Length `xml:"height,attr"` // 0 (or 'auto'): derived from the image
+	// Raw preserveAspectRatio value, cf. ParsePreserveAspectRatio
+	PreserveAspectRatioStr string `xml:"preserveAspectRatio,attr"`
+}
+
+func (i *Image) Clone() *Image {
+	i2 := new(Image)
+	i2.SVGCoreAttributes = i.SVGCoreAttributes
+	i2.SVGPresentationTransform = i.SVGPresentationTransform
+	i2.SVGLinkAttributes = i.SVGLinkAttributes
+	i2.X = i.X
+	i2.Y = i.Y
+	i2.Width = i.Width
+	i2.Height = i.Height
+	i2.PreserveAspectRatioStr = i.PreserveAspectRatioStr
+	return i2
+}
+
+func (i *Image) CloneSVGElement() SVGElement {
+	return i.Clone()
+}
+
+func (i *Image) Children() []SVGElement {
+	return []SVGElement{}
+}
+
+// Position and size of the image's viewport, in user units. Lengths must be
+// resolved. A missing width or height is derived from the image's aspect
+// ratio, given its size in pixels.
+func (i *Image) Viewport(imageSize math64.VectorF2) (pos, size math64.VectorF2) {
+	pos = math64.VectorF2{X: i.X.Value, Y: i.Y.Value}
+	size = math64.VectorF2{X: i.Width.Value, Y: i.Height.Value}
+	switch {
+	case size.X > 0 && size.Y > 0:
+	case size.X > 0:
+		size.Y = size.X * imageSize.Y / imageSize.X
+	case size.Y > 0:
+		size.X = size.Y * imageSize.X / imageSize.Y
+	default:
+		size = imageSize
+	}
+	return pos, size
+}
+
+// Place an image of the given size (in pixels) in the image element's
+// viewport, as per preserveAspectRatio. Returns the transformation from pixel
+// coordinates to user space and the part of the image that is visible in the
+// viewport (in pixel coordinates). Lengths must be resolved.
+func (i *Image) Placement(imageSize math64.VectorF2) (chain svgtransform.TransformChain, visibleMin, visibleMax math64.VectorF2) {
+	pos, size := i.Viewport(imageSize)
+	vb := ViewBox{Size: imageSize}
+	chain = vb.Transform(ParsePreserveAspectRatio(i.PreserveAspectRatioStr), pos, size)
+	// The placement only translates and scales
+	tMat := chain.ToMatrix()
+	origin := tMat.ApplyP(math64.VectorF2{X: 0, Y: 0})
+	scale := tMat.ApplyP(math64.VectorF2{X: 1, Y: 1}).Sub(origin)
+	toPixels := func(p math64.VectorF2) math64.VectorF2 {
+		return math64.VectorF2{X: (p.X - origin.X) / scale.X, Y: (p.Y - origin.Y) / scale.Y}
+	}
+	visibleMin = toPixels(pos).Max(math64.VectorF2{X: 0, Y: 0})
+	visibleMax = toPixels(pos.Add(size)).Min(imageSize)
+	return chain, visibleMin, visibleMax
+}
@@ -0,0 +1,209 @@
+package svg
+
+import (
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// A length attribute value such as "10", "10mm", "2em", or "50%", cf.
+// https://www.w3.org/TR/SVG2/types.html#InterfaceSVGLength. Use Resolve to
+// obtain its value in user units.
+type Length struct {
+	Value math64.Float
+	Unit  math64.UnitLength
+}
+
+// Create a length in user units
+func UserLength(value math64.Float) Length {
+	return Length{Value: value, Unit: math64.UnitNone}
+}
+
+func (l *Length) UnmarshalXMLAttr(attr xml.Attr) error {
+	if strings.TrimSpace(attr.Value) == "auto" {
+		// Left to the element to interpret, e.g., Image
+		*l = Length{}
+		return nil
+	}
+	value, unit, err := math64.ParseLength(attr.Value)
+	if err != nil {
+		return err
+	}
+	l.Value = value
+	l.Unit = unit
+	return nil
+}
+
+func (l Length) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: l.String()}, nil
+}
+
+func (l Length) String() string {
+	return strconv.FormatFloat(float64(l.Value), 'g', -1, 64) + string(l.Unit)
+}
+
+// A list of lengths, separated by white space and/or commas
+type LengthList []Length
+
+func (l *LengthList) UnmarshalXMLAttr(attr xml.Attr) error {
+	*l = nil
+	for _, field := range strings.Fields(strings.ReplaceAll(attr.Value, ",", " ")) {
+		value, unit, err := math64.ParseLength(field)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, Length{Value: value, Unit: unit})
+	}
+	return nil
+}
+
+func (l LengthList) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	values := make([]string, len(l))
+	for i, length := range l {
+		values[i] = length.String()
+	}
+	return xml.Attr{Name: name, Value: strings.Join(values, " ")}, nil
+}
+
+// A copy of the list with all lengths expressed in user units
+func (l LengthList) resolve(ctx LengthContext, axis LengthAxis) LengthList {
+	resolved := make(LengthList, len(l))
+	for i, length := range l {
+		resolved[i] = UserLength(length.Resolve(ctx, axis))
+	}
+	return resolved
+}
+
+// The dimension that a length refers to. Only relevant for percentages.
+type LengthAxis int
+
+const (
+	AxisX     LengthAxis = iota // Relative to the viewport's width
+	AxisY                       // Relative to the viewport's height
+	AxisOther                   // Relative to the viewport's normalized diagonal
+)
+
+// Information needed to express lengths in user units.
+type LengthContext struct {
+	Unit     math64.UnitLength // The unit of the user coordinate system
+	Viewport math64.VectorF2   // Size of the nearest viewport, in user units
+	FontSize math64.Float      // The font size, in user units
+	DPI      math64.Float      // Dots per inch for converting pixels
+}
+
+// Default font size (CSS 'medium')
+const defaultFontSizePX = math64.Float(16)
+
+func NewLengthContext(unit math64.UnitLength, viewport math64.VectorF2, dpi math64.Float) LengthContext {
+	return LengthContext{
+		Unit:     unit,
+		Viewport: viewport,
+		FontSize: math64.LengthConvertDPI(defaultFontSizePX, math64.UnitPX, unit, dpi),
+		DPI:      dpi,
+	}
+}
+
+// Express the length in user units.
+func (l Length) Resolve(ctx LengthContext, axis LengthAxis) math64.Float {
+	switch l.Unit {
+	case math64.UnitNone:
+		return l.Value
+	case math64.UnitEM:
+		return l.Value * ctx.FontSize
+	case math64.UnitPercent:
+		var ref math64.Float
+		switch axis {
+		case AxisX:
+			ref = ctx.Viewport.X
+		case AxisY:
+			ref = ctx.Viewport.Y
+		default:
+			ref = math64.Float(math.Sqrt(float64(ctx.Viewport.X*ctx.Viewport.X+ctx.Viewport.Y*ctx.Viewport.Y) / 2))
+		}
+		return l.Value / 100 * ref
+	}
+	if !l.Unit.IsAbsolute() {
+		llog.Panicf("Cannot resolve length '%s': unsupported unit", l.String())
+	}
+	return math64.LengthConvertDPI(l.Value, l.Unit, ctx.Unit, ctx.DPI)
+}
+
+// Determine the length context of the last element of the given path: its
+// user unit is determined by the outermost svg element (cf. SVG.userUnit),
+// its viewport is established by the innermost svg element.
+func LengthContextForPath(path []SVGElement) LengthContext {
+	unit := math64.UnitPX
+	dpi := math64.DefaultDPI
+	var viewport math64.VectorF2
+	found := false
+	for _, element := range path {
+		s, ok := element.(*SVG)
+		if !ok {
+			continue
+		}
+		if !found {
+			unit, dpi = s.userUnit(), s.dpi()
+			found = true
+		}
+		viewport = s.ViewportSize(unit, viewport)
+	}
+	return NewLengthContext(unit, viewport, dpi)
+}
+
+type lengthResolver interface {
+	resolveLengths(ctx LengthContext)
+}
+
+// Return a copy of the element with all its length attributes expressed in
+// user units, i.e., with unit UnitNone. Children are not resolved.
+func ResolveLengths(s SVGElement, ctx LengthContext) SVGElement {
+	_, ok := s.(lengthResolver)
+	if !ok {
+		return s
+	}
+	s2 := s.CloneSVGElement()
+	s2.SetRoot(s.Root())
+	s2.(lengthResolver).resolveLengths(ctx)
+	return s2
+}
+
+func resolveAll(ctx LengthContext, axis LengthAxis, lengths ...*Length) {
+	for _, l := range lengths {
+		*l = UserLength(l.Resolve(ctx, axis))
+	}
+}
+
+func (u *Use) resolveLengths(ctx LengthContext) {
+	resolveAll(ctx, AxisX, &u.X, &u.Width)
+	resolveAll(ctx, AxisY, &u.Y, &u.Height)
+}
+
+func (l *Line) resolveLengths(ctx LengthContext) {
+	resolveAll(ctx, AxisX, &l.X1, &l.X2)
+	resolveAll(ctx, AxisY, &l.Y1, &l.Y2)
+}
+
+func (r *Rect) resolveLengths(ctx LengthContext) {
+	resolveAll(ctx, AxisX, &r.X, &r.Width, &r.RX)
+	resolveAll(ctx, AxisY, &r.Y, &r.Height, &r.RY)
+}
+
+func (i *Image) resolveLengths(ctx LengthContext) {
+	resolveAll(ctx, AxisX, &i.X, &i.Width)
+	resolveAll(ctx, AxisY, &i.Y, &i.Height)
+}
+
+func (c *Circle) resolveLengths(ctx LengthContext) {
+	resolveAll(ctx, AxisX, &c.CX)
+	resolveAll(ctx, AxisY, &c.CY)
+	resolveAll(ctx, AxisOther, &c.R)
+}
+
+func (e *Ellipse) resolveLengths(ctx LengthContext) {
+	resolveAll(ctx, AxisX, &e.CX, &e.RX)
+	resolveAll(ctx, AxisY, &e.CY, &e.RY)
+}
@@ -0,0 +1,127 @@
+package svg
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// SVGElements keeps its children in one list per tag, which loses their
+// document order. Containers whose children are rendered therefore remember
+// the document order while decoding (cf. decodeInOrder), such that Children
+// returns them in the order in which they are painted.
+
+// Replays buffered tokens
+type tokenReplay struct {
+	tokens []xml.Token
+}
+
+func (r *tokenReplay) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	t := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return t, nil
+}
+
+// Decode the element that starts with start into v, which must not implement
+// xml.Unmarshaler itself. Returns the tag names of the element's children, in
+// document order.
+func decodeInOrder(d *xml.Decoder, start xml.StartElement, v any) ([]string, error) {
+	tokens := []xml.Token{start.Copy()}
+	var order []string
+	for depth := 1; depth > 0; {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				order = append(order, t.Name.Local)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		tokens = append(tokens, xml.CopyToken(t))
+	}
+	if err := xml.NewTokenDecoder(&tokenReplay{tokens: tokens}).Decode(v); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Sort the children (as listed per tag) into document order, if it is known
+func (svgElem *SVGElements) inDocumentOrder(children []SVGElement) []SVGElement {
+	if len(svgElem.order) == 0 {
+		return children
+	}
+	byTag := make(map[string][]SVGElement)
+	for _, child := range children {
+		tag := TagName(child)
+		byTag[tag] = append(byTag[tag], child)
+	}
+	ordered := make([]SVGElement, 0, len(children))
+	for _, tag := range svgElem.order {
+		if len(byTag[tag]) == 0 {
+			// Not decoded, e.g., foreignObject
+			continue
+		}
+		ordered = append(ordered, byTag[tag][0])
+		byTag[tag] = byTag[tag][1:]
+	}
+	if len(ordered) != len(children) {
+		// Children have been added after decoding
+		return children
+	}
+	return ordered
+}
+
+func (s *SVG) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainSVG SVG // Without UnmarshalXML
+	var plain plainSVG
+	order, err := decodeInOrder(d, start, &plain)
+	if err != nil {
+		return err
+	}
+	*s = SVG(plain)
+	s.order = order
+	return nil
+}
+
+func (g *Grouping) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainGrouping Grouping // Without UnmarshalXML
+	var plain plainGrouping
+	order, err := decodeInOrder(d, start, &plain)
+	if err != nil {
+		return err
+	}
+	*g = Grouping(plain)
+	g.order = order
+	return nil
+}
+
+func (a *ALink) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainALink ALink // Without UnmarshalXML
+	var plain plainALink
+	order, err := decodeInOrder(d, start, &plain)
+	if err != nil {
+		return err
+	}
+	*a = ALink(plain)
+	a.order = order
+	return nil
+}
+
+func (s *Symbol) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainSymbol Symbol // Without UnmarshalXML
+	var plain plainSymbol
+	order, err := decodeInOrder(d, start, &plain)
+	if err != nil {
+		return err
+	}
+	*s = Symbol(plain)
+	s.order = order
+	return nil
+}
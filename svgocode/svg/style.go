@@ -0,0 +1,368 @@
+package svg
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// A <style> element
+type StyleSheet struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+func (s *StyleSheet) Clone() *StyleSheet {
+	s2 := new(StyleSheet)
+	*s2 = *s
+	return s2
+}
+
+// Computed values of presentation properties, cf.
+// https://www.w3.org/TR/SVG2/styling.html
+type Style map[string]string
+
+// Properties that are resolved, with their initial values
+var styleInitial = map[string]string{
+	"fill":           "black",
+	"fill-rule":      "nonzero",
+	"fill-opacity":   "1",
+	"stroke":         "none",
+	"stroke-width":   "1",
+	"stroke-opacity": "1",
+	"display":        "inline",
+	"visibility":     "visible",
+	"opacity":        "1",
+	"clip-rule":      "nonzero",
+	"font-family":    "",
+	"font-size":      "medium",
+	"text-anchor":    "start",
+}
+
+// Properties that are inherited from the parent element by default
+var styleInherited = []string{"fill", "fill-rule", "fill-opacity", "stroke", "stroke-width", "stroke-opacity", "visibility", "clip-rule", "font-family", "font-size", "text-anchor"}
+
+// Value of the given property
+func (s Style) Get(name string) string {
+	if value, ok := s[name]; ok {
+		return value
+	}
+	return styleInitial[name]
+}
+
+// Numeric value of the given property (e.g., 'opacity'). Percentages are
+// converted to fractions.
+func (s Style) Number(name string) math64.Float {
+	value := s.Get(name)
+	factor := 1.0
+	if v, ok := strings.CutSuffix(value, "%"); ok {
+		value = v
+		factor = 0.01
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		f, _ = strconv.ParseFloat(styleInitial[name], 64)
+		factor = 1
+	}
+	return math64.Float(f * factor)
+}
+
+// Font sizes of the absolute-size keywords, in px
+var fontSizeKeywords map[string]math64.Float = map[string]math64.Float{
+	"xx-small": 9, "x-small": 10, "small": 13, "medium": 16, "large": 18, "x-large": 24, "xx-large": 32, "xxx-large": 48,
+}
+
+// Express a font size that is given as keyword or relative to the parent's
+// font size (em, %, larger, smaller) as absolute length. parent must be an
+// absolute length (or empty, for 'medium').
+func absoluteFontSize(value, parent string) string {
+	if px, ok := fontSizeKeywords[value]; ok {
+		return strconv.FormatFloat(float64(px), 'g', -1, 64) + string(math64.UnitPX)
+	}
+	pValue, pUnit, err := math64.ParseLength(parent)
+	if err != nil {
+		pValue, pUnit = fontSizeKeywords["medium"], math64.UnitPX
+	}
+	var factor math64.Float
+	switch value {
+	case "larger":
+		factor = 1.2
+	case "smaller":
+		factor = 1 / 1.2
+	default:
+		v, unit, err := math64.ParseLength(value)
+		if err != nil {
+			llog.Warnf("Ignoring malformed font-size '%s'\n", value)
+			return parent
+		}
+		switch unit {
+		case math64.UnitEM:
+			factor = v
+		case math64.UnitPercent:
+			factor = v / 100
+		default:
+			return value
+		}
+	}
+	return strconv.FormatFloat(float64(pValue*factor), 'g', -1, 64) + string(pUnit)
+}
+
+// Returns true, if the element is not rendered at all, i.e., if it or one of
+// its ancestors is 'display: none'
+func (s Style) IsDisplayed() bool {
+	return s.Get("display") != "none"
+}
+
+// Returns true, if the element is rendered visibly
+func (s Style) IsVisible() bool {
+	switch s.Get("visibility") {
+	case "hidden", "collapse":
+		return false
+	}
+	return s.IsDisplayed()
+}
+
+// Returns true, if the element's fill is painted
+func (s Style) HasFill() bool {
+	switch s.Get("fill") {
+	case "none", "transparent":
+		return false
+	}
+	return s.Number("fill-opacity") > 0
+}
+
+// Returns true, if the element's stroke is painted
+func (s Style) HasStroke() bool {
+	switch s.Get("stroke") {
+	case "none", "transparent":
+		return false
+	}
+	return s.Number("stroke-opacity") > 0 && s.Number("stroke-width") > 0
+}
+
+// A simple selector: type, classes, and id (e.g., 'path.outline#a'). Empty
+// fields match any element.
+type cssSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+func (sel cssSelector) specificity() int {
+	specificity := len(sel.classes) * 100
+	if len(sel.id) > 0 {
+		specificity += 10000
+	}
+	if len(sel.tag) > 0 {
+		specificity += 1
+	}
+	return specificity
+}
+
+func (sel cssSelector) matches(element SVGElement) bool {
+	attrs := element.Attributes()
+	if len(sel.tag) > 0 && sel.tag != TagName(element) {
+		return false
+	}
+	if len(sel.id) > 0 && sel.id != string(attrs.Id) {
+		return false
+	}
+	classes := strings.Fields(attrs.Class)
+	for _, class := range sel.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+type cssRule struct {
+	selector     cssSelector
+	declarations map[string]string
+	order        int // Position in the style sheets
+}
+
+var cssComment *regexp.Regexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+var cssSelectorMatch *regexp.Regexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[.#][a-zA-Z0-9_-]+)*)$`)
+var cssSelectorPart *regexp.Regexp = regexp.MustCompile(`[.#][a-zA-Z0-9_-]+`)
+
+// Parse a simple selector. Returns false for unsupported selectors (e.g.,
+// combinators, attribute selectors, pseudo-classes).
+func parseCSSSelector(s string) (cssSelector, bool) {
+	m := cssSelectorMatch.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return cssSelector{}, false
+	}
+	sel := cssSelector{tag: m[1]}
+	if sel.tag == "*" {
+		sel.tag = ""
+	}
+	for _, part := range cssSelectorPart.FindAllString(m[2], -1) {
+		if part[0] == '#' {
+			sel.id = part[1:]
+		} else {
+			sel.classes = append(sel.classes, part[1:])
+		}
+	}
+	return sel, true
+}
+
+// Parse the rules of a style sheet. Unsupported selectors and at-rules are
+// skipped.
+func parseCSS(css string, order int) []cssRule {
+	var rules []cssRule
+	css = cssComment.ReplaceAllString(css, "")
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(css[open:], '}')
+		if end < 0 {
+			break
+		}
+		prelude := strings.TrimSpace(css[:open])
+		body := css[open+1 : open+end]
+		css = css[open+end+1:]
+		if strings.HasPrefix(prelude, "@") {
+			llog.Debugf("Skipping CSS at-rule '%s'\n", prelude)
+			continue
+		}
+		declarations := ParseStyle(body)
+		for _, s := range strings.Split(prelude, ",") {
+			sel, ok := parseCSSSelector(s)
+			if !ok {
+				llog.Debugf("Skipping unsupported CSS selector '%s'\n", strings.TrimSpace(s))
+				continue
+			}
+			rules = append(rules, cssRule{selector: sel, declarations: declarations, order: order})
+			order++
+		}
+	}
+	return rules
+}
+
+// Resolves the computed style of elements from inline styles, style sheets
+// (<style> elements), presentation attributes, and inheritance.
+type Cascade struct {
+	rules []cssRule
+}
+
+// Create a cascade for all style sheets that are contained in root
+func NewCascade(root SVGElement) *Cascade {
+	c := new(Cascade)
+	for element := range Seq(root) {
+		e, ok := element.(interface{ StyleSheets() []*StyleSheet })
+		if !ok {
+			continue
+		}
+		for _, sheet := range e.StyleSheets() {
+			if len(sheet.Type) > 0 && sheet.Type != "text/css" {
+				continue
+			}
+			c.rules = append(c.rules, parseCSS(sheet.Text, len(c.rules))...)
+		}
+	}
+	// Rules with higher specificity (or, if equal, later rules) win
+	slices.SortStableFunc(c.rules, func(a, b cssRule) int {
+		if a.selector.specificity() != b.selector.specificity() {
+			return a.selector.specificity() - b.selector.specificity()
+		}
+		return a.order - b.order
+	})
+	return c
+}
+
+// Declared value of a property for an element (without inheritance)
+func (c *Cascade) declared(element SVGElement, name string) (string, bool) {
+	attrs := element.Attributes()
+	if value, ok := ParseStyle(attrs.Style)[name]; ok {
+		return value, true
+	}
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if value, ok := c.rules[i].declarations[name]; ok && c.rules[i].selector.matches(element) {
+			return value, true
+		}
+	}
+	if value := attrs.Attribute(name); len(value) > 0 {
+		return value, true
+	}
+	return "", false
+}
+
+// Compute the style of the last element of the given path (from root to
+// element). Display and opacity are effective values: an element is not
+// displayed, if one of its ancestors is not, and its opacity is multiplied by
+// its ancestors' opacities.
+func (c *Cascade) ComputedStyle(path []SVGElement) Style {
+	parent := Style{}
+	for _, element := range path {
+		style := Style{}
+		for name := range styleInitial {
+			value, ok := c.declared(element, name)
+			inherited := slices.Contains(styleInherited, name)
+			if !ok && inherited || value == "inherit" {
+				value = parent.Get(name)
+			} else if !ok || value == "initial" {
+				value = styleInitial[name]
+			}
+			style[name] = value
+		}
+		if !parent.IsDisplayed() {
+			style["display"] = "none"
+		}
+		style["opacity"] = strconv.FormatFloat(float64(style.Number("opacity")*parent.Number("opacity")), 'g', -1, 64)
+		style["font-size"] = absoluteFontSize(style.Get("font-size"), absoluteFontSize(parent.Get("font-size"), ""))
+		parent = style
+	}
+	return parent
+}
+
+// Name of the element's tag, e.g., 'path'
+func TagName(element SVGElement) string {
+	switch element.(type) {
+	case *SVG:
+		return "svg"
+	case *Grouping:
+		return "g"
+	case *ALink:
+		return "a"
+	case *Defs:
+		return "defs"
+	case *Use:
+		return "use"
+	case *Text:
+		return "text"
+	case *Tspan:
+		return "tspan"
+	case *Image:
+		return "image"
+	case *Path:
+		return "path"
+	case *Line:
+		return "line"
+	case *Rect:
+		return "rect"
+	case *Circle:
+		return "circle"
+	case *Ellipse:
+		return "ellipse"
+	case *Polygon:
+		return "polygon"
+	case *Polyline:
+		return "polyline"
+	case *ClipPath:
+		return "clipPath"
+	case *Mask:
+		return "mask"
+	case *Symbol:
+		return "symbol"
+	case *Switch:
+		return "switch"
+	}
+	return ""
+}
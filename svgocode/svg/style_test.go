@@ -0,0 +1,66 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCascade(t *testing.T) {
+	doc := `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+  <style>
+    /* comment */
+    path { stroke: blue }
+    .thin { stroke-width: 0.5 }
+    #special, rect.thin { stroke: green }
+    g > path { stroke: purple }
+  </style>
+  <g id="g1" stroke="red" opacity="0.5" class="thin">
+    <path id="p1" d="M0 0 L1 1"/>
+    <path id="p2" d="M0 0 L1 1" style="stroke: orange" stroke="yellow"/>
+    <path id="special" d="M0 0 L1 1"/>
+    <rect id="r1" class="thin" width="1" height="1" opacity="50%"/>
+    <circle id="c1" r="1"/>
+  </g>
+  <g id="g2" display="none" visibility="hidden" fill="none">
+    <circle id="c2" r="1" display="inline" visibility="visible" fill="inherit"/>
+  </g>
+</svg>`
+	s := new(SVG)
+	if err := NewDecoder(strings.NewReader(doc)).Decode(s); err != nil {
+		t.Fatal(err)
+	}
+	cascade := NewCascade(s)
+	styles := make(map[SvgId]Style)
+	for path := range PathSeq(s) {
+		styles[path[len(path)-1].ID()] = cascade.ComputedStyle(path)
+	}
+	expected := []struct {
+		id       SvgId
+		property string
+		value    string
+	}{
+		{"p1", "stroke", "blue"},
+		{"p1", "stroke-width", "0.5"},
+		{"p1", "opacity", "0.5"},
+		{"p2", "stroke", "orange"},
+		{"special", "stroke", "green"},
+		{"r1", "stroke", "green"},
+		{"r1", "opacity", "0.25"},
+		{"c1", "stroke", "red"},
+		{"c1", "fill", "black"},
+		{"c2", "display", "none"},
+		{"c2", "visibility", "visible"},
+		{"c2", "fill", "none"},
+	}
+	for _, e := range expected {
+		if got := styles[e.id].Get(e.property); got != e.value {
+			t.Errorf("Element '%s': expected %s '%s', got '%s'", e.id, e.property, e.value, got)
+		}
+	}
+	if styles["c2"].IsVisible() {
+		t.Error("Element 'c2' should not be visible")
+	}
+	if !styles["c1"].HasFill() || styles["c1"].Number("stroke-width") != 0.5 {
+		t.Error("Element 'c1' should be filled and inherit stroke-width 0.5")
+	}
+}
@@ -0,0 +1,290 @@
+package svg
+
+import (
+	"math"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Geometry of svg paths: subpaths and segments in absolute user space
+// coordinates
+
+type SegmentType int
+
+const (
+	SegLine SegmentType = iota
+	SegCubic
+	SegQuadratic
+	SegArc
+)
+
+// A segment of a subpath in absolute user space coordinates. Segments start
+// where their predecessor (or the subpath) ends.
+type PathSegment struct {
+	Type SegmentType
+	Ctrl [2]math64.VectorF2 // Control points of Bézier curves
+	Arc  EllipticalArcArg
+	To   math64.VectorF2
+}
+
+// Control points of the segment as cubic Bézier curve, starting at from.
+// Quadratic curves are elevated to cubic ones.
+func (seg PathSegment) Cubic(from math64.VectorF2) [4]math64.VectorF2 {
+	if seg.Type == SegQuadratic {
+		q := seg.Ctrl[0]
+		return [4]math64.VectorF2{
+			from,
+			from.Add(q.Sub(from).Scale(2.0 / 3.0)),
+			seg.To.Add(q.Sub(seg.To).Scale(2.0 / 3.0)),
+			seg.To,
+		}
+	}
+	return [4]math64.VectorF2{from, seg.Ctrl[0], seg.Ctrl[1], seg.To}
+}
+
+// A subpath in user space, i.e., before any transformation.
+type Subpath struct {
+	Start    math64.VectorF2
+	Segments []PathSegment
+	Closed   bool // Whether the subpath was closed via 'Z'
+}
+
+// Parse path commands into subpaths, resolving relative coordinates.
+// Returns the subpaths and the final position.
+func ParseSubpaths(commands []PathCommand) ([]Subpath, math64.VectorF2) {
+	var subpaths []Subpath
+	current := math64.VectorF2{X: 0, Y: 0}
+	pathSegmentStart := math64.VectorF2{X: 0, Y: 0} // The first point since the last drawing began
+	var sp *Subpath
+	var last PathSegment // The previous segment, if it was added by the previous command
+
+	// Begin a new subpath at p
+	begin := func(p math64.VectorF2) {
+		subpaths = append(subpaths, Subpath{Start: p})
+		sp = &subpaths[len(subpaths)-1]
+		last = PathSegment{}
+	}
+	// Add seg to the current subpath (starting a new one, if necessary)
+	add := func(seg PathSegment) {
+		if sp == nil {
+			begin(current)
+		}
+		sp.Segments = append(sp.Segments, seg)
+		current = seg.To
+		last = seg
+	}
+	// Reflect the last control point of the previous segment at the current
+	// point, if the previous segment is a curve of type typ. Else, the first
+	// control point of smooth curves is the current point.
+	// https://www.w3.org/TR/SVG2/paths.html#ReflectedControlPoints
+	reflect := func(typ SegmentType) math64.VectorF2 {
+		if last.Type != typ {
+			return current
+		}
+		ctrl := last.Ctrl[0]
+		if typ == SegCubic {
+			ctrl = last.Ctrl[1]
+		}
+		return current.Scale(2).Sub(ctrl)
+	}
+
+	for _, cmd := range commands {
+		switch cmd.Type {
+		case CmdMoveTo:
+			for _, p := range cmd.PathPoints {
+				if cmd.Relative {
+					p = p.Add(current)
+				}
+				begin(p)
+				current = p
+				pathSegmentStart = current
+			}
+
+		case CmdLineTo:
+			for _, p := range cmd.PathPoints {
+				if cmd.Relative {
+					p = p.Add(current)
+				}
+				add(PathSegment{Type: SegLine, To: p})
+			}
+
+		case CmdHLineTo:
+			for _, cx := range cmd.Coordinates {
+				x := cx
+				if cmd.Relative {
+					x += current.X
+				}
+				add(PathSegment{Type: SegLine, To: math64.VectorF2{X: x, Y: current.Y}})
+			}
+
+		case CmdVLineTo:
+			for _, cy := range cmd.Coordinates {
+				y := cy
+				if cmd.Relative {
+					y += current.Y
+				}
+				add(PathSegment{Type: SegLine, To: math64.VectorF2{X: current.X, Y: y}})
+			}
+
+		case CmdCurveTo: // Cubic Bézier curves
+			for i := 0; i+2 < len(cmd.PathPoints); i += 3 {
+				p1 := cmd.PathPoints[i]
+				p2 := cmd.PathPoints[i+1]
+				p3 := cmd.PathPoints[i+2]
+				if cmd.Relative {
+					p1 = p1.Add(current)
+					p2 = p2.Add(current)
+					p3 = p3.Add(current)
+				}
+				add(PathSegment{Type: SegCubic, Ctrl: [2]math64.VectorF2{p1, p2}, To: p3})
+			}
+
+		case CmdSmoothCurveTo: // Cubic Bézier curves with reflected first control point
+			for i := 0; i+1 < len(cmd.PathPoints); i += 2 {
+				p2 := cmd.PathPoints[i]
+				p3 := cmd.PathPoints[i+1]
+				if cmd.Relative {
+					p2 = p2.Add(current)
+					p3 = p3.Add(current)
+				}
+				add(PathSegment{Type: SegCubic, Ctrl: [2]math64.VectorF2{reflect(SegCubic), p2}, To: p3})
+			}
+
+		case CmdQuadraticBezierTo: // Quadratic Bézier
+			for i := 0; i+1 < len(cmd.PathPoints); i += 2 {
+				p1 := cmd.PathPoints[i]
+				p2 := cmd.PathPoints[i+1]
+				if cmd.Relative {
+					p1 = p1.Add(current)
+					p2 = p2.Add(current)
+				}
+				add(PathSegment{Type: SegQuadratic, Ctrl: [2]math64.VectorF2{p1}, To: p2})
+			}
+
+		case CmdSmoothQuadraticBezierTo: // Quadratic Bézier with reflected control point
+			for _, p := range cmd.PathPoints {
+				if cmd.Relative {
+					p = p.Add(current)
+				}
+				add(PathSegment{Type: SegQuadratic, Ctrl: [2]math64.VectorF2{reflect(SegQuadratic)}, To: p})
+			}
+
+		case CmdEllipticalArc: // Elliptical arc
+			for _, a := range cmd.ArcArgs {
+				to := a.To
+				if cmd.Relative {
+					to = to.Add(current)
+				}
+				add(PathSegment{Type: SegArc, Arc: a, To: to})
+			}
+
+		case CmdClosePath:
+			if sp != nil {
+				sp.Closed = true
+				sp = nil
+			}
+			current = pathSegmentStart
+			last = PathSegment{}
+
+		default:
+			llog.Warnf("Unsupported path command: %s\n", cmd.Type)
+		}
+	}
+	return subpaths, current
+}
+
+// Center parameterization of an elliptical arc, cf.
+// https://www.w3.org/TR/SVG2/implnote.html#ArcConversionEndpointToCenter
+type EllipticalArc struct {
+	Center math64.VectorF2
+	Radii  math64.VectorF2 // Corrected radii
+	Phi    math64.AngRad   // Rotation of the x-axis
+	Start  math64.AngRad
+	Delta  math64.AngRad // Positive for angle-increasing direction
+}
+
+// Point on the arc at angle start + t*delta
+func (e EllipticalArc) At(t math64.AngRad) math64.VectorF2 {
+	angle := e.Start + t*e.Delta
+	x := e.Center.X + e.Radii.X*angle.Cos()*e.Phi.Cos() - e.Radii.Y*angle.Sin()*e.Phi.Sin()
+	y := e.Center.Y + e.Radii.X*angle.Cos()*e.Phi.Sin() + e.Radii.Y*angle.Sin()*e.Phi.Cos()
+	return math64.VectorF2{X: x, Y: y}
+}
+
+// Convert an elliptical arc from endpoint to center parameterization.
+// Returns false, if a radius is zero (i.e., the arc is a straight line).
+func ArcCenter(from, to math64.VectorF2, a EllipticalArcArg) (EllipticalArc, bool) {
+	rx := math64.Float(math.Abs(float64(a.R.X)))
+	ry := math64.Float(math.Abs(float64(a.R.Y)))
+	if rx == 0 || ry == 0 {
+		return EllipticalArc{}, false
+	}
+
+	// Convert rotation to radians
+	phi := math64.AngDeg(a.XAxis).Rad()
+
+	// Step 1: compute (x1', y1')
+	dx := (from.X - to.X) / 2.0
+	dy := (from.Y - to.Y) / 2.0
+	x1p := phi.Cos()*dx + phi.Sin()*dy
+	y1p := -phi.Sin()*dx + phi.Cos()*dy
+
+	// Step 2: correct radii if too small
+	rx2 := rx * rx
+	ry2 := ry * ry
+	x1p2 := x1p * x1p
+	y1p2 := y1p * y1p
+
+	rCheck := x1p2/rx2 + y1p2/ry2
+	if rCheck > 1 {
+		scale := rCheck.Sqrt()
+		rx *= scale
+		ry *= scale
+		rx2 = rx * rx
+		ry2 = ry * ry
+	}
+
+	// Step 3: compute center in transformed coordinates (cx', cy')
+	sign := math64.Float(-1.0)
+	if a.Large != a.Sweep {
+		sign = 1.0
+	}
+
+	num := rx2*ry2 - rx2*y1p2 - ry2*x1p2
+	den := rx2*y1p2 + ry2*x1p2
+	if den == 0 {
+		den = 1e-9
+	}
+	cfac := sign * (num / den).Max(0).Sqrt() // math.Sqrt(math.Max(0, num/den))
+	cxp := cfac * (rx * y1p / ry)
+	cyp := cfac * (-ry * x1p / rx)
+
+	// Step 4: transform center back to original coordinate system
+	cx := phi.Cos()*cxp - phi.Sin()*cyp + (from.X+to.X)/2
+	cy := phi.Sin()*cxp + phi.Cos()*cyp + (from.Y+to.Y)/2
+
+	// Step 5: compute start and end angles
+	v1x := (x1p - cxp) / rx
+	v1y := (y1p - cyp) / ry
+	v2x := (-x1p - cxp) / rx
+	v2y := (-y1p - cyp) / ry
+
+	startAngle := math64.Atan2(v1y, v1x)
+	endAngle := math64.Atan2(v2y, v2x)
+
+	// Step 6: compute delta angle
+	delta := endAngle - startAngle
+	if !a.Sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if a.Sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	return EllipticalArc{
+		Center: math64.VectorF2{X: cx, Y: cy},
+		Radii:  math64.VectorF2{X: rx, Y: ry},
+		Phi:    phi,
+		Start:  startAngle,
+		Delta:  delta,
+	}, true
+}
@@ -2,6 +2,9 @@ package svg
 
 import (
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/abzicht/svgocode/llog"
@@ -14,6 +17,7 @@ type SVGElement interface {
 	CloneSVGElement() SVGElement
 	SVGPresentation
 	ID() SvgId
+	Attributes() SVGCoreAttributes
 	Children() []SVGElement
 	Root() SVGElement
 	SetRoot(SVGElement)
@@ -41,12 +45,23 @@ type SVGCore struct {
 
 type SVGElements struct {
 	SVGShapeElements
-	SVG       []*SVG      `xml:"svg"`
-	Groupings []*Grouping `xml:"g"`
-	ALinks    []*ALink    `xml:"a"`
-	Defs      []*Defs     `xml:"defs"`
-	Uses      []*Use      `xml:"use"`
-	Texts     []*Text     `xml:"text"`
+	SVG       []*SVG        `xml:"svg"`
+	Groupings []*Grouping   `xml:"g"`
+	ALinks    []*ALink      `xml:"a"`
+	Defs      []*Defs       `xml:"defs"`
+	Uses      []*Use        `xml:"use"`
+	Texts     []*Text       `xml:"text"`
+	Images    []*Image      `xml:"image"`
+	Styles    []*StyleSheet `xml:"style"`
+	ClipPaths []*ClipPath   `xml:"clipPath"`
+	Masks     []*Mask       `xml:"mask"`
+	Symbols   []*Symbol     `xml:"symbol"`
+	Switches  []*Switch     `xml:"switch"`
+	order     []string      // Tag names of the children, in document order (if known, cf. inDocumentOrder)
+}
+
+func (s *SVGElements) StyleSheets() []*StyleSheet {
+	return s.Styles
 }
 
 func (s *SVGElements) Clone() *SVGElements {
@@ -58,18 +73,47 @@ func (s *SVGElements) Clone() *SVGElements {
 	s2.Defs = forgo.Clone[*Defs](s.Defs)
 	s2.Uses = forgo.Clone[*Use](s.Uses)
 	s2.Texts = forgo.Clone[*Text](s.Texts)
+	s2.Images = forgo.Clone[*Image](s.Images)
+	s2.Styles = forgo.Clone[*StyleSheet](s.Styles)
+	s2.ClipPaths = forgo.Clone[*ClipPath](s.ClipPaths)
+	s2.Masks = forgo.Clone[*Mask](s.Masks)
+	s2.Symbols = forgo.Clone[*Symbol](s.Symbols)
+	s2.Switches = forgo.Clone[*Switch](s.Switches)
+	s2.order = slices.Clone(s.order)
 	return s2
 }
 
-// Produce a TransformChain with all transform operations contained in a given path
-func TransformChainForPath(path []SVGElement) svgtransform.TransformChain {
+// Produce a TransformChain with all transform operations contained in a given
+// path. Transformations take place around the elements' transform-origin.
+func (c *Cascade) TransformChainForPath(path []SVGElement) svgtransform.TransformChain {
 	chain := svgtransform.TransformChain{}
-	for _, element := range path {
-		chain = append(chain, element.Transform()...)
+	for i := range path {
+		chain = append(chain, c.elementTransform(path[:i+1])...)
 	}
 	return chain
 }
 
+// Transformations of the last element of the given path, around its
+// transform-origin
+func (c *Cascade) elementTransform(path []SVGElement) svgtransform.TransformChain {
+	element := path[len(path)-1]
+	origin, box := c.transformReference(element)
+	if s, ok := element.(*SVG); ok {
+		// Percentages of the viewport refer to the parent's viewport
+		var o math64.VectorF2
+		if len(origin) > 0 || len(box) > 0 {
+			o = c.TransformOrigin(path)
+		}
+		return s.TransformIn(LengthContextForPath(path[:len(path)-1]), o)
+	}
+	if len(origin) == 0 && len(box) == 0 {
+		// Skip computing the reference box
+		return element.Transform()
+	}
+	return element.TransformAround(c.TransformOrigin(path))
+}
+
+// The children, in document order if it is known (cf. inDocumentOrder)
 func (svgElem *SVGElements) Children() []SVGElement {
 	var children []SVGElement
 	for _, s := range svgElem.SVG {
@@ -90,8 +134,23 @@ func (svgElem *SVGElements) Children() []SVGElement {
 	for _, t := range svgElem.Texts {
 		children = append(children, t)
 	}
+	for _, i := range svgElem.Images {
+		children = append(children, i)
+	}
+	for _, c := range svgElem.ClipPaths {
+		children = append(children, c)
+	}
+	for _, m := range svgElem.Masks {
+		children = append(children, m)
+	}
+	for _, s := range svgElem.Symbols {
+		children = append(children, s)
+	}
+	for _, s := range svgElem.Switches {
+		children = append(children, s)
+	}
 	children = append(children, svgElem.SVGShapeElements.Children()...)
-	return children
+	return svgElem.inDocumentOrder(children)
 }
 
 type SVGShapeElements struct {
@@ -148,6 +207,12 @@ type SVG struct {
 	Y       string   `xml:"y,attr"`
 	Width   string   `xml:"width,attr"`
 	Height  string   `xml:"height,attr"`
+	// Raw viewBox / preserveAspectRatio values, cf. ViewportTransform
+	ViewBoxStr             string `xml:"viewBox,attr"`
+	PreserveAspectRatioStr string `xml:"preserveAspectRatio,attr"`
+	// Dots per inch for resolving absolute lengths (cf. LengthContext). Only
+	// considered on the outermost svg element. 0: math64.DefaultDPI
+	DPI math64.Float `xml:"-"`
 	SVGCore
 	SVGCoreAttributes
 	SVGPresentationTransform
@@ -161,6 +226,9 @@ func (s *SVG) Clone() *SVG {
 	s2.Y = s.Y
 	s2.Width = s.Width
 	s2.Height = s.Height
+	s2.ViewBoxStr = s.ViewBoxStr
+	s2.PreserveAspectRatioStr = s.PreserveAspectRatioStr
+	s2.DPI = s.DPI
 	s2.SVGCoreAttributes = s.SVGCoreAttributes
 	s2.SVGPresentationTransform = s.SVGPresentationTransform
 	s2.SVGElements = *s.SVGElements.Clone()
@@ -171,80 +239,90 @@ func (s *SVG) CloneSVGElement() SVGElement {
 	return s.Clone()
 }
 
-// Determine the unit defined in the SVG's attributes
-func (s *SVG) Unit() (unit math64.UnitLength) {
-	defer func() {
-		if r := recover(); r != nil {
-			llog.Warnf("Failed to determine SVG's unit type based on width/height: '%s'. Assuming millimeters. Verify produced gcode!\n", r)
-			unit = math64.UnitMM
-		}
-	}()
-	if len(s.Width) > 0 {
-		_, unit = math64.NumberUnit(s.Width)
-	} else if len(s.Height) > 0 {
-		_, unit = math64.NumberUnit(s.Height)
-	} else {
-		llog.Warn("Could not determine SVG's unit type. Assuming millimeters. Verify produced gcode!\n")
-		unit = math64.UnitMM
-	}
-	return
+// Transformations of the svg element: its transform attribute, followed by
+// the mapping of its view box onto its viewport. As the parent is unknown,
+// percentages refer to the outermost viewport (cf. TransformIn).
+func (s *SVG) Transform() svgtransform.TransformChain {
+	return s.TransformIn(s.outermostContext(), math64.VectorF2{X: 0, Y: 0})
 }
 
-type Tspan struct {
-	SVGCore
-	SVGCoreAttributes
-	SVGPresentationTransform
-	X           math64.Float `xml:"x,attr"`
-	Y           math64.Float `xml:"y,attr"`
-	InnerString string       `xml:"innerhtml"`
+// Same as Transform, but the transform attribute takes place around the given
+// origin
+func (s *SVG) TransformAround(origin math64.VectorF2) svgtransform.TransformChain {
+	return s.TransformIn(s.outermostContext(), origin)
 }
 
-func (t *Tspan) Clone() *Tspan {
-	t2 := new(Tspan)
-	t2.X = t.X
-	t2.Y = t.Y
-	t2.InnerString = t.InnerString
-	t2.SVGCoreAttributes = t.SVGCoreAttributes
-	t2.SVGPresentationTransform = t.SVGPresentationTransform
-	return t2
+// Transformations of the svg element within the given length context of its
+// parent, with the transform attribute taking place around the given origin
+func (s *SVG) TransformIn(parentCtx LengthContext, origin math64.VectorF2) svgtransform.TransformChain {
+	chain := s.SVGPresentationTransform.TransformAround(origin)
+	return append(chain, s.ViewportTransform(parentCtx)...)
 }
 
-func (t *Tspan) CloneSVGElement() SVGElement {
-	return t.Clone()
+// Length context of the children of the outermost svg element
+func (s *SVG) outermostContext() LengthContext {
+	return LengthContextForPath([]SVGElement{s.outermost()})
 }
 
-func (t *Tspan) Children() []SVGElement {
-	return []SVGElement{}
+// Determine the unit defined in the SVG's attributes
+func (s *SVG) Unit() (unit math64.UnitLength) {
+	unit, err := s.unit()
+	if err != nil {
+		llog.Warnf("Could not determine SVG's unit type (%s). Assuming pixels. Verify produced gcode!\n", err.Error())
+	}
+	return unit
 }
 
-type Text struct {
-	SVGCore
-	SVGCoreAttributes
-	SVGPresentationTransform
-	Tspan *Tspan       `xml:"tspan,attr"`
-	X     math64.Float `xml:"x,attr"`
-	Y     math64.Float `xml:"y,attr"`
+// The unit of the document's user coordinates. As per spec, user units are
+// pixels, if the outermost svg element has a view box. Otherwise, they are
+// expressed in the outermost svg element's unit (cf. Unit).
+func (s *SVG) userUnit() math64.UnitLength {
+	root := s.outermost()
+	if _, ok := ParseViewBox(root.ViewBoxStr); ok {
+		return math64.UnitPX
+	}
+	unit, _ := root.unit()
+	return unit
 }
 
-func (t *Text) Clone() *Text {
-	t2 := new(Text)
-	t2.X = t.X
-	t2.Y = t.Y
-	t2.Tspan = t.Tspan.Clone()
-	t2.SVGCoreAttributes = t.SVGCoreAttributes
-	t2.SVGPresentationTransform = t.SVGPresentationTransform
-	return t2
+// Dots per inch of the document (cf. DPI)
+func (s *SVG) dpi() math64.Float {
+	if root := s.outermost(); root.DPI > 0 {
+		return root.DPI
+	}
+	return math64.DefaultDPI
 }
 
-func (t *Text) CloneSVGElement() SVGElement {
-	return t.Clone()
+// The outermost svg element of the document
+func (s *SVG) outermost() *SVG {
+	if root, ok := s.Root().(*SVG); ok {
+		return root
+	}
+	return s
 }
 
-func (t *Text) Children() []SVGElement {
-	if nil == t.Tspan {
-		return []SVGElement{}
+// Same as Unit, but returns an error instead of logging warnings.
+func (s *SVG) unit() (unit math64.UnitLength, err error) {
+	var l string
+	if len(s.Width) > 0 {
+		l = s.Width
+	} else if len(s.Height) > 0 {
+		l = s.Height
+	} else {
+		return math64.UnitPX, errors.New("neither width nor height are set")
+	}
+	_, unit, err = math64.ParseLength(l)
+	if err != nil {
+		return math64.UnitPX, err
 	}
-	return []SVGElement{t.Tspan}
+	switch {
+	case unit == math64.UnitNone, unit == math64.UnitPercent:
+		// User units are pixels, unless specified otherwise
+		return math64.UnitPX, nil
+	case !unit.IsAbsolute():
+		return math64.UnitPX, fmt.Errorf("unit '%s' cannot be used as user unit", unit)
+	}
+	return unit, nil
 }
 
 type Defs struct {
@@ -271,11 +349,16 @@ type Grouping struct {
 	SVGCoreAttributes
 	SVGPresentationTransform
 	SVGElements
+	// Inkscape layers are groups with groupmode 'layer'
+	InkscapeGroupMode string `xml:"http://www.inkscape.org/namespaces/inkscape groupmode,attr"`
+	InkscapeLabel     string `xml:"http://www.inkscape.org/namespaces/inkscape label,attr"`
 }
 
 func (g *Grouping) Clone() *Grouping {
 	g2 := new(Grouping)
 	g2.SVGCoreAttributes = g.SVGCoreAttributes
+	g2.InkscapeGroupMode = g.InkscapeGroupMode
+	g2.InkscapeLabel = g.InkscapeLabel
 	g2.SVGPresentationTransform = g.SVGPresentationTransform
 	g2.SVGElements = *g.SVGElements.Clone()
 	return g2
@@ -285,6 +368,31 @@ func (g *Grouping) CloneSVGElement() SVGElement {
 	return g.Clone()
 }
 
+// Returns true, if the group is an Inkscape layer
+func (g *Grouping) IsLayer() bool {
+	return g.InkscapeGroupMode == "layer"
+}
+
+// Name of the layer: its label or, if it has none, its id
+func (g *Grouping) LayerName() string {
+	if len(g.InkscapeLabel) > 0 {
+		return g.InkscapeLabel
+	}
+	return string(g.Id)
+}
+
+// The layers that contain the last element of the given path, outermost
+// first
+func LayersForPath(path []SVGElement) []*Grouping {
+	var layers []*Grouping
+	for _, element := range path {
+		if g, ok := element.(*Grouping); ok && g.IsLayer() {
+			layers = append(layers, g)
+		}
+	}
+	return layers
+}
+
 type ALink struct {
 	SVGCore
 	SVGCoreAttributes
@@ -312,8 +420,10 @@ type Use struct {
 	SVGPresentationTransform
 	SVGLinkAttributes
 	SVGElements
-	X math64.Float `xml:"x,attr"`
-	Y math64.Float `xml:"y,attr"`
+	X      Length `xml:"x,attr"`
+	Y      Length `xml:"y,attr"`
+	Width  Length `xml:"width,attr"`  // Only applies to referenced symbol and svg elements
+	Height Length `xml:"height,attr"` // Only applies to referenced symbol and svg elements
 }
 
 func (u *Use) Clone() *Use {
@@ -322,6 +432,10 @@ func (u *Use) Clone() *Use {
 	u2.SVGPresentationTransform = u.SVGPresentationTransform
 	u2.SVGLinkAttributes = u.SVGLinkAttributes
 	u2.SVGElements = *u.SVGElements.Clone()
+	u2.X = u.X
+	u2.Y = u.Y
+	u2.Width = u.Width
+	u2.Height = u.Height
 	return u2
 }
 
@@ -372,10 +486,10 @@ type Line struct {
 	SVGCore
 	SVGCoreAttributes
 	SVGPresentationTransform
-	X1 math64.Float `xml:"x1,attr"`
-	Y1 math64.Float `xml:"y1,attr"`
-	X2 math64.Float `xml:"x2,attr"`
-	Y2 math64.Float `xml:"y2,attr"`
+	X1 Length `xml:"x1,attr"`
+	Y1 Length `xml:"y1,attr"`
+	X2 Length `xml:"x2,attr"`
+	Y2 Length `xml:"y2,attr"`
 }
 
 func (l *Line) Clone() *Line {
@@ -401,12 +515,12 @@ type Rect struct {
 	SVGCore
 	SVGCoreAttributes
 	SVGPresentationTransform
-	X      math64.Float `xml:"x,attr"`
-	Y      math64.Float `xml:"y,attr"`
-	Width  math64.Float `xml:"width,attr"`
-	Height math64.Float `xml:"height,attr"`
-	RX     math64.Float `xml:"rx,attr"`
-	RY     math64.Float `xml:"ry,attr"`
+	X      Length `xml:"x,attr"`
+	Y      Length `xml:"y,attr"`
+	Width  Length `xml:"width,attr"`
+	Height Length `xml:"height,attr"`
+	RX     Length `xml:"rx,attr"`
+	RY     Length `xml:"ry,attr"`
 }
 
 func (r *Rect) Clone() *Rect {
@@ -434,9 +548,9 @@ type Circle struct {
 	SVGCore
 	SVGCoreAttributes
 	SVGPresentationTransform
-	CX math64.Float `xml:"cx,attr"`
-	CY math64.Float `xml:"cy,attr"`
-	R  math64.Float `xml:"r,attr"`
+	CX Length `xml:"cx,attr"`
+	CY Length `xml:"cy,attr"`
+	R  Length `xml:"r,attr"`
 }
 
 func (c *Circle) Clone() *Circle {
@@ -461,10 +575,10 @@ type Ellipse struct {
 	SVGCore
 	SVGCoreAttributes
 	SVGPresentationTransform
-	CX math64.Float `xml:"cx,attr"`
-	CY math64.Float `xml:"cy,attr"`
-	RX math64.Float `xml:"rx,attr"`
-	RY math64.Float `xml:"ry,attr"`
+	CX Length `xml:"cx,attr"`
+	CY Length `xml:"cy,attr"`
+	RX Length `xml:"rx,attr"`
+	RY Length `xml:"ry,attr"`
 }
 
 func (e *Ellipse) Clone() *Ellipse {
@@ -545,7 +659,7 @@ func (p *Polyline) Points() []math64.VectorF2 {
 // Returns false else
 func IsCollection(s SVGElement) bool {
 	switch s.(type) {
-	case *Grouping, *ALink, *SVG, *Defs:
+	case *Grouping, *ALink, *SVG, *Defs, *ClipPath, *Mask, *Symbol, *Switch:
 		return true
 	}
 	return false
@@ -554,7 +668,7 @@ func IsCollection(s SVGElement) bool {
 // Returns true, iff element can not contain children
 func IsLeaf(s SVGElement) bool {
 	switch s.(type) {
-	case *Path, *Line, *Rect, *Circle, *Ellipse, *Polygon, *Polyline:
+	case *Path, *Line, *Rect, *Circle, *Ellipse, *Polygon, *Polyline, *Text, *Image:
 		return true
 	}
 	return false
@@ -40,17 +40,38 @@ func recursePath(yield func([]SVGElement) bool, resolveUses bool, sMap SvgIdMap,
 	for _, s := range svgElements {
 		s.SetRoot(root)
 		if resolveUses {
+			if !s.Attributes().ConditionsMet() {
+				continue
+			}
 			switch s.(type) {
-			case *Defs:
-				// If we resolve uses, we don't want to step through defs
-				return true
+			case *Defs, *ClipPath, *Mask, *Symbol:
+				// If we resolve uses, we don't want to step through defs.
+				// Clip paths, masks, and symbols are not rendered
+				// themselves.
+				continue
 			case *Use:
 				if len(currentPath) == 0 {
 					llog.Panicf("Cannot resolve 'use' tag's reference due to missing root node")
 				}
-				refElement := s.(*Use).GetRefElement(sMap).CloneSVGElement()
-				refElement.AppendTransform(fmt.Sprintf("translate(%f, %f)", s.(*Use).X, s.(*Use).Y), true)
-				if !recursePath(yield, resolveUses, sMap, root, currentPath, refElement) {
+				use := ResolveLengths(s, LengthContextForPath(currentPath)).(*Use)
+				refElement := use.GetRefElement(sMap).CloneSVGElement()
+				switch ref := refElement.(type) {
+				case *Symbol:
+					// Symbols are rendered like nested svg elements
+					refElement = ref.Instance(use)
+				case *SVG:
+					if use.Width.Value != 0 {
+						ref.Width = use.Width.String()
+					}
+					if use.Height.Value != 0 {
+						ref.Height = use.Height.String()
+					}
+				}
+				refElement.AppendTransform(fmt.Sprintf("translate(%f, %f)", use.X.Value, use.Y.Value), true)
+				// The use element stays part of the path, such that its
+				// transform and style apply to the referenced element
+				usePath := append(slices.Clone(currentPath), use)
+				if !recursePath(yield, resolveUses, sMap, root, usePath, refElement) {
 					return false
 				}
 				continue
@@ -62,6 +83,13 @@ func recursePath(yield func([]SVGElement) bool, resolveUses bool, sMap SvgIdMap,
 			return false
 		}
 		children := s.Children()
+		if sw, ok := s.(*Switch); ok && resolveUses {
+			// Only the selected child is rendered
+			children = nil
+			if selected := sw.Selected(); selected != nil {
+				children = []SVGElement{selected}
+			}
+		}
 		if len(children) != 0 {
 			if !recursePath(yield, resolveUses, sMap, root, path, children...) {
 				return false
@@ -73,14 +101,14 @@ func recursePath(yield func([]SVGElement) bool, resolveUses bool, sMap SvgIdMap,
 
 // Iterate over all paths traversable from the given element. If resolveUses is true,
 // "use" tags are resolved to the referenced path (referenced path must be
-// present in s); paths that contain "defs" tags are skipped. Yields all paths
+// present in root); paths that contain "defs" tags are skipped. Yields all paths
 // from root to sub-nodes (including non-leafs and a path that only contains
 // the root node). For all iterated elements, the referenced root element is
 // set to the provided "root".
 func PathSeq_(s SVGElement, root SVGElement, resolveUses bool) iter.Seq[[]SVGElement] {
 	var sMap SvgIdMap
 	if resolveUses {
-		sMap = SvgToMap(s)
+		sMap = SvgToMap(root)
 	}
 	return func(yield func([]SVGElement) bool) {
 		recursePath(yield, resolveUses, sMap, root, []SVGElement{}, s)
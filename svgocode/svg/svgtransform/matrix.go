@@ -36,3 +36,30 @@ func (tMat *TransformMatrix) Apply(points ...math64.VectorF2) []math64.VectorF2
 	}
 	return points2
 }
+
+// Determinant of the 2D part of the transformation. Negative, if the
+// transformation mirrors.
+func (tMat *TransformMatrix) Det() math64.Float {
+	m := tMat.M
+	return m[0]*m[5] - m[1]*m[4]
+}
+
+// Returns true, if the transformation preserves shapes, i.e., if it only
+// consists of translation, rotation, mirroring, and uniform scaling. Circles
+// remain circles under such transformations.
+func (tMat *TransformMatrix) IsSimilarity() bool {
+	m := tMat.M
+	colX := m[0]*m[0] + m[4]*m[4]
+	colY := m[1]*m[1] + m[5]*m[5]
+	eps := 1e-9 * colX.Max(colY)
+	return colX > 0 && (colX-colY).Abs() <= eps && (m[0]*m[1]+m[4]*m[5]).Abs() <= eps
+}
+
+// Largest factor by which the transformation stretches distances, i.e., the
+// largest singular value of its 2D part.
+func (tMat *TransformMatrix) MaxScale() math64.Float {
+	m := tMat.M
+	sum := m[0]*m[0] + m[1]*m[1] + m[4]*m[4] + m[5]*m[5]
+	det := tMat.Det()
+	return ((sum + (sum*sum - 4*det*det).Max(0).Sqrt()) / 2).Sqrt()
+}
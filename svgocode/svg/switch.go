@@ -0,0 +1,86 @@
+package svg
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+)
+
+// Conditional processing, cf.
+// https://www.w3.org/TR/SVG2/struct.html#ConditionalProcessing
+
+// Languages of the user, used for evaluating 'systemLanguage'. A language
+// matches, if it equals one of the user's languages or is a prefix of one
+// (e.g., 'en' matches 'en-US').
+var UserLanguages []string = []string{"en"}
+
+type SVGConditionalAttributes struct {
+	RequiredExtensions string `xml:"requiredExtensions,attr"`
+	SystemLanguage     string `xml:"systemLanguage,attr"`
+}
+
+// Returns true, if the element's conditions evaluate to true. No extensions
+// are supported, i.e., elements that require any are not rendered.
+func (s SVGConditionalAttributes) ConditionsMet() bool {
+	if len(strings.TrimSpace(s.RequiredExtensions)) > 0 {
+		return false
+	}
+	if len(s.SystemLanguage) == 0 {
+		return true
+	}
+	for _, lang := range strings.Split(s.SystemLanguage, ",") {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if slices.ContainsFunc(UserLanguages, func(user string) bool {
+			user = strings.ToLower(user)
+			return user == lang || strings.HasPrefix(user, lang+"-")
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Renders only the first of its children whose conditions evaluate to true
+type Switch struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGElements
+}
+
+func (s *Switch) Clone() *Switch {
+	s2 := new(Switch)
+	s2.SVGCoreAttributes = s.SVGCoreAttributes
+	s2.SVGPresentationTransform = s.SVGPresentationTransform
+	s2.SVGElements = *s.SVGElements.Clone()
+	return s2
+}
+
+func (s *Switch) CloneSVGElement() SVGElement {
+	return s.Clone()
+}
+
+// Decode the switch like any other container, remembering the document order
+// of its children (cf. decodeInOrder)
+func (s *Switch) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainSwitch Switch // Without UnmarshalXML
+	var plain plainSwitch
+	order, err := decodeInOrder(d, start, &plain)
+	if err != nil {
+		return err
+	}
+	*s = Switch(plain)
+	s.order = order
+	return nil
+}
+
+// The child that is rendered: the first one whose conditions evaluate to true.
+// Returns nil, if there is none.
+func (s *Switch) Selected() SVGElement {
+	for _, child := range s.Children() {
+		if child.Attributes().ConditionsMet() {
+			return child
+		}
+	}
+	return nil
+}
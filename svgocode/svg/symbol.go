@@ -0,0 +1,67 @@
+package svg
+
+import "encoding/xml"
+
+// A template that is only rendered when referenced by a 'use' element, cf.
+// https://www.w3.org/TR/SVG2/struct.html#SymbolElement
+type Symbol struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGElements
+	X      string `xml:"x,attr"`
+	Y      string `xml:"y,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+	// Raw viewBox / preserveAspectRatio values, cf. ViewportTransform
+	ViewBoxStr             string `xml:"viewBox,attr"`
+	PreserveAspectRatioStr string `xml:"preserveAspectRatio,attr"`
+}
+
+func (s *Symbol) Clone() *Symbol {
+	s2 := new(Symbol)
+	s2.SVGCoreAttributes = s.SVGCoreAttributes
+	s2.SVGPresentationTransform = s.SVGPresentationTransform
+	s2.SVGElements = *s.SVGElements.Clone()
+	s2.X = s.X
+	s2.Y = s.Y
+	s2.Width = s.Width
+	s2.Height = s.Height
+	s2.ViewBoxStr = s.ViewBoxStr
+	s2.PreserveAspectRatioStr = s.PreserveAspectRatioStr
+	return s2
+}
+
+func (s *Symbol) CloneSVGElement() SVGElement {
+	return s.Clone()
+}
+
+// Produce the svg element that the symbol is rendered as, when referenced by
+// the given use element. Width and height of the use element take precedence
+// over the symbol's ones and default to 100%.
+func (s *Symbol) Instance(use *Use) *SVG {
+	instance := new(SVG)
+	instance.XMLName = xml.Name{Local: "svg"}
+	instance.X = s.X
+	instance.Y = s.Y
+	instance.Width = s.Width
+	instance.Height = s.Height
+	if use.Width.Value != 0 {
+		instance.Width = use.Width.String()
+	}
+	if use.Height.Value != 0 {
+		instance.Height = use.Height.String()
+	}
+	if len(instance.Width) == 0 {
+		instance.Width = "100%"
+	}
+	if len(instance.Height) == 0 {
+		instance.Height = "100%"
+	}
+	instance.ViewBoxStr = s.ViewBoxStr
+	instance.PreserveAspectRatioStr = s.PreserveAspectRatioStr
+	instance.SVGCoreAttributes = s.SVGCoreAttributes
+	instance.SVGPresentationTransform = s.SVGPresentationTransform
+	instance.SVGElements = *s.SVGElements.Clone()
+	return instance
+}
@@ -0,0 +1,64 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+const symbolTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="100" height="100">
+  <symbol id="icon" viewBox="0 0 10 10">
+    <circle id="dot" cx="5" cy="5" r="5"/>
+  </symbol>
+  <use id="u1" xlink:href="#icon" x="20" y="30" width="20" height="20"/>
+  <switch>
+    <foreignObject requiredExtensions="http://www.w3.org/1999/xhtml"/>
+    <rect id="german" systemLanguage="de" width="1" height="1"/>
+    <g id="english" systemLanguage="en-GB, en"><line id="l1" x2="1"/></g>
+    <line id="fallback" x2="1"/>
+  </switch>
+  <svg x="50" y="50" width="10" height="10" viewBox="0 0 100 100">
+    <line id="nested" x1="0" y1="0" x2="100" y2="100"/>
+  </svg>
+</svg>`
+
+func TestSymbolSwitch(t *testing.T) {
+	s := new(SVG)
+	if err := NewDecoder(strings.NewReader(symbolTestSvg)).Decode(s); err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[SvgId][]SVGElement)
+	for path := range PathSeq(s) {
+		if element := path[len(path)-1]; IsLeaf(element) {
+			paths[element.ID()] = path
+		}
+	}
+	for _, id := range []SvgId{"dot", "l1", "nested"} {
+		if _, ok := paths[id]; !ok {
+			t.Errorf("Expected element '%s' to be rendered", id)
+		}
+	}
+	for _, id := range []SvgId{"german", "fallback"} {
+		if _, ok := paths[id]; ok {
+			t.Errorf("Expected element '%s' not to be rendered", id)
+		}
+	}
+	// The symbol's view box is scaled onto the use element's viewport
+	cascade := NewCascade(s)
+	tMat := cascade.TransformChainForPath(paths["dot"]).ToMatrix()
+	if p := tMat.ApplyP(math64.VectorF2{X: 10, Y: 10}); p.DistEuclid(math64.VectorF2{X: 40, Y: 50}) > 1e-9 {
+		t.Errorf("Symbol corner was mapped to %s, expected 40x 50y", p.String())
+	}
+	tMat = cascade.TransformChainForPath(paths["nested"]).ToMatrix()
+	if p := tMat.ApplyP(math64.VectorF2{X: 100, Y: 100}); p.DistEuclid(math64.VectorF2{X: 60, Y: 60}) > 1e-9 {
+		t.Errorf("Nested svg corner was mapped to %s, expected 60x 60y", p.String())
+	}
+	clips := cascade.ClipsForPath(paths["nested"], SvgToMap(s))
+	if len(clips) != 1 || len(clips[0].Element.(*ClipPath).Rects) != 1 {
+		t.Fatalf("Expected the nested svg's viewport as clip, got %v", clips)
+	}
+	if r := clips[0].Element.(*ClipPath).Rects[0]; r.X.Value != 50 || r.Width.Value != 10 {
+		t.Errorf("Unexpected viewport clip: x=%s, width=%s", r.X.String(), r.Width.String())
+	}
+}
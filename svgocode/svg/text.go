@@ -0,0 +1,228 @@
+package svg
+
+import (
+	"encoding/xml"
+	"slices"
+
+	"github.com/abzicht/svgocode/llog"
+)
+
+// Text content elements, cf. https://www.w3.org/TR/SVG2/text.html
+
+// Character data or a tspan element within a text content element
+type TextRun struct {
+	Text string // Character data (if Span is nil)
+	Span *Tspan
+}
+
+// Positioning attributes of text content elements. The n-th value applies to
+// the n-th character of the element (including its descendants).
+type SVGTextPositioning struct {
+	X  LengthList `xml:"x,attr"`
+	Y  LengthList `xml:"y,attr"`
+	DX LengthList `xml:"dx,attr"`
+	DY LengthList `xml:"dy,attr"`
+}
+
+func (p *SVGTextPositioning) resolveLengths(ctx LengthContext) {
+	p.X = p.X.resolve(ctx, AxisX)
+	p.Y = p.Y.resolve(ctx, AxisY)
+	p.DX = p.DX.resolve(ctx, AxisX)
+	p.DY = p.DY.resolve(ctx, AxisY)
+}
+
+// The text content of text and tspan elements, in document order
+type SVGTextContent struct {
+	Runs []TextRun `xml:"-"`
+}
+
+func (c *SVGTextContent) Clone() *SVGTextContent {
+	c2 := new(SVGTextContent)
+	for _, run := range c.Runs {
+		if run.Span != nil {
+			run.Span = run.Span.Clone()
+		}
+		c2.Runs = append(c2.Runs, run)
+	}
+	return c2
+}
+
+func (c *SVGTextContent) Children() []SVGElement {
+	var children []SVGElement
+	for _, run := range c.Runs {
+		if run.Span != nil {
+			children = append(children, run.Span)
+		}
+	}
+	return children
+}
+
+// Decode the content of a text content element, keeping character data and
+// tspan elements in document order. Links ('a') are treated like tspan
+// elements, other elements are skipped.
+func decodeTextRuns(d *xml.Decoder) ([]TextRun, error) {
+	var runs []TextRun
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.CharData:
+			runs = append(runs, TextRun{Text: string(t)})
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tspan", "a":
+				span := new(Tspan)
+				if err := d.DecodeElement(span, &t); err != nil {
+					return nil, err
+				}
+				runs = append(runs, TextRun{Span: span})
+			default:
+				llog.Warnf("Ignoring unsupported text content element '%s'\n", t.Name.Local)
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			return runs, nil
+		}
+	}
+}
+
+// Decode only the attributes of the given start element into v
+func decodeAttributes(start xml.StartElement, v any) error {
+	return xml.NewTokenDecoder(&tokenReplay{tokens: []xml.Token{start.Copy(), start.End()}}).Decode(v)
+}
+
+type Tspan struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGTextPositioning
+	SVGTextContent
+}
+
+func (t *Tspan) Clone() *Tspan {
+	t2 := new(Tspan)
+	t2.SVGCoreAttributes = t.SVGCoreAttributes
+	t2.SVGPresentationTransform = t.SVGPresentationTransform
+	t2.SVGTextPositioning = t.SVGTextPositioning.clone()
+	t2.SVGTextContent = *t.SVGTextContent.Clone()
+	return t2
+}
+
+func (t *Tspan) CloneSVGElement() SVGElement {
+	return t.Clone()
+}
+
+func (t *Tspan) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainTspan Tspan // Without UnmarshalXML
+	var plain plainTspan
+	if err := decodeAttributes(start, &plain); err != nil {
+		return err
+	}
+	runs, err := decodeTextRuns(d)
+	if err != nil {
+		return err
+	}
+	*t = Tspan(plain)
+	t.Runs = runs
+	return nil
+}
+
+type Text struct {
+	SVGCore
+	SVGCoreAttributes
+	SVGPresentationTransform
+	SVGTextPositioning
+	SVGTextContent
+}
+
+func (t *Text) Clone() *Text {
+	t2 := new(Text)
+	t2.SVGCoreAttributes = t.SVGCoreAttributes
+	t2.SVGPresentationTransform = t.SVGPresentationTransform
+	t2.SVGTextPositioning = t.SVGTextPositioning.clone()
+	t2.SVGTextContent = *t.SVGTextContent.Clone()
+	return t2
+}
+
+func (t *Text) CloneSVGElement() SVGElement {
+	return t.Clone()
+}
+
+func (t *Text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plainText Text // Without UnmarshalXML
+	var plain plainText
+	if err := decodeAttributes(start, &plain); err != nil {
+		return err
+	}
+	runs, err := decodeTextRuns(d)
+	if err != nil {
+		return err
+	}
+	*t = Text(plain)
+	t.Runs = runs
+	return nil
+}
+
+func (p SVGTextPositioning) clone() SVGTextPositioning {
+	return SVGTextPositioning{X: slices.Clone(p.X), Y: slices.Clone(p.Y), DX: slices.Clone(p.DX), DY: slices.Clone(p.DY)}
+}
+
+// A character of a text element. Positioning attributes are nil, if not
+// specified for the character.
+type TextChar struct {
+	Rune rune
+	// From the text element to the innermost text content element that
+	// contains the character
+	Path         []SVGElement
+	X, Y, DX, DY *Length
+}
+
+// The characters of the text element, with white space being collapsed (as
+// done by browsers, i.e., line breaks become spaces), and their positioning
+// attributes. Attributes of
+// tspan elements take precedence over those of their ancestors.
+func (t *Text) Chars() []TextChar {
+	var chars []TextChar
+	space := true // Leading white space is removed
+	var walk func(path []SVGElement, positioning SVGTextPositioning, runs []TextRun)
+	walk = func(path []SVGElement, positioning SVGTextPositioning, runs []TextRun) {
+		start := len(chars)
+		for _, run := range runs {
+			if run.Span != nil {
+				walk(append(slices.Clone(path), run.Span), run.Span.SVGTextPositioning, run.Span.Runs)
+				continue
+			}
+			for _, r := range run.Text {
+				switch r {
+				case '\n', '\r', '\t':
+					r = ' '
+				}
+				if r == ' ' && space {
+					continue
+				}
+				space = r == ' '
+				chars = append(chars, TextChar{Rune: r, Path: path})
+			}
+		}
+		assign := func(values LengthList, field func(c *TextChar) **Length) {
+			for i := range min(len(values), len(chars)-start) {
+				if f := field(&chars[start+i]); *f == nil {
+					*f = &values[i]
+				}
+			}
+		}
+		assign(positioning.X, func(c *TextChar) **Length { return &c.X })
+		assign(positioning.Y, func(c *TextChar) **Length { return &c.Y })
+		assign(positioning.DX, func(c *TextChar) **Length { return &c.DX })
+		assign(positioning.DY, func(c *TextChar) **Length { return &c.DY })
+	}
+	walk([]SVGElement{t}, t.SVGTextPositioning, t.Runs)
+	if n := len(chars); n > 0 && chars[n-1].Rune == ' ' {
+		chars = chars[:n-1]
+	}
+	return chars
+}
@@ -0,0 +1,196 @@
+package svg
+
+import (
+	"strings"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+// Reference boxes and origins of transformations, cf.
+// https://www.w3.org/TR/css-transforms-1/#transform-box
+// https://www.w3.org/TR/css-transforms-1/#transform-origin-property
+
+// Number of points that curves are sampled with when computing bounding boxes
+const bboxCurveSamples = 32
+
+// Bounding box of the element's geometry in its user space, i.e., before its
+// own transformation is applied. Lengths are resolved with the given context.
+// Strokes are not considered. Returns false, if the element has no geometry.
+func BBox(element SVGElement, ctx LengthContext) (min, max math64.VectorF2, ok bool) {
+	var points math64.Polyline
+	switch s := ResolveLengths(element, ctx).(type) {
+	case *Line:
+		points = math64.Polyline{{X: s.X1.Value, Y: s.Y1.Value}, {X: s.X2.Value, Y: s.Y2.Value}}
+	case *Rect:
+		points = math64.Polyline{{X: s.X.Value, Y: s.Y.Value}, {X: s.X.Value + s.Width.Value, Y: s.Y.Value + s.Height.Value}}
+	case *Image:
+		// The image's size is unknown here, only the given one counts
+		points = math64.Polyline{{X: s.X.Value, Y: s.Y.Value}, {X: s.X.Value + s.Width.Value, Y: s.Y.Value + s.Height.Value}}
+	case *Circle:
+		points = math64.Polyline{{X: s.CX.Value - s.R.Value, Y: s.CY.Value - s.R.Value}, {X: s.CX.Value + s.R.Value, Y: s.CY.Value + s.R.Value}}
+	case *Ellipse:
+		points = math64.Polyline{{X: s.CX.Value - s.RX.Value, Y: s.CY.Value - s.RY.Value}, {X: s.CX.Value + s.RX.Value, Y: s.CY.Value + s.RY.Value}}
+	case *Polygon:
+		points = s.Points()
+	case *Polyline:
+		points = s.Points()
+	case *Path:
+		points = pathPoints(s.D)
+	case *Defs, *ClipPath, *Mask, *Symbol:
+		// Never rendered directly
+	default:
+		// The union of the children's boxes, in the element's user space
+		for _, child := range element.Children() {
+			cMin, cMax, ok := BBox(child, ctx)
+			if !ok {
+				continue
+			}
+			tMat := child.Transform().ToMatrix()
+			if s, ok := child.(*SVG); ok {
+				tMat = s.TransformIn(ctx, math64.VectorF2{X: 0, Y: 0}).ToMatrix()
+			}
+			points = append(points,
+				tMat.ApplyP(cMin), tMat.ApplyP(math64.VectorF2{X: cMax.X, Y: cMin.Y}),
+				tMat.ApplyP(cMax), tMat.ApplyP(math64.VectorF2{X: cMin.X, Y: cMax.Y}))
+		}
+	}
+	if len(points) == 0 {
+		return min, max, false
+	}
+	min, max = math64.Bounds(points)
+	return min, max, true
+}
+
+// Points on the outline of the path, with curves being sampled
+func pathPoints(d string) math64.Polyline {
+	commands, err := ParseSVGPath(d)
+	if err != nil {
+		llog.Warnf("Failed to parse path for bounding box: %s\n", err.Error())
+		return nil
+	}
+	subpaths, _ := ParseSubpaths(commands)
+	var points math64.Polyline
+	for _, sp := range subpaths {
+		from := sp.Start
+		points = append(points, from)
+		for _, seg := range sp.Segments {
+			switch seg.Type {
+			case SegCubic, SegQuadratic:
+				c := seg.Cubic(from)
+				for i := 1; i < bboxCurveSamples; i++ {
+					points = append(points, math64.CubicBezier(math64.Float(i)/bboxCurveSamples, c[0], c[1], c[2], c[3]))
+				}
+			case SegArc:
+				if e, ok := ArcCenter(from, seg.To, seg.Arc); ok {
+					for i := 1; i < bboxCurveSamples; i++ {
+						points = append(points, e.At(math64.AngRad(i)/bboxCurveSamples))
+					}
+				}
+			}
+			points = append(points, seg.To)
+			from = seg.To
+		}
+	}
+	return points
+}
+
+// The element's declared transform-origin and transform-box. Declarations in
+// style sheets and the style attribute take precedence over the attributes.
+func (c *Cascade) transformReference(element SVGElement) (origin, box string) {
+	origin, box = element.TransformReference()
+	if value, ok := c.declared(element, "transform-origin"); ok {
+		origin = value
+	}
+	if value, ok := c.declared(element, "transform-box"); ok {
+		box = value
+	}
+	return origin, box
+}
+
+// Reference box of the transformation of the last element of the given path
+// (from root to element), in the element's user space. Depending on
+// 'transform-box', this is the nearest viewport (view-box, the default) or the
+// element's bounding box (fill-box), optionally extended by its stroke
+// (stroke-box).
+func (c *Cascade) TransformBox(path []SVGElement) (pos, size math64.VectorF2) {
+	element := path[len(path)-1]
+	_, box := c.transformReference(element)
+	// The element's own viewport does not apply to its transform attribute
+	ctx := LengthContextForPath(path[:len(path)-1])
+	switch box {
+	case "", "view-box", "border-box":
+	case "fill-box", "content-box", "stroke-box", "padding-box":
+		min, max, ok := BBox(element, ctx)
+		if !ok {
+			llog.Warnf("Cannot determine the bounding box of '%s' for transform-box '%s'. Using the viewport instead.\n", element.ID(), box)
+			break
+		}
+		if box == "stroke-box" || box == "padding-box" {
+			if style := c.ComputedStyle(path); style.HasStroke() {
+				width := UserLength(1)
+				if value, unit, err := math64.ParseLength(style.Get("stroke-width")); err == nil {
+					width = Length{Value: value, Unit: unit}
+				}
+				half := width.Resolve(ctx, AxisOther) / 2
+				min = min.Sub(math64.VectorF2{X: half, Y: half})
+				max = max.Add(math64.VectorF2{X: half, Y: half})
+			}
+		}
+		return min, max.Sub(min)
+	default:
+		llog.Warnf("Unknown transform-box value '%s', assuming 'view-box'\n", box)
+	}
+	return math64.VectorF2{X: 0, Y: 0}, ctx.Viewport
+}
+
+// Origin of the transformation of the last element of the given path, in the
+// element's user space. Resolves 'transform-origin' against the element's
+// reference box (cf. TransformBox). Defaults to the box's top left corner.
+func (c *Cascade) TransformOrigin(path []SVGElement) math64.VectorF2 {
+	pos, size := c.TransformBox(path)
+	origin, _ := c.transformReference(path[len(path)-1])
+	offset := parseTransformOrigin(origin, size, LengthContextForPath(path[:len(path)-1]))
+	return pos.Add(offset)
+}
+
+// Fractions of the reference box that transform-origin keywords refer to
+var transformOriginKeywords map[string]math64.Float = map[string]math64.Float{
+	"left": 0, "top": 0, "center": 0.5, "right": 1, "bottom": 1,
+}
+
+// Parse "<x> [<y> [<z>]]" (keywords, lengths and percentages of the reference
+// box's size) into an offset from the reference box's position. The z
+// component is ignored.
+func parseTransformOrigin(s string, size math64.VectorF2, ctx LengthContext) math64.VectorF2 {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return math64.VectorF2{X: 0, Y: 0}
+	}
+	if len(fields) == 1 {
+		if fields[0] == "top" || fields[0] == "bottom" {
+			fields = []string{"center", fields[0]}
+		} else {
+			fields = append(fields, "center")
+		}
+	}
+	// Keywords may be given in any order, e.g., "top left"
+	if fields[0] == "top" || fields[0] == "bottom" || fields[1] == "left" || fields[1] == "right" {
+		fields[0], fields[1] = fields[1], fields[0]
+	}
+	resolve := func(value string, ref math64.Float, axis LengthAxis) math64.Float {
+		if f, ok := transformOriginKeywords[value]; ok {
+			return f * ref
+		}
+		v, unit, err := math64.ParseLength(value)
+		if err != nil {
+			llog.Warnf("Ignoring malformed transform-origin value '%s'\n", value)
+			return 0
+		}
+		if unit == math64.UnitPercent {
+			return v / 100 * ref
+		}
+		return Length{Value: v, Unit: unit}.Resolve(ctx, axis)
+	}
+	return math64.VectorF2{X: resolve(fields[0], size.X, AxisX), Y: resolve(fields[1], size.Y, AxisY)}
+}
@@ -0,0 +1,79 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+const transformBoxTestSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+  <rect id="fill" x="10" y="10" width="20" height="20" transform="rotate(90)" transform-origin="center" transform-box="fill-box"/>
+  <line id="view" x1="0" y1="0" x2="1" y2="1" transform="scale(2 2)" style="transform-origin: 50% 50%"/>
+  <g id="group" transform="scale(2 2)" transform-origin="bottom right" transform-box="stroke-box" stroke="black" stroke-width="2">
+    <path id="arc" d="M 0 10 A 10 10 0 0 0 20 10 A 10 10 0 0 0 0 10"/>
+  </g>
+  <style>.boxed { transform-box: stroke-box; transform-origin: left top } .stroked { stroke: black; stroke-width: 2 }</style>
+  <g class="stroked">
+    <rect id="css" class="boxed" x="0" y="50" width="10" height="10" transform="scale(2 2)"/>
+  </g>
+</svg>`
+
+func TestTransformOrigin(t *testing.T) {
+	s := new(SVG)
+	if err := NewDecoder(strings.NewReader(transformBoxTestSvg)).Decode(s); err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[SvgId][]SVGElement)
+	for path := range PathSeq(s) {
+		paths[path[len(path)-1].ID()] = path
+	}
+	if min, max, ok := BBox(paths["arc"][len(paths["arc"])-1], NewLengthContext(math64.UnitPX, math64.VectorF2{X: 100, Y: 100}, math64.DefaultDPI)); !ok ||
+		min.DistEuclid(math64.VectorF2{X: 0, Y: 0}) > 0.1 || max.DistEuclid(math64.VectorF2{X: 20, Y: 20}) > 0.1 {
+		t.Errorf("Unexpected bounding box of arcs: %s, %s", min.String(), max.String())
+	}
+	testCases := []struct {
+		id       SvgId
+		from, to math64.VectorF2
+	}{
+		// Rotation around the center of the rect
+		{"fill", math64.VectorF2{X: 10, Y: 10}, math64.VectorF2{X: 30, Y: 10}},
+		// Scaling around the center of the viewport
+		{"view", math64.VectorF2{X: 50, Y: 50}, math64.VectorF2{X: 50, Y: 50}},
+		{"view", math64.VectorF2{X: 0, Y: 0}, math64.VectorF2{X: -50, Y: -50}},
+		// Scaling around the bottom right corner of the group, including its stroke
+		{"arc", math64.VectorF2{X: 21, Y: 21}, math64.VectorF2{X: 21, Y: 21}},
+		{"arc", math64.VectorF2{X: 11, Y: 11}, math64.VectorF2{X: 1, Y: 1}},
+		// Scaling around the top left corner of the rect, including its
+		// inherited stroke, as declared by style sheets
+		{"css", math64.VectorF2{X: -1, Y: 49}, math64.VectorF2{X: -1, Y: 49}},
+		{"css", math64.VectorF2{X: 4, Y: 54}, math64.VectorF2{X: 9, Y: 59}},
+	}
+	cascade := NewCascade(s)
+	for _, tc := range testCases {
+		p := cascade.TransformChainForPath(paths[tc.id]).ToMatrix().ApplyP(tc.from)
+		if p.DistEuclid(tc.to) > 0.1 {
+			t.Errorf("Element '%s': %s was mapped to %s, expected %s", tc.id, tc.from.String(), p.String(), tc.to.String())
+		}
+	}
+}
+
+func TestParseTransformOrigin(t *testing.T) {
+	ctx := NewLengthContext(math64.UnitMM, math64.VectorF2{X: 100, Y: 100}, math64.DefaultDPI)
+	size := math64.VectorF2{X: 20, Y: 40}
+	testCases := map[string]math64.VectorF2{
+		"":              {X: 0, Y: 0},
+		"center":        {X: 10, Y: 20},
+		"top":           {X: 10, Y: 0},
+		"bottom right":  {X: 20, Y: 40},
+		"left 25%":      {X: 0, Y: 10},
+		"1cm 5mm 3":     {X: 10, Y: 5},
+		"5 bottom":      {X: 5, Y: 40},
+		"top right 2px": {X: 20, Y: 0},
+	}
+	for s, expected := range testCases {
+		if origin := parseTransformOrigin(s, size, ctx); origin.DistEuclid(expected) > 1e-9 {
+			t.Errorf("'%s' resolved to %s, expected %s", s, origin.String(), expected.String())
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package svg
+
+import (
+	"strings"
+
+	"github.com/abzicht/svgocode/llog"
+	"github.com/abzicht/svgocode/svgocode/math64"
+	"github.com/abzicht/svgocode/svgocode/svg/svgtransform"
+)
+
+// Viewport handling for 'svg' elements, cf.
+// https://www.w3.org/TR/SVG2/coords.html#ComputingAViewportsTransform
+
+type ViewBox struct {
+	Min  math64.VectorF2 // min-x, min-y
+	Size math64.VectorF2 // width, height
+}
+
+// Parse "min-x min-y width height" (separated by whitespace and/or commas).
+// Returns false, if the string is empty or malformed, or if width/height are
+// not positive (which disables rendering of the element, as per spec).
+func ParseViewBox(s string) (ViewBox, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return ViewBox{}, false
+	}
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(fields) != 4 {
+		llog.Warnf("Ignoring malformed viewBox value '%s'\n", s)
+		return ViewBox{}, false
+	}
+	vb := ViewBox{
+		Min:  math64.VectorF2{X: math64.ParseFloat(fields[0]), Y: math64.ParseFloat(fields[1])},
+		Size: math64.VectorF2{X: math64.ParseFloat(fields[2]), Y: math64.ParseFloat(fields[3])},
+	}
+	if vb.Size.X <= 0 || vb.Size.Y <= 0 {
+		llog.Warnf("Ignoring viewBox with non-positive width/height: '%s'\n", s)
+		return ViewBox{}, false
+	}
+	return vb, true
+}
+
+type AspectAlign string
+
+const (
+	AlignNone     = AspectAlign("none")
+	AlignXMinYMin = AspectAlign("xMinYMin")
+	AlignXMidYMin = AspectAlign("xMidYMin")
+	AlignXMaxYMin = AspectAlign("xMaxYMin")
+	AlignXMinYMid = AspectAlign("xMinYMid")
+	AlignXMidYMid = AspectAlign("xMidYMid") // Default
+	AlignXMaxYMid = AspectAlign("xMaxYMid")
+	AlignXMinYMax = AspectAlign("xMinYMax")
+	AlignXMidYMax = AspectAlign("xMidYMax")
+	AlignXMaxYMax = AspectAlign("xMaxYMax")
+)
+
+var aspectAligns []AspectAlign = []AspectAlign{AlignNone, AlignXMinYMin, AlignXMidYMin, AlignXMaxYMin, AlignXMinYMid, AlignXMidYMid, AlignXMaxYMid, AlignXMinYMax, AlignXMidYMax, AlignXMaxYMax}
+
+type PreserveAspectRatio struct {
+	Align AspectAlign
+	Slice bool // 'slice' if true, 'meet' else
+}
+
+// Parse "<align> [<meetOrSlice>]". Empty or unknown values resolve to the
+// default 'xMidYMid meet'.
+func ParsePreserveAspectRatio(s string) PreserveAspectRatio {
+	par := PreserveAspectRatio{Align: AlignXMidYMid, Slice: false}
+	fields := strings.Fields(s)
+	if len(fields) > 0 && fields[0] == "defer" {
+		// Only relevant for 'image' elements
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return par
+	}
+	known := false
+	for _, a := range aspectAligns {
+		if AspectAlign(fields[0]) == a {
+			par.Align = a
+			known = true
+		}
+	}
+	if !known {
+		llog.Warnf("Unknown preserveAspectRatio alignment '%s', assuming '%s'\n", fields[0], AlignXMidYMid)
+	}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "meet":
+			par.Slice = false
+		case "slice":
+			par.Slice = true
+		default:
+			llog.Warnf("Unknown preserveAspectRatio value '%s', assuming 'meet'\n", fields[1])
+		}
+	}
+	return par
+}
+
+// Produce the transformation that maps the view box onto a viewport at
+// position pos with the given size.
+func (vb ViewBox) Transform(par PreserveAspectRatio, pos, size math64.VectorF2) svgtransform.TransformChain {
+	scale := math64.VectorF2{X: size.X / vb.Size.X, Y: size.Y / vb.Size.Y}
+	if par.Align != AlignNone {
+		if par.Slice {
+			scale.X = scale.X.Max(scale.Y)
+		} else {
+			scale.X = scale.X.Min(scale.Y)
+		}
+		scale.Y = scale.X
+	}
+	offset := math64.VectorF2{X: pos.X - vb.Min.X*scale.X, Y: pos.Y - vb.Min.Y*scale.Y}
+	align := string(par.Align)
+	if strings.Contains(align, "xMid") {
+		offset.X += (size.X - vb.Size.X*scale.X) / 2
+	} else if strings.Contains(align, "xMax") {
+		offset.X += size.X - vb.Size.X*scale.X
+	}
+	if strings.Contains(align, "YMid") {
+		offset.Y += (size.Y - vb.Size.Y*scale.Y) / 2
+	} else if strings.Contains(align, "YMax") {
+		offset.Y += size.Y - vb.Size.Y*scale.Y
+	}
+	chain := svgtransform.TransformChain{}
+	if !offset.Equal(math64.VectorF2{X: 0, Y: 0}) {
+		chain = append(chain, svgtransform.NewTranslate(offset))
+	}
+	if !scale.Equal(math64.VectorF2{X: 1, Y: 1}) {
+		chain = append(chain, svgtransform.NewScale(scale))
+	}
+	return chain
+}
+
+// Parse a length attribute of an 'svg' element and convert it to the given
+// unit (with pixels at the given DPI). Unitless values are taken as they are,
+// percentages refer to ref (if it is non-zero). Returns false, if s is empty
+// or cannot be interpreted.
+func viewportLength(s string, unit math64.UnitLength, ref, dpi math64.Float) (math64.Float, bool) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return 0, false
+	}
+	value, from, err := math64.ParseLength(s)
+	if err != nil {
+		llog.Warnf("Failed to interpret viewport length: %s. Ignoring it.\n", err.Error())
+		return 0, false
+	}
+	if from == math64.UnitPercent && ref == 0 {
+		return 0, false
+	}
+	return Length{Value: value, Unit: from}.Resolve(NewLengthContext(unit, math64.VectorF2{X: ref, Y: ref}, dpi), AxisX), true
+}
+
+// Determine the viewport size of the svg element. Percentages refer to the
+// size of the parent viewport (if non-zero). If only one of width and height
+// is given, the other one is derived from the view box's aspect ratio. If
+// neither is given, the view box size is used.
+func (s *SVG) viewportSize(vb ViewBox, unit math64.UnitLength, parent math64.VectorF2) math64.VectorF2 {
+	width, okW := viewportLength(s.Width, unit, parent.X, s.dpi())
+	height, okH := viewportLength(s.Height, unit, parent.Y, s.dpi())
+	switch {
+	case okW && okH:
+	case okW:
+		height = width * vb.Size.Y / vb.Size.X
+	case okH:
+		width = height * vb.Size.X / vb.Size.Y
+	default:
+		width, height = vb.Size.X, vb.Size.Y
+	}
+	return math64.VectorF2{X: width, Y: height}
+}
+
+// Size of the viewport that the svg element establishes for its children,
+// in their user units. I.e., the view box size if present, or width/height
+// otherwise (falling back to the parent's viewport size).
+func (s *SVG) ViewportSize(unit math64.UnitLength, parent math64.VectorF2) math64.VectorF2 {
+	if vb, ok := ParseViewBox(s.ViewBoxStr); ok {
+		return vb.Size
+	}
+	size := parent
+	if width, ok := viewportLength(s.Width, unit, parent.X, s.dpi()); ok {
+		size.X = width
+	}
+	if height, ok := viewportLength(s.Height, unit, parent.Y, s.dpi()); ok {
+		size.Y = height
+	}
+	return size
+}
+
+// Returns true, if the svg element is the outermost one (or if it has not
+// been assigned a root element).
+func (s *SVG) IsRoot() bool {
+	return s.Root() == nil || s.Root() == SVGElement(s)
+}
+
+// Position and size of the viewport that the svg element establishes, in the
+// user space of its parent. Lengths of the outermost svg element are
+// expressed in its unit, those of nested ones in user units, with percentages
+// referring to the viewport of the given length context of the parent (cf.
+// LengthContextForPath). Without a view box, the size is only known if width
+// and height are given (or if the parent's viewport size is known).
+func (s *SVG) Viewport(parentCtx LengthContext) (pos, size math64.VectorF2) {
+	unit, _ := s.unit()
+	var parent math64.VectorF2
+	if !s.IsRoot() {
+		unit = parentCtx.Unit
+		parent = parentCtx.Viewport
+		// x and y have no effect on the outermost svg element
+		pos.X, _ = viewportLength(s.X, unit, parent.X, s.dpi())
+		pos.Y, _ = viewportLength(s.Y, unit, parent.Y, s.dpi())
+	}
+	if vb, ok := ParseViewBox(s.ViewBoxStr); ok {
+		return pos, s.viewportSize(vb, unit, parent)
+	}
+	return pos, s.ViewportSize(unit, parent)
+}
+
+// Produce the transform chain that maps the svg element's user space onto its
+// viewport (cf. Viewport), based on viewBox and preserveAspectRatio. Nested
+// svg elements are additionally translated by their x/y attributes.
+func (s *SVG) ViewportTransform(parentCtx LengthContext) svgtransform.TransformChain {
+	pos, size := s.Viewport(parentCtx)
+	vb, ok := ParseViewBox(s.ViewBoxStr)
+	if !ok {
+		if pos.Equal(math64.VectorF2{X: 0, Y: 0}) {
+			return svgtransform.TransformChain{}
+		}
+		return svgtransform.TransformChain{svgtransform.NewTranslate(pos)}
+	}
+	par := ParsePreserveAspectRatio(s.PreserveAspectRatioStr)
+	return vb.Transform(par, pos, size)
+}
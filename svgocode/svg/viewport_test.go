@@ -0,0 +1,75 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abzicht/svgocode/svgocode/math64"
+)
+
+func TestViewBoxTransform(t *testing.T) {
+	vb, ok := ParseViewBox("0 0 793 1122")
+	if !ok {
+		t.Fatal("Failed to parse view box")
+	}
+	size := math64.VectorF2{X: 210, Y: 297}
+	pos := math64.VectorF2{X: 0, Y: 0}
+	none := vb.Transform(ParsePreserveAspectRatio("none"), pos, size).ToMatrix()
+	if p := none.ApplyP(math64.VectorF2{X: 793, Y: 1122}); p.DistEuclid(size) > 1e-9 {
+		t.Errorf("View box corner was mapped to %s, expected %s", p.String(), size.String())
+	}
+
+	vb, _ = ParseViewBox("-5,-5 10,10")
+	size = math64.VectorF2{X: 40, Y: 20}
+	expected := map[string]math64.VectorF2{
+		"":                    {X: 10, Y: 0},
+		"xMinYMin meet":       {X: 0, Y: 0},
+		"xMaxYMax meet":       {X: 20, Y: 0},
+		"xMidYMid slice":      {X: 0, Y: -10},
+		"xMinYMax slice":      {X: 0, Y: -20},
+		"defer xMidYMin meet": {X: 10, Y: 0},
+	}
+	for par, exp := range expected {
+		tMat := vb.Transform(ParsePreserveAspectRatio(par), pos, size).ToMatrix()
+		if p := tMat.ApplyP(vb.Min); p.DistEuclid(exp) > 1e-9 {
+			t.Errorf("preserveAspectRatio='%s': view box origin was mapped to %s, expected %s", par, p.String(), exp.String())
+		}
+	}
+}
+
+func TestAbsoluteLengths(t *testing.T) {
+	expected := []struct {
+		svg    string
+		dpi    math64.Float
+		length math64.Float // In the outermost svg element's unit
+	}{
+		// User units are pixels, scaled by the view box
+		{`<svg width="210mm" height="297mm" viewBox="0 0 793.7 1122.5"><line id="l" x1="10mm" x2="20mm"/></svg>`, 0, 10},
+		{`<svg width="210mm" height="297mm" viewBox="0 0 210 297"><line id="l" x1="0" x2="1in"/></svg>`, 0, 96},
+		{`<svg width="100px" height="100px" viewBox="0 0 100 100"><line id="l" x1="0" x2="1in"/></svg>`, 72, 72},
+		// Percentages of nested viewports refer to the nearest viewport
+		{`<svg width="100mm" height="100mm" viewBox="0 0 100 100"><svg width="50" height="50" viewBox="0 0 10 10">` +
+			`<svg width="50%" viewBox="0 0 1 1"><line id="l" x1="0" x2="1"/></svg></svg></svg>`, 0, 25},
+		// Without view box, user units are the outermost svg element's unit
+		{`<svg width="210mm" height="297mm"><line id="l" x1="10mm" x2="20mm"/></svg>`, 0, 10},
+	}
+	for _, e := range expected {
+		var s SVG
+		if err := NewDecoder(strings.NewReader(e.svg)).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		s.DPI = e.dpi
+		for path := range PathSeq(&s) {
+			if path[len(path)-1].ID() != "l" {
+				continue
+			}
+			line := ResolveLengths(path[len(path)-1], LengthContextForPath(path)).(*Line)
+			tMat := NewCascade(&s).TransformChainForPath(path).ToMatrix()
+			p1 := tMat.ApplyP(math64.VectorF2{X: line.X1.Value, Y: line.Y1.Value})
+			p2 := tMat.ApplyP(math64.VectorF2{X: line.X2.Value, Y: line.Y2.Value})
+			if length := p1.DistEuclid(p2); (length - e.length).Abs() > 1e-3 {
+				t.Errorf("%s: expected a line of length %f, got %f", e.svg, e.length, length)
+			}
+		}
+	}
+}